@@ -0,0 +1,11 @@
+package api
+
+import _ "embed"
+
+// Спецификация API, по которой проверяются запросы, встроенная в бинарник.
+// Единственный источник — задание/openapi.yml; openapi.yml в этом каталоге
+// создается из него командой go generate и вручную не редактируется.
+//
+//go:generate cp ../задание/openapi.yml openapi.yml
+//go:embed openapi.yml
+var Spec []byte
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/auth"
+)
+
+func TestBearerAuthentication(t *testing.T) {
+	t.Parallel()
+	keys, err := auth.ParseKeys("test-secret")
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	app := newTestApp(t, withAuth(keys))
+	s := app.tenderScenario()
+
+	token, err := auth.NewToken(keys, auth.DefaultKeyId, s.Responsible.Username, time.Hour)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	// Имя пользователя подставляется из токена
+	app.e.GET("/api/tenders/"+s.TenderId+"/status").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual("\"Created\"\n")
+
+	app.e.GET("/api/tenders/"+s.TenderId+"/status").
+		WithHeader("Authorization", "Bearer "+token).
+		WithQuery("username", s.Outsider.Username).
+		Expect().
+		Status(http.StatusForbidden)
+	app.e.GET("/api/tenders/"+s.TenderId+"/status").
+		WithHeader("Authorization", "Bearer invalid").
+		Expect().
+		Status(http.StatusUnauthorized)
+}
+
+// Пользователь из токена не может подать предложение от имени чужой организации
+func TestBearerOrganizationBid(t *testing.T) {
+	t.Parallel()
+	keys, err := auth.ParseKeys("test-secret")
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	app := newTestApp(t, withAuth(keys))
+	s := app.publishedTenderScenario()
+	bidder := app.employee("bidder")
+	bidderOrg := app.organization(bidder)
+
+	token, err := auth.NewToken(keys, auth.DefaultKeyId, s.Author.Username, time.Hour)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	app.e.POST("/api/bids/new").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(map[string]interface{}{
+			"name":        "Чужое предложение",
+			"description": "Описание предложения",
+			"tenderId":    s.TenderId,
+			"authorType":  "Organization",
+			"authorId":    bidderOrg.Id,
+		}).
+		Expect().
+		Status(http.StatusForbidden)
+}
+
+// В строгом режиме все операции, кроме проверок доступности, требуют токен,
+// в том числе зарегистрированные вне спецификации
+func TestRequiredAuthentication(t *testing.T) {
+	t.Parallel()
+	keys, err := auth.ParseKeys("test-secret")
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	app := newTestApp(t, withAuth(keys), withAuthRequired())
+	responsible := app.employee("responsible")
+	org := app.organization(responsible)
+	author := app.employee("author")
+
+	// Проверка токена выполняется до поиска объектов, поэтому их
+	// существование не важно
+	const id = "00000000-0000-0000-0000-000000000000"
+	routes := []struct{ method, path string }{
+		{http.MethodGet, "/api/tenders/" + id + "/versions"},
+		{http.MethodGet, "/api/tenders/" + id + "/events"},
+		{http.MethodGet, "/api/bids/search"},
+		{http.MethodGet, "/api/organizations"},
+		{http.MethodPost, "/api/organizations/new"},
+		{http.MethodGet, "/api/organizations/" + org.Id},
+		{http.MethodPatch, "/api/organizations/" + org.Id + "/edit"},
+		{http.MethodGet, "/api/organizations/" + org.Id + "/responsibles"},
+		{http.MethodPut, "/api/organizations/" + org.Id + "/responsibles/" + author.Username},
+		{http.MethodDelete, "/api/organizations/" + org.Id + "/responsibles/" + responsible.Username},
+		{http.MethodPost, "/api/employees/new"},
+		{http.MethodGet, "/api/employees/" + author.Username},
+		{http.MethodGet, "/api/webhooks"},
+		{http.MethodPost, "/api/webhooks/new"},
+		{http.MethodGet, "/api/webhooks/" + id},
+		{http.MethodPatch, "/api/webhooks/" + id + "/edit"},
+		{http.MethodDelete, "/api/webhooks/" + id},
+		{http.MethodGet, "/api/webhooks/" + id + "/deliveries"},
+		{http.MethodGet, "/api/tenders/my"},
+		{http.MethodGet, "/api/bids/" + id + "/status"},
+	}
+	for _, route := range routes {
+		// Имя пользователя в параметрах без токена не принимается
+		app.e.Request(route.method, route.path).
+			WithQuery("username", responsible.Username).
+			WithQuery("requesterUsername", responsible.Username).
+			WithQuery("organizationId", org.Id).
+			Expect().
+			Status(http.StatusUnauthorized)
+	}
+
+	app.e.GET("/api/ping").Expect().Status(http.StatusOK)
+	app.e.GET("/api/health/live").Expect().Status(http.StatusOK)
+
+	token, err := auth.NewToken(keys, auth.DefaultKeyId, responsible.Username, time.Hour)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	app.e.GET("/api/organizations/"+org.Id).
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK)
+}
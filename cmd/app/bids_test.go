@@ -0,0 +1,417 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCreateBid(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.publishedTenderScenario()
+
+	response := app.e.POST("/api/bids/new").
+		WithJSON(map[string]interface{}{
+			"name":        "Предложение 1",
+			"description": "Описание предложения",
+			"tenderId":    s.TenderId,
+			"authorType":  "User",
+			"authorId":    s.Author.Id,
+		}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+
+	response.Value("id").String().NotEmpty()
+	response.Value("createdAt").String().NotEmpty()
+	response.Value("status").String().IsEqual("Created")
+	response.Value("authorType").String().IsEqual("User")
+	response.Value("authorId").String().IsEqual(s.Author.Id)
+	response.Value("version").Number().IsEqual(1)
+
+	// Тип автора в том виде, в котором он указан в спецификации
+	app.e.POST("/api/bids/new").
+		WithJSON(map[string]interface{}{
+			"name":        "Предложение 2",
+			"description": "Описание предложения",
+			"tenderId":    s.TenderId,
+			"authorType":  "User",
+			"authorId":    s.Author.Id,
+		}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("authorType").String().IsEqual("User")
+	app.e.POST("/api/bids/new").
+		WithJSON(map[string]interface{}{
+			"name":        "Предложение 3",
+			"description": "Описание предложения",
+			"tenderId":    s.TenderId,
+			"authorType":  "Company",
+			"authorId":    s.Author.Id,
+		}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	app.e.POST("/api/bids/new").
+		WithJSON(map[string]interface{}{
+			"name":        "Предложение 1",
+			"description": "Описание предложения",
+			"tenderId":    "00000000-0000-0000-0000-000000000000",
+			"authorType":  "User",
+			"authorId":    s.Author.Id,
+		}).
+		Expect().
+		Status(http.StatusNotFound)
+	app.e.POST("/api/bids/new").
+		WithJSON(map[string]interface{}{
+			"name":        "Предложение 1",
+			"description": "Описание предложения",
+			"tenderId":    s.TenderId,
+			"authorType":  "User",
+			"authorId":    "00000000-0000-0000-0000-000000000000",
+		}).
+		Expect().
+		Status(http.StatusUnauthorized)
+}
+
+// Предложение от организации подает ответственный за нее, указанный в username
+func TestCreateOrganizationBid(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.publishedTenderScenario()
+	bidder := app.employee("bidder")
+	bidderOrg := app.organization(bidder)
+
+	newBid := map[string]interface{}{
+		"name":        "Предложение организации",
+		"description": "Описание предложения",
+		"tenderId":    s.TenderId,
+		"authorType":  "Organization",
+		"authorId":    bidderOrg.Id,
+	}
+	app.e.POST("/api/bids/new").
+		WithQuery("username", bidder.Username).
+		WithJSON(newBid).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("authorType").String().IsEqual("Organization")
+
+	app.e.POST("/api/bids/new").
+		WithQuery("username", s.Author.Username).
+		WithJSON(newBid).
+		Expect().
+		Status(http.StatusForbidden)
+	app.e.POST("/api/bids/new").
+		WithJSON(newBid).
+		Expect().
+		Status(http.StatusUnauthorized)
+}
+
+func TestGetUserBids(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+	app.createBid(s.TenderId, s.Author)
+
+	app.e.GET("/api/bids/my").
+		WithQuery("username", s.Author.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(2)
+
+	app.e.GET("/api/bids/my").
+		WithQuery("username", s.Author.Username).
+		WithQuery("limit", 1).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(1)
+}
+
+func TestGetBidsForTender(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+	// Неопубликованное предложение видит только его автор
+	draftAuthor := app.employee("draft")
+	app.createBid(s.TenderId, draftAuthor)
+
+	app.e.GET("/api/bids/"+s.TenderId+"/list").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(1)
+	app.e.GET("/api/bids/"+s.TenderId+"/list").
+		WithQuery("username", draftAuthor.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(1)
+	app.e.GET("/api/bids/"+s.TenderId+"/list").
+		WithQuery("username", s.Outsider.Username).
+		Expect().
+		Status(http.StatusForbidden)
+}
+
+func TestSearchBids(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+	other := app.employee("author")
+	app.createBid(s.TenderId, other)
+
+	// Ответственный находит только опубликованное предложение
+	results := app.e.GET("/api/bids/search").
+		WithQuery("q", "предложения").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array()
+	results.Length().IsEqual(1)
+	results.Value(0).Object().Value("id").String().IsEqual(s.BidId)
+	results.Value(0).Object().Value("snippet").String().Contains("<b>Предложение</b>")
+
+	// Автор находит собственное неопубликованное предложение
+	app.e.GET("/api/bids/search").
+		WithQuery("q", "предложение").
+		WithQuery("username", other.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(1)
+	app.e.GET("/api/bids/search").
+		WithQuery("q", "предложение").
+		WithQuery("username", s.Outsider.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().IsEmpty()
+
+	app.e.GET("/api/bids/search").
+		WithQuery("q", "предложение").
+		WithQuery("username", "nobody").
+		Expect().
+		Status(http.StatusUnauthorized)
+	app.e.GET("/api/bids/search").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func TestGetBidStatus(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+
+	app.e.GET("/api/bids/"+s.BidId+"/status").
+		WithQuery("username", s.Author.Username).
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual("\"Published\"\n")
+	app.e.GET("/api/bids/"+s.BidId+"/status").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusOK)
+
+	// Черновик не виден ответственным за тендер
+	draftId := app.createBid(s.TenderId, s.Author)
+	app.e.GET("/api/bids/"+draftId+"/status").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusForbidden)
+	app.e.GET("/api/bids/"+draftId+"/status").
+		WithQuery("username", s.Author.Username).
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual("\"Created\"\n")
+
+	app.e.GET("/api/bids/"+s.BidId+"/status").
+		WithQuery("username", "nobody").
+		Expect().
+		Status(http.StatusUnauthorized)
+	app.e.GET("/api/bids/00000000-0000-0000-0000-000000000000/status").
+		WithQuery("username", s.Author.Username).
+		Expect().
+		Status(http.StatusNotFound)
+}
+
+func TestUpdateBidStatus(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+
+	app.e.PUT("/api/bids/"+s.BidId+"/status").
+		WithQuery("username", s.Outsider.Username).
+		WithQuery("status", "Canceled").
+		Expect().
+		Status(http.StatusForbidden)
+
+	// Согласовать предложение можно только решением ответственных
+	app.e.PUT("/api/bids/"+s.BidId+"/status").
+		WithQuery("username", s.Author.Username).
+		WithQuery("status", "Approved").
+		Expect().
+		Status(http.StatusBadRequest)
+
+	// Смена статуса, как и у тендера, создает новую версию
+	response := app.e.PUT("/api/bids/"+s.BidId+"/status").
+		WithQuery("username", s.Author.Username).
+		WithQuery("status", "Canceled").
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+	response.Value("status").String().IsEqual("Canceled")
+	response.Value("version").Number().IsEqual(3)
+}
+
+func TestEditBid(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+
+	response := app.e.PATCH("/api/bids/"+s.BidId+"/edit").
+		WithQuery("username", s.Author.Username).
+		WithJSON(map[string]interface{}{"name": "Обновленное предложение"}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+
+	response.Value("name").String().IsEqual("Обновленное предложение")
+	response.Value("description").String().IsEqual("Описание предложения")
+	// Версия 2 создана публикацией предложения
+	response.Value("version").Number().IsEqual(3)
+
+	app.e.PATCH("/api/bids/"+s.BidId+"/edit").
+		WithQuery("username", s.Responsible.Username).
+		WithJSON(map[string]interface{}{"name": "Чужое предложение"}).
+		Expect().
+		Status(http.StatusForbidden)
+}
+
+func TestRollbackBid(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+
+	app.e.PATCH("/api/bids/"+s.BidId+"/edit").
+		WithQuery("username", s.Author.Username).
+		WithJSON(map[string]interface{}{"name": "Обновленное предложение"}).
+		Expect().
+		Status(http.StatusOK)
+
+	response := app.e.PUT("/api/bids/"+s.BidId+"/rollback/1").
+		WithQuery("username", s.Author.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+
+	// Откат восстанавливает название и описание, но не статус
+	response.Value("name").String().IsEqual("Предложение 1")
+	response.Value("status").String().IsEqual("Published")
+	response.Value("version").Number().IsEqual(4)
+
+	app.e.PUT("/api/bids/"+s.BidId+"/rollback/10").
+		WithQuery("username", s.Author.Username).
+		Expect().
+		Status(http.StatusNotFound)
+}
+
+func TestSubmitBidDecision(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+
+	app.e.PUT("/api/bids/"+s.BidId+"/submit_decision").
+		WithQuery("username", s.Author.Username).
+		WithQuery("decision", "Approved").
+		Expect().
+		Status(http.StatusForbidden)
+
+	// Единственный ответственный составляет кворум, тендер закрывается
+	app.e.PUT("/api/bids/"+s.BidId+"/submit_decision").
+		WithQuery("username", s.Responsible.Username).
+		WithQuery("decision", "Approved").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("status").String().IsEqual("Approved")
+
+	app.e.GET("/api/tenders/"+s.TenderId+"/status").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusOK).
+		Body().IsEqual("\"Closed\"\n")
+}
+
+func TestSubmitBidDecisionQuorum(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+	second := app.employee("responsible")
+	if err := app.store.SeedResponsible(s.Org.Id, second.Username); err != nil {
+		t.Fatalf("SeedResponsible: %v", err)
+	}
+
+	app.e.PUT("/api/bids/"+s.BidId+"/submit_decision").
+		WithQuery("username", s.Responsible.Username).
+		WithQuery("decision", "Approved").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("status").String().IsEqual("Published")
+	app.e.PUT("/api/bids/"+s.BidId+"/submit_decision").
+		WithQuery("username", s.Responsible.Username).
+		WithQuery("decision", "Approved").
+		Expect().
+		Status(http.StatusBadRequest)
+	app.e.PUT("/api/bids/"+s.BidId+"/submit_decision").
+		WithQuery("username", second.Username).
+		WithQuery("decision", "Rejected").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("status").String().IsEqual("Rejected")
+}
+
+func TestSubmitBidFeedbackAndReviews(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+
+	app.e.PUT("/api/bids/"+s.BidId+"/feedback").
+		WithQuery("username", s.Responsible.Username).
+		WithQuery("bidFeedback", "Хорошее предложение").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("id").String().IsEqual(s.BidId)
+	app.e.PUT("/api/bids/"+s.BidId+"/feedback").
+		WithQuery("username", s.Outsider.Username).
+		WithQuery("bidFeedback", "Чужой отзыв").
+		Expect().
+		Status(http.StatusForbidden)
+
+	// Черновик не виден ответственным за тендер, отзыв на него не принимается
+	draftId := app.createBid(s.TenderId, s.Author)
+	app.e.PUT("/api/bids/"+draftId+"/feedback").
+		WithQuery("username", s.Responsible.Username).
+		WithQuery("bidFeedback", "Отзыв на черновик").
+		Expect().
+		Status(http.StatusForbidden)
+
+	reviews := app.e.GET("/api/bids/"+s.TenderId+"/reviews").
+		WithQuery("authorUsername", s.Author.Username).
+		WithQuery("requesterUsername", s.Responsible.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array()
+	reviews.Length().IsEqual(1)
+	reviews.Value(0).Object().Value("description").String().IsEqual("Хорошее предложение")
+
+	app.e.GET("/api/bids/"+s.TenderId+"/reviews").
+		WithQuery("authorUsername", s.Author.Username).
+		WithQuery("requesterUsername", s.Outsider.Username).
+		Expect().
+		Status(http.StatusForbidden)
+	app.e.GET("/api/bids/"+s.TenderId+"/reviews").
+		WithQuery("authorUsername", "nobody").
+		WithQuery("requesterUsername", s.Responsible.Username).
+		Expect().
+		Status(http.StatusNotFound)
+}
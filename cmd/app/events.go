@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/config"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/netguard"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/webhooks"
+)
+
+// Собирает доставку событий из outbox по настройкам EVENTS_* с
+// дополнительными получателями extra. closeSinks освобождает ресурсы
+// получателей после остановки доставки.
+func newDispatcher(cfg config.Events, outbox events.Outbox, log *slog.Logger, extra ...events.Sink) (dispatcher *events.Dispatcher, closeSinks func(), err error) {
+	sinks := extra
+	closeSinks = func() {}
+
+	if cfg.Log {
+		sinks = append(sinks, events.LogSink{Log: log})
+	}
+	if cfg.File != "" {
+		file, err := events.NewFileSink(cfg.File)
+		if err != nil {
+			return nil, nil, fmt.Errorf("EVENTS_FILE: %w", err)
+		}
+		sinks = append(sinks, file)
+		closeSinks = func() { file.Close() }
+	}
+	if cfg.HTTPURL != "" {
+		sinks = append(sinks, events.NewHTTPSink(cfg.HTTPURL))
+	}
+
+	return &events.Dispatcher{
+		Outbox:    outbox,
+		Sinks:     sinks,
+		Interval:  cfg.PollInterval,
+		BatchSize: cfg.BatchSize,
+		Retention: cfg.Retention,
+		Log:       log,
+	}, closeSinks, nil
+}
+
+// Собирает отправку вебхуков по настройкам WEBHOOKS_*. Запросы
+// отправляются только на публичные адреса и адреса из allowed.
+func newWebhookWorker(cfg config.Webhooks, allowed *netguard.Allowlist, store webhooks.Store, log *slog.Logger) *webhooks.Worker {
+	return &webhooks.Worker{
+		Store:        store,
+		Client:       allowed.Client(cfg.Timeout),
+		Interval:     cfg.PollInterval,
+		Timeout:      cfg.Timeout,
+		MaxAttempts:  cfg.MaxAttempts,
+		Backoff:      cfg.Backoff,
+		MaxBackoff:   cfg.MaxBackoff,
+		DisableAfter: cfg.DisableAfter,
+		Log:          log,
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+)
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+
+	app.e.GET("/api/health/live").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("status").String().IsEqual("ok")
+	app.e.GET("/api/health/ready").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("status").String().IsEqual("ok")
+
+	// При остановке сервис перестает быть готовым, но остается живым
+	app.health.Drain()
+	app.e.GET("/api/health/ready").
+		Expect().
+		Status(http.StatusServiceUnavailable).
+		JSON().Object().Value("reason").String().IsEqual("shutting down")
+	app.e.GET("/api/health/live").
+		Expect().
+		Status(http.StatusOK)
+}
+
+type unavailableStore struct {
+	*db.Memory
+}
+
+func (s unavailableStore) Ping(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestHealthStorageUnavailable(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t, withStore(func(m *db.Memory) db.Store { return unavailableStore{m} }))
+
+	app.e.GET("/api/health/ready").
+		Expect().
+		Status(http.StatusServiceUnavailable).
+		JSON().Object().Value("status").String().IsEqual("unavailable")
+	app.e.GET("/api/health/live").
+		Expect().
+		Status(http.StatusOK)
+}
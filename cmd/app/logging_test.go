@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/auth"
+)
+
+// Журнал сервиса, который пишется из обработчиков запросов параллельно
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// Записи журнала с идентификатором запроса id
+func (b *logBuffer) records(t *testing.T, id string) []map[string]any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid log record %q: %v", line, err)
+		}
+		if record["request_id"] == id {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func TestRequestLogging(t *testing.T) {
+	t.Parallel()
+	keys, err := auth.ParseKeys("test-secret")
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	var logs logBuffer
+	app := newTestApp(t, withAuth(keys), withLog(&logs))
+
+	app.e.GET("/api/tenders/my").
+		WithHeader("X-Request-ID", "req-42").
+		WithHeader("Authorization", "Bearer invalid").
+		Expect().
+		Status(http.StatusUnauthorized).
+		Header("X-Request-ID").IsEqual("req-42")
+
+	// Все записи о запросе, включая сделанные middleware, помечены его
+	// идентификатором
+	var messages []string
+	for _, record := range logs.records(t, "req-42") {
+		messages = append(messages, record["msg"].(string))
+	}
+	if !slices.Equal(messages, []string{"Rejected bearer token", "Request handled"}) {
+		t.Errorf("records of the request = %v", messages)
+	}
+
+	// Без заголовка или с недопустимым значением идентификатор создается сервером
+	for _, header := range []string{"", "bad id"} {
+		req := app.e.GET("/api/ping")
+		if header != "" {
+			req = req.WithHeader("X-Request-ID", header)
+		}
+		id := req.Expect().
+			Status(http.StatusOK).
+			Header("X-Request-ID").NotEmpty().NotEqual(header).Raw()
+
+		records := logs.records(t, id)
+		if len(records) != 1 {
+			t.Fatalf("records of request %s = %v, want access record", id, records)
+		}
+		if r := records[0]; r["route"] != "/api/ping" || r["method"] != "GET" || r["status"] != float64(http.StatusOK) {
+			t.Errorf("access record = %v", r)
+		}
+	}
+}
@@ -6,69 +6,221 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
-
-	"github.com/go-chi/chi/v5"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/auth"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/config"
 	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
 	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/handlers"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/migrate"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/netguard"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/openapi"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/webhooks"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
 )
 
-type Config struct {
-	Port string
-	DSN  string
-}
-
 var _ api.ServerInterface = (*handlers.MyServer)(nil)
 
-func setupLogging() *slog.Logger {
-	log := slog.New(
-		slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-	)
+// Повторные попытки подключения к базе данных при старте
+func retryPolicy(cfg config.Database) db.RetryPolicy {
+	return db.RetryPolicy{
+		MaxAttempts:    cfg.ConnectAttempts,
+		InitialBackoff: cfg.ConnectBackoff,
+		MaxBackoff:     cfg.ConnectMaxBackoff,
+	}
+}
 
-	return log
+// Выше уровня debug описания и имена пользователей в журнале скрываются
+func setupLogging(level slog.Level) *slog.Logger {
+	return sl.New(os.Stdout, level)
 }
 
 func main() {
-	log := setupLogging()
+	cfg, err := config.Load()
+	log := setupLogging(cfg.LogLevel)
 	slog.SetDefault(log)
+	if err != nil {
+		log.Error("Invalid configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
-	log.Info("Starting server", slog.String("Port", "8080"))
-	log.Info("Starting db", slog.String("DSN", os.Getenv("POSTGRES_CONN")))
+	// SIGINT и SIGTERM прерывают ожидание базы данных при старте и
+	// запускают плавную остановку сервера
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	cfg := Config{
-		Port: os.Getenv("SERVER_ADDRESS"), // 8080
-		DSN:  os.Getenv("POSTGRES_CONN"),
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(ctx, log, cfg, os.Args[2:]))
 	}
 
-	dbConn, err := db.NewDB(context.Background(), cfg.DSN)
+	tenderStates, err := db.NewTenderStateMachine()
+	if back := cfg.TenderBackTransitions; back != "" {
+		var transitions []db.TenderTransition
+		transitions, err = db.ParseTenderTransitions(back)
+		if err == nil {
+			tenderStates, err = db.NewTenderStateMachine(transitions...)
+		}
+	}
+	if err != nil {
+		log.Error("Invalid TENDER_BACK_TRANSITIONS", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	policy := authz.Default()
+	if policyFile := cfg.AuthzPolicyFile; policyFile != "" {
+		policy, err = authz.Load(policyFile)
+		if err != nil {
+			log.Error("Invalid AUTHZ_POLICY_FILE", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	webhookHosts, err := netguard.ParseAllowlist(cfg.Webhooks.AllowedHosts)
 	if err != nil {
-		log.Error("Failed to connect to database", slog.String("error", err.Error()))
+		log.Error("Invalid WEBHOOKS_ALLOWED_HOSTS", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
-	defer dbConn.Close()
 
-	log.Info("Starting server", slog.String("Port", cfg.Port))
+	// STORAGE=memory запускает сервис без базы данных, данные хранятся
+	// только в памяти процесса
+	var storage db.Store
+	if cfg.Storage == config.StorageMemory {
+		log.Warn("Using in-memory storage, data will be lost on restart")
+		memory := db.NewMemory()
+		memory.TenderStates, memory.Policy = tenderStates, policy
+		memory.WebhookHosts = webhookHosts
+		// Администраторы должны быть сотрудниками, а в пустом хранилище
+		// больше некому их создать
+		for _, username := range policy.Admins() {
+			memory.SeedEmployee(username, "", "")
+		}
+		storage = memory
+	} else {
+		dbConn, err := db.ConnectWithRetry(ctx, cfg.Database.DSN, retryPolicy(cfg.Database), log)
+		if err != nil {
+			log.Error("Failed to connect to database", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		// Миграции применяются при старте, если это не отключено явно
+		if cfg.MigrateOnStart {
+			migrator, err := migrate.New(dbConn.Pool, log)
+			if err != nil {
+				log.Error("Failed to load migrations", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			applied, err := migrator.Up(ctx)
+			if err != nil {
+				log.Error("Failed to apply migrations", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			log.Info("Migrations applied", slog.Int("count", len(applied)))
+		}
+
+		dbConn.TenderStates, dbConn.Policy = tenderStates, policy
+		dbConn.WebhookHosts = webhookHosts
+		dbConn.QueryTimeout = cfg.Database.QueryTimeout
+		storage = dbConn
+	}
+	defer storage.Close()
+
 	log.Debug("Debugging info enabled")
 
-	r := chi.NewRouter()
+	// Аутентификация по bearer-токенам включается, если заданы ключи подписи
+	var authn *auth.Authenticator
+	if keys := cfg.Auth.HMACKeys; keys != "" {
+		parsedKeys, err := auth.ParseKeys(keys)
+		if err != nil {
+			log.Error("Invalid AUTH_HMAC_KEYS", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		authn = auth.NewAuthenticator(parsedKeys, storage, auth.Options{
+			Issuer:   cfg.Auth.Issuer,
+			Required: cfg.Auth.Required,
+			Log:      log,
+		})
+	}
 
-	myServer := handlers.NewServer(dbConn)
+	validator, err := openapi.NewValidator(openapi.Options{})
+	if err != nil {
+		log.Error("Failed to load OpenAPI spec", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
-	r.Route("/api", func(apiRouter chi.Router) {
-		apiHandler := api.HandlerFromMux(myServer, apiRouter)
-		apiRouter.Mount("/", apiHandler)
-	})
+	// Доставка доменных событий и вебхуков работает в фоне до остановки сервера
+	var background sync.WaitGroup
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	var sinks []events.Sink
+	if cfg.Webhooks.Enabled {
+		sinks = append(sinks, webhooks.Sink{Store: storage})
+		worker := newWebhookWorker(cfg.Webhooks, webhookHosts, storage, log)
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			worker.Run(backgroundCtx)
+		}()
+	}
+	// Без получателей события только отмечаются доставленными и хранятся
+	// для ленты событий тендера до истечения EVENTS_RETENTION
+	dispatcher, closeSinks, err := newDispatcher(cfg.Events, storage, log, sinks...)
+	if err != nil {
+		log.Error("Invalid events configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer closeSinks()
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		dispatcher.Run(backgroundCtx)
+	}()
+
+	m, err := newMetrics(storage, log)
+	if err != nil {
+		log.Error("Failed to set up metrics", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
-	log.Info("Starting server", slog.String("port", cfg.Port))
+	health := handlers.NewHealth(storage, log)
+	r := newRouter(storage, authn, validator, health, m, log)
+
+	log.Info("Starting server", slog.String("address", cfg.Address))
 	srv := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: r,
+		Addr:         cfg.Address,
+		Handler:      r,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+		IdleTimeout:  cfg.IdleTimeout,
 	}
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
 		log.Error("Failed to start server", slog.String("error", err.Error()))
 		os.Exit(1)
+	case <-ctx.Done():
 	}
 
+	// Новые запросы перестают поступать, обрабатываемые завершаются за
+	// ShutdownTimeout, после чего закрывается пул соединений с базой
+	log.Info("Shutting down server", slog.Duration("timeout", cfg.ShutdownTimeout))
+	health.Drain()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("Failed to shut down server gracefully", slog.String("error", err.Error()))
+	}
+	// Недоставленные события остаются в outbox, а доставки вебхукам — в
+	// очереди до следующего запуска
+	stopBackground()
+	background.Wait()
+	log.Info("Server stopped")
 }
@@ -1,45 +1,206 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/http"
-
+	"strings"
 	"testing"
 
-	"github.com/gavv/httpexpect/v2"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
 )
 
-var TEST_TENDER_ID = "" // will be set by TestCreateTender
-
-const TEST_ORG_ID = "550e8400-e29b-41d4-a716-446655440000"
-
 func TestPing(t *testing.T) {
-	e := httpexpect.Default(t, "http://localhost:8080")
+	t.Parallel()
+	app := newTestApp(t)
 
-	e.GET("/api/ping").
+	app.e.GET("/api/ping").
 		Expect().
 		Status(http.StatusOK).
 		Body().IsEqual("ok")
 }
 
 func TestGetTenders(t *testing.T) {
-	e := httpexpect.Default(t, "http://localhost:8080")
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.tenderScenario()
+	app.createTender(s.Org, s.Responsible)
+
+	app.e.GET("/api/tenders").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(2)
+
+	app.e.GET("/api/tenders").
+		WithQuery("limit", 1).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(1)
+
+	app.e.GET("/api/tenders").
+		WithQuery("service_type", "Delivery").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().IsEmpty()
+}
+
+func TestTendersPagination(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	responsible := app.employee("responsible")
+	org := app.organization(responsible)
+	for _, name := range []string{"Г", "Б", "Д", "А", "В"} {
+		app.e.POST("/api/tenders/new").
+			WithJSON(map[string]interface{}{
+				"name":            name,
+				"description":     "Описание тендера",
+				"serviceType":     "Construction",
+				"organizationId":  org.Id,
+				"creatorUsername": responsible.Username,
+			}).
+			Expect().
+			Status(http.StatusOK)
+	}
+
+	// Страницы по курсору в алфавитном порядке
+	var names []string
+	cursor := ""
+	for {
+		request := app.e.GET("/api/tenders").WithQuery("limit", 2).WithQuery("total", true)
+		if cursor != "" {
+			request = request.WithQuery("cursor", cursor)
+		}
+		response := request.Expect().Status(http.StatusOK)
+		response.Header("X-Total-Count").IsEqual("5")
+		for _, v := range response.JSON().Array().Iter() {
+			names = append(names, v.Object().Value("name").String().Raw())
+		}
+		cursor = response.Header("X-Next-Cursor").Raw()
+		if cursor == "" {
+			break
+		}
+	}
+	if got := strings.Join(names, ""); got != "АБВГД" {
+		t.Errorf("pages = %q, want АБВГД", got)
+	}
+
+	first := app.e.GET("/api/tenders/my").
+		WithQuery("username", responsible.Username).
+		WithQuery("sort", "name,desc").
+		WithQuery("limit", 2).
+		Expect().
+		Status(http.StatusOK)
+	first.JSON().Array().Value(0).Object().Value("name").String().IsEqual("Д")
+	next := first.Header("X-Next-Cursor").NotEmpty().Raw()
+	app.e.GET("/api/tenders/my").
+		WithQuery("username", responsible.Username).
+		WithQuery("cursor", next).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Value(0).Object().Value("name").String().IsEqual("В")
 
-	e.GET("/api/tenders").
+	app.e.GET("/api/tenders").
+		WithQuery("limit", 0).
+		WithQuery("total", true).
 		Expect().
 		Status(http.StatusOK).
-		JSON().Array().NotEmpty()
+		Header("X-Total-Count").IsEqual("5")
+
+	// Некорректные параметры
+	app.e.GET("/api/tenders").
+		WithQuery("sort", "price").
+		Expect().
+		Status(http.StatusBadRequest)
+	app.e.GET("/api/tenders").
+		WithQuery("cursor", "garbage").
+		Expect().
+		Status(http.StatusBadRequest)
+	app.e.GET("/api/tenders").
+		WithQuery("cursor", next).
+		WithQuery("offset", 1).
+		Expect().
+		Status(http.StatusBadRequest)
+	app.e.GET("/api/tenders").
+		WithQuery("cursor", next).
+		WithQuery("sort", "createdAt").
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func TestSearchTenders(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.tenderScenario()
+	app.e.POST("/api/tenders/new").
+		WithJSON(map[string]interface{}{
+			"name":            "Укладка асфальта",
+			"description":     "Асфальтирование двора и ремонт дорожек",
+			"serviceType":     "Construction",
+			"organizationId":  s.Org.Id,
+			"creatorUsername": s.Responsible.Username,
+		}).
+		Expect().
+		Status(http.StatusOK)
+	app.e.POST("/api/tenders/new").
+		WithJSON(map[string]interface{}{
+			"name":            "Ремонт офиса",
+			"description":     "Нужен свежий асфальт на парковке",
+			"serviceType":     "Construction",
+			"organizationId":  s.Org.Id,
+			"creatorUsername": s.Responsible.Username,
+		}).
+		Expect().
+		Status(http.StatusOK)
+
+	// Совпадение в названии ранжируется выше совпадения в описании
+	results := app.e.GET("/api/tenders").
+		WithQuery("q", "асфальт").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array()
+	results.Length().IsEqual(2)
+	first := results.Value(0).Object()
+	first.Value("name").String().IsEqual("Укладка асфальта")
+	first.Value("snippet").String().Contains("<b>асфальта</b>")
+	first.Value("rank").Number().Gt(results.Value(1).Object().Value("rank").Number().Raw())
+
+	app.e.GET("/api/tenders").
+		WithQuery("q", "ремонт парковки").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(1)
+	app.e.GET("/api/tenders").
+		WithQuery("q", "асфальт").
+		WithQuery("service_type", "Delivery").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().IsEmpty()
+	app.e.GET("/api/tenders").
+		WithQuery("q", "асфальт").
+		WithQuery("limit", 1).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(1)
+	app.e.GET("/api/tenders").
+		WithQuery("q", "...").
+		Expect().
+		Status(http.StatusBadRequest)
 }
 
 func TestCreateTender(t *testing.T) {
-	e := httpexpect.Default(t, "http://localhost:8080")
+	t.Parallel()
+	app := newTestApp(t)
+	responsible := app.employee("responsible")
+	org := app.organization(responsible)
 
-	response := e.POST("/api/tenders/new").
+	response := app.e.POST("/api/tenders/new").
 		WithJSON(map[string]interface{}{
 			"name":            "Тендер 1",
 			"description":     "Описание тендера",
 			"serviceType":     "Construction",
-			"organizationId":  TEST_ORG_ID,
-			"creatorUsername": "test_user",
+			"organizationId":  org.Id,
+			"creatorUsername": responsible.Username,
 		}).
 		Expect().
 		Status(http.StatusOK).
@@ -48,40 +209,135 @@ func TestCreateTender(t *testing.T) {
 
 	response.Value("createdAt").String().NotEmpty()
 	response.Value("description").String().IsEqual("Описание тендера")
-	response.Value("id").String().NotEmpty() // id должен быть не пустым UUID
+	response.Value("id").String().NotEmpty()
 	response.Value("name").String().IsEqual("Тендер 1")
-	response.Value("organizationId").String().IsEqual(TEST_ORG_ID)
+	response.Value("organizationId").String().IsEqual(org.Id)
 	response.Value("serviceType").String().IsEqual("Construction")
-	response.Value("status").String().IsEqual("CREATED")
+	response.Value("status").String().IsEqual("Created")
 	response.Value("version").Number().IsEqual(1)
-	TEST_TENDER_ID = response.Value("id").String().Raw()
+}
+
+func TestCreateTenderErrors(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	responsible := app.employee("responsible")
+	outsider := app.employee("outsider")
+	org := app.organization(responsible)
+
+	tender := func(orgId string, username string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":            "Тендер 1",
+			"description":     "Описание тендера",
+			"serviceType":     "Construction",
+			"organizationId":  orgId,
+			"creatorUsername": username,
+		}
+	}
+
+	app.e.POST("/api/tenders/new").
+		WithJSON(map[string]interface{}{"name": "Тендер 1"}).
+		Expect().
+		Status(http.StatusBadRequest)
+	app.e.POST("/api/tenders/new").
+		WithJSON(tender(org.Id, "nobody")).
+		Expect().
+		Status(http.StatusUnauthorized)
+	app.e.POST("/api/tenders/new").
+		WithJSON(tender(org.Id, outsider.Username)).
+		Expect().
+		Status(http.StatusForbidden)
+	app.e.POST("/api/tenders/new").
+		WithJSON(tender("00000000-0000-0000-0000-000000000000", responsible.Username)).
+		Expect().
+		Status(http.StatusNotFound)
+
+	// Ограничения спецификации
+	longName := tender(org.Id, responsible.Username)
+	longName["name"] = strings.Repeat("Т", 101)
+	app.e.POST("/api/tenders/new").
+		WithJSON(longName).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().Value("reason").String().Contains(`"name"`)
+	unknownType := tender(org.Id, responsible.Username)
+	unknownType["serviceType"] = "Catering"
+	app.e.POST("/api/tenders/new").
+		WithJSON(unknownType).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().Value("reason").String().Contains(`"serviceType"`)
+	app.e.GET("/api/tenders").
+		WithQuery("limit", 51).
+		Expect().
+		Status(http.StatusBadRequest)
 }
 
 func TestGetUserTenders(t *testing.T) {
-	e := httpexpect.Default(t, "http://localhost:8080")
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.tenderScenario()
 
-	e.GET("/api/tenders/my").
-		WithQuery("username", "test_user").
+	app.e.GET("/api/tenders/my").
+		WithQuery("username", s.Responsible.Username).
 		Expect().
 		Status(http.StatusOK).
-		JSON().Array().NotEmpty()
+		JSON().Array().Length().IsEqual(1)
+
+	app.e.GET("/api/tenders/my").
+		WithQuery("username", s.Outsider.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().IsEmpty()
+
+	// Тендеры видны всем ответственным организации, а не только создателю
+	colleague := app.employee("colleague")
+	if err := app.store.SeedResponsible(s.Org.Id, colleague.Username); err != nil {
+		t.Fatalf("SeedResponsible: %v", err)
+	}
+	app.e.GET("/api/tenders/my").
+		WithQuery("username", colleague.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Value(0).Object().Value("id").String().IsEqual(s.TenderId)
+
+	// Тендеры другой организации в список не попадают
+	app.createTender(app.organization(s.Outsider), s.Outsider)
+	app.e.GET("/api/tenders/my").
+		WithQuery("username", colleague.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(1)
 }
 
 func TestGetTenderStatus(t *testing.T) {
-	e := httpexpect.Default(t, "http://localhost:8080")
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.tenderScenario()
 
-	e.GET("/api/tenders/"+TEST_TENDER_ID+"/status").
-		WithQuery("username", "test_user").
+	app.e.GET("/api/tenders/"+s.TenderId+"/status").
+		WithQuery("username", s.Responsible.Username).
 		Expect().
 		Status(http.StatusOK).
-		Body().IsEqual("\"CREATED\"\n")
+		Body().IsEqual("\"Created\"\n")
+
+	// Неопубликованный тендер видят только ответственные
+	app.e.GET("/api/tenders/"+s.TenderId+"/status").
+		WithQuery("username", s.Outsider.Username).
+		Expect().
+		Status(http.StatusForbidden)
+	app.e.GET("/api/tenders/"+s.TenderId+"/status").
+		WithQuery("username", "nobody").
+		Expect().
+		Status(http.StatusUnauthorized)
 }
 
 func TestPutTenderStatus(t *testing.T) {
-	e := httpexpect.Default(t, "http://localhost:8080")
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.tenderScenario()
 
-	response := e.PUT("/api/tenders/"+TEST_TENDER_ID+"/status").
-		WithQuery("username", "test_user").
+	response := app.e.PUT("/api/tenders/"+s.TenderId+"/status").
+		WithQuery("username", s.Responsible.Username).
 		WithQuery("status", "Published").
 		Expect().
 		Status(http.StatusOK).
@@ -90,22 +346,35 @@ func TestPutTenderStatus(t *testing.T) {
 
 	response.Value("createdAt").String().NotEmpty()
 	response.Value("description").String().IsEqual("Описание тендера")
-	response.Value("id").String().NotEmpty()
+	response.Value("id").String().IsEqual(s.TenderId)
 	response.Value("name").String().IsEqual("Тендер 1")
-	response.Value("organizationId").String().NotEmpty()
+	response.Value("organizationId").String().IsEqual(s.Org.Id)
 	response.Value("serviceType").String().IsEqual("Construction")
-	response.Value("status").String().IsEqual("PUBLISHED")
+	response.Value("status").String().IsEqual("Published")
+	response.Value("version").Number().IsEqual(2)
+
+	app.e.PUT("/api/tenders/"+s.TenderId+"/status").
+		WithQuery("username", s.Outsider.Username).
+		WithQuery("status", "Closed").
+		Expect().
+		Status(http.StatusForbidden)
+	app.e.PUT("/api/tenders/"+s.TenderId+"/status").
+		WithQuery("username", s.Responsible.Username).
+		WithQuery("status", "Created").
+		Expect().
+		Status(http.StatusConflict)
 }
 
 func TestEditTender(t *testing.T) {
-	e := httpexpect.Default(t, "http://localhost:8080")
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.publishedTenderScenario()
 
-	response := e.PATCH("/api/tenders/"+TEST_TENDER_ID+"/edit").
-		WithQuery("username", "test_user").
+	response := app.e.PATCH("/api/tenders/"+s.TenderId+"/edit").
+		WithQuery("username", s.Responsible.Username).
 		WithJSON(map[string]interface{}{
 			"name":        "Обновленный Тендер 1",
 			"description": "Обновленное описание",
-			"serviceType": "Construction",
 		}).
 		Expect().
 		Status(http.StatusOK).
@@ -114,9 +383,141 @@ func TestEditTender(t *testing.T) {
 
 	response.Value("createdAt").String().NotEmpty()
 	response.Value("description").String().IsEqual("Обновленное описание")
-	response.Value("id").String().IsEqual(TEST_TENDER_ID)
+	response.Value("id").String().IsEqual(s.TenderId)
 	response.Value("name").String().IsEqual("Обновленный Тендер 1")
-	response.Value("organizationId").String().NotEmpty()
+	response.Value("organizationId").String().IsEqual(s.Org.Id)
 	response.Value("serviceType").String().IsEqual("Construction")
-	response.Value("status").String().IsEqual("PUBLISHED")
+	response.Value("status").String().IsEqual("Published")
+	response.Value("version").Number().IsEqual(3)
+
+	app.e.PATCH("/api/tenders/"+s.TenderId+"/edit").
+		WithQuery("username", s.Outsider.Username).
+		WithJSON(map[string]interface{}{"name": "Чужой тендер"}).
+		Expect().
+		Status(http.StatusForbidden)
+
+	// Закрытый тендер не правится, а о недопустимом переходе статуса
+	// ответственный по-прежнему узнает
+	app.setTenderStatus(s.TenderId, "Closed", s.Responsible.Username)
+	app.e.PATCH("/api/tenders/"+s.TenderId+"/edit").
+		WithQuery("username", s.Responsible.Username).
+		WithJSON(map[string]interface{}{"name": "После закрытия"}).
+		Expect().
+		Status(http.StatusForbidden)
+	app.e.PUT("/api/tenders/"+s.TenderId+"/status").
+		WithQuery("username", s.Responsible.Username).
+		WithQuery("status", "Published").
+		Expect().
+		Status(http.StatusConflict)
+}
+
+func TestRollbackTender(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.tenderScenario()
+
+	app.e.PATCH("/api/tenders/"+s.TenderId+"/edit").
+		WithQuery("username", s.Responsible.Username).
+		WithJSON(map[string]interface{}{"name": "Обновленный Тендер 1"}).
+		Expect().
+		Status(http.StatusOK)
+
+	response := app.e.PUT("/api/tenders/"+s.TenderId+"/rollback/1").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+
+	response.Value("name").String().IsEqual("Тендер 1")
+	response.Value("version").Number().IsEqual(3)
+
+	app.e.PUT("/api/tenders/"+s.TenderId+"/rollback/10").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusNotFound)
+	app.e.PUT("/api/tenders/"+s.TenderId+"/rollback/1").
+		WithQuery("username", s.Outsider.Username).
+		Expect().
+		Status(http.StatusForbidden)
+}
+
+func TestGetTenderVersions(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.publishedTenderScenario()
+
+	versions := app.e.GET("/api/tenders/"+s.TenderId+"/versions").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array()
+
+	versions.Length().IsEqual(2)
+	versions.Value(0).Object().Value("changeType").String().IsEqual("CREATED")
+	versions.Value(1).Object().Value("status").String().IsEqual("Published")
+
+	app.e.GET("/api/tenders/"+s.TenderId+"/versions").
+		WithQuery("username", s.Outsider.Username).
+		Expect().
+		Status(http.StatusForbidden)
+}
+
+func TestUnknownRoute(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+
+	app.e.GET("/api/unknown").
+		Expect().
+		Status(http.StatusNotFound)
+}
+
+// Хранилище, у которого истекает дедлайн операции со списком тендеров
+type deadlineStore struct {
+	*db.Memory
+}
+
+func (s deadlineStore) GetTenders(ctx context.Context, serviceTypes []api.TenderServiceType, page db.Page) ([]api.Tender, db.PageInfo, error) {
+	return nil, db.PageInfo{}, fmt.Errorf("query tenders: %w", context.DeadlineExceeded)
+}
+
+func TestStorageTimeout(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t, withStore(func(m *db.Memory) db.Store { return deadlineStore{m} }))
+
+	app.e.GET("/api/tenders").
+		Expect().
+		Status(http.StatusGatewayTimeout)
+}
+
+func TestErrorResponse(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.tenderScenario()
+
+	// Ошибка привязки параметров
+	app.e.GET("/api/tenders").
+		WithQuery("limit", "many").
+		Expect().
+		Status(http.StatusBadRequest).
+		ContentType("application/json").
+		JSON().Object().Value("reason").String().Contains("limit")
+	app.e.PUT("/api/tenders/" + s.TenderId + "/status").
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ContainsKey("reason")
+
+	// Доменные ошибки хранилища
+	app.e.GET("/api/tenders/00000000-0000-0000-0000-000000000000/status").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusNotFound).
+		ContentType("application/json").
+		JSON().Object().Value("reason").String().IsEqual("tender not found")
+	app.e.PATCH("/api/tenders/"+s.TenderId+"/edit").
+		WithQuery("username", "nobody").
+		WithJSON(map[string]interface{}{"name": "Тендер 2"}).
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().Value("reason").String().IsEqual("user not found")
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log/slog"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/metrics"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/openapi"
+)
+
+// Собирает метрики сервиса: запросы по операциям спецификации, показатели
+// данных хранилища и, для PostgreSQL, состояние пула соединений
+func newMetrics(storage db.Store, log *slog.Logger) (*metrics.Metrics, error) {
+	operations, err := openapi.Operations()
+	if err != nil {
+		return nil, err
+	}
+
+	m := metrics.New(operations)
+	m.Registry.MustRegister(metrics.NewStoreCollector(storage, log))
+	if pg, ok := storage.(*db.DB); ok {
+		m.Registry.MustRegister(metrics.NewPoolCollector(pg.Pool))
+	}
+	return m, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+	app.tenderScenario()
+
+	app.e.GET("/api/tenders").
+		Expect().
+		Status(http.StatusOK)
+	app.e.GET("/api/tenders/my").
+		Expect().
+		Status(http.StatusUnauthorized)
+	app.e.GET("/api/tenders/"+s.TenderId+"/status").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusOK)
+	app.e.GET("/api/unknown").
+		Expect().
+		Status(http.StatusNotFound)
+
+	body := app.e.GET("/metrics").
+		Expect().
+		Status(http.StatusOK).
+		Body()
+	// Запросы учитываются по operationId спецификации, в том числе
+	// отклоненные до обработчика
+	body.Contains(`http_requests_total{code="200",operation="getTenders"} 1`)
+	body.Contains(`http_requests_total{code="401",operation="getUserTenders"} 1`)
+	body.Contains(`http_requests_total{code="200",operation="getTenderStatus"} 1`)
+	body.Contains(`http_requests_total{code="404",operation="unmatched"} 1`)
+	body.Contains(`http_request_duration_seconds_count{operation="getTenders"} 1`)
+	// Показатели хранилища: опубликованный тендер с предложением и черновик
+	body.Contains(`tenders{status="CREATED"} 1`)
+	body.Contains(`tenders{status="PUBLISHED"} 1`)
+	body.Contains(`tenders{status="CLOSED"} 0`)
+	body.Contains(`bids_awaiting_decision 1`)
+	body.Contains(`go_goroutines`)
+}
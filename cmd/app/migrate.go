@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/config"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/migrate"
+)
+
+const migrateUsage = `Usage: app migrate [-dry-run] [command]
+
+Commands:
+  up          apply all pending migrations (default)
+  down [N]    roll back the last N applied migrations (default 1)
+  status      list migrations and whether they are applied
+`
+
+// Подкоманда migrate. Возвращает код завершения процесса.
+func runMigrate(ctx context.Context, log *slog.Logger, cfg config.Config, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print SQL of the migrations instead of applying them")
+	flags.Usage = func() { fmt.Fprint(flags.Output(), migrateUsage) }
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	command, steps := "up", 1
+	if flags.NArg() > 0 {
+		command = flags.Arg(0)
+	}
+	if command == "down" && flags.NArg() > 1 {
+		n, err := strconv.Atoi(flags.Arg(1))
+		if err != nil || n < 1 {
+			fmt.Fprintf(os.Stderr, "invalid number of steps %q\n", flags.Arg(1))
+			return 2
+		}
+		steps = n
+	}
+
+	dbConn, err := db.ConnectWithRetry(ctx, cfg.Database.DSN, retryPolicy(cfg.Database), log)
+	if err != nil {
+		log.Error("Failed to connect to database", slog.String("error", err.Error()))
+		return 1
+	}
+	defer dbConn.Close()
+
+	migrator, err := migrate.New(dbConn.Pool, log)
+	if err != nil {
+		log.Error("Failed to load migrations", slog.String("error", err.Error()))
+		return 1
+	}
+
+	switch command {
+	case "up":
+		if *dryRun {
+			pending, err := migrator.Pending(ctx)
+			if err != nil {
+				log.Error("Failed to read applied migrations", slog.String("error", err.Error()))
+				return 1
+			}
+			printMigrations(os.Stdout, pending, true)
+			return 0
+		}
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Error("Failed to apply migrations", slog.String("error", err.Error()))
+			return 1
+		}
+		log.Info("Migrations applied", slog.Int("count", len(applied)))
+
+	case "down":
+		if *dryRun {
+			migrations, err := migrator.Rollbackable(ctx, steps)
+			if err != nil {
+				log.Error("Failed to read applied migrations", slog.String("error", err.Error()))
+				return 1
+			}
+			printMigrations(os.Stdout, migrations, false)
+			return 0
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Error("Failed to roll back migrations", slog.String("error", err.Error()))
+			return 1
+		}
+		log.Info("Migrations rolled back", slog.Int("count", len(rolledBack)))
+
+	case "status":
+		applied, err := migrator.Applied(ctx)
+		if err != nil {
+			log.Error("Failed to read applied migrations", slog.String("error", err.Error()))
+			return 1
+		}
+		for _, m := range migrator.Migrations() {
+			state := "pending"
+			if applied[m.Version] {
+				state = "applied"
+			}
+			fmt.Printf("%-40s %s\n", m, state)
+		}
+
+	default:
+		flags.Usage()
+		return 2
+	}
+	return 0
+}
+
+// Печатает SQL миграций для режима dry-run
+func printMigrations(w io.Writer, migrations []migrate.Migration, up bool) {
+	if len(migrations) == 0 {
+		fmt.Fprintln(w, "-- nothing to do")
+		return
+	}
+	for _, m := range migrations {
+		sql, direction := m.Up, "up"
+		if !up {
+			sql, direction = m.Down, "down"
+		}
+		fmt.Fprintf(w, "-- %s (%s)\n%s\n", m, direction, sql)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCreateOrganizationAndEmployee(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	outsider := app.employee("outsider")
+
+	// Администратор из политики должен быть сотрудником
+	app.e.POST("/api/organizations/new").
+		WithQuery("username", testAdmin).
+		WithJSON(map[string]interface{}{"name": "Avito", "type": "LLC"}).
+		Expect().
+		Status(http.StatusUnauthorized)
+	app.store.SeedEmployee(testAdmin, "Admin", "Admin")
+
+	org := app.e.POST("/api/organizations/new").
+		WithQuery("username", testAdmin).
+		WithJSON(map[string]interface{}{"name": "Avito", "description": "Classifieds", "type": "LLC"}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+	org.Value("name").String().IsEqual("Avito")
+	org.Value("type").String().IsEqual("LLC")
+	orgId := org.Value("id").String().Raw()
+
+	app.e.POST("/api/organizations/new").
+		WithQuery("username", outsider.Username).
+		WithJSON(map[string]interface{}{"name": "Other", "type": "LLC"}).
+		Expect().
+		Status(http.StatusForbidden)
+	app.e.POST("/api/organizations/new").
+		WithQuery("username", testAdmin).
+		WithJSON(map[string]interface{}{"name": "Other", "type": "GmbH"}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	app.e.POST("/api/employees/new").
+		WithQuery("username", testAdmin).
+		WithJSON(map[string]interface{}{"username": "new_user", "firstName": "New", "lastName": "User"}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("id").String().NotEmpty()
+	app.e.POST("/api/employees/new").
+		WithQuery("username", testAdmin).
+		WithJSON(map[string]interface{}{"username": "new_user"}).
+		Expect().
+		Status(http.StatusConflict)
+
+	app.e.GET("/api/employees/new_user").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("firstName").String().IsEqual("New")
+	app.e.GET("/api/employees/nobody").
+		Expect().
+		Status(http.StatusNotFound)
+
+	// Созданная через API организация доступна для тендеров ее ответственных
+	app.e.PUT("/api/organizations/"+orgId+"/responsibles/new_user").
+		WithQuery("username", testAdmin).
+		Expect().
+		Status(http.StatusOK)
+	app.e.POST("/api/tenders/new").
+		WithJSON(map[string]interface{}{
+			"name":            "Тендер 1",
+			"description":     "Описание тендера",
+			"serviceType":     "Construction",
+			"organizationId":  orgId,
+			"creatorUsername": "new_user",
+		}).
+		Expect().
+		Status(http.StatusOK)
+}
+
+func TestGetOrganizations(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	org := app.organization()
+	app.organization()
+
+	app.e.GET("/api/organizations").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(2)
+	app.e.GET("/api/organizations").
+		WithQuery("limit", 1).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(1)
+
+	app.e.GET("/api/organizations/" + org.Id).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("name").String().IsEqual(org.Name)
+	app.e.GET("/api/organizations/00000000-0000-0000-0000-000000000000").
+		Expect().
+		Status(http.StatusNotFound)
+	app.e.GET("/api/organizations/not-a-uuid").
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func TestEditOrganization(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	responsible := app.employee("responsible")
+	outsider := app.employee("outsider")
+	org := app.organization(responsible)
+
+	response := app.e.PATCH("/api/organizations/"+org.Id+"/edit").
+		WithQuery("username", responsible.Username).
+		WithJSON(map[string]interface{}{"description": "Новое описание"}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+	response.Value("name").String().IsEqual(org.Name)
+	response.Value("description").String().IsEqual("Новое описание")
+
+	app.e.PATCH("/api/organizations/"+org.Id+"/edit").
+		WithQuery("username", outsider.Username).
+		WithJSON(map[string]interface{}{"name": "Чужая организация"}).
+		Expect().
+		Status(http.StatusForbidden)
+	app.e.PATCH("/api/organizations/"+org.Id+"/edit").
+		WithQuery("username", responsible.Username).
+		WithJSON(map[string]interface{}{}).
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func TestOrganizationResponsibles(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	app.store.SeedEmployee(testAdmin, "Admin", "Admin")
+	responsible := app.employee("responsible")
+	employee := app.employee("employee")
+	org := app.organization(responsible)
+	other := app.organization()
+
+	app.e.PUT("/api/organizations/"+org.Id+"/responsibles/"+employee.Username).
+		WithQuery("username", responsible.Username).
+		Expect().
+		Status(http.StatusForbidden)
+
+	app.e.PUT("/api/organizations/"+org.Id+"/responsibles/"+employee.Username).
+		WithQuery("username", testAdmin).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("username").String().IsEqual(employee.Username)
+	app.e.PUT("/api/organizations/"+other.Id+"/responsibles/"+employee.Username).
+		WithQuery("username", testAdmin).
+		Expect().
+		Status(http.StatusConflict)
+
+	app.e.GET("/api/organizations/" + org.Id + "/responsibles").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(2)
+
+	app.e.DELETE("/api/organizations/"+org.Id+"/responsibles/"+employee.Username).
+		WithQuery("username", testAdmin).
+		Expect().
+		Status(http.StatusNoContent)
+	app.e.DELETE("/api/organizations/"+org.Id+"/responsibles/"+employee.Username).
+		WithQuery("username", testAdmin).
+		Expect().
+		Status(http.StatusNotFound)
+}
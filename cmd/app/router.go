@@ -0,0 +1,92 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/auth"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/handlers"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/metrics"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/openapi"
+)
+
+// Собирает HTTP-роутер сервиса поверх хранилища storage.
+// authn может быть nil, тогда аутентификация по токенам отключена.
+// validator может быть nil, тогда запросы не проверяются по спецификации.
+// m может быть nil, тогда метрики не собираются и /metrics не отдается.
+func newRouter(storage db.Store, authn *auth.Authenticator, validator *openapi.Validator, health *handlers.Health, m *metrics.Metrics, log *slog.Logger) http.Handler {
+	r := chi.NewRouter()
+	if m != nil {
+		r.Method(http.MethodGet, "/metrics", m.Handler())
+	}
+
+	myServer := handlers.NewServer(storage, log)
+	myServer.Stopping = health.Stopping()
+
+	r.Route("/api", func(apiRouter chi.Router) {
+		var middlewares []api.MiddlewareFunc
+		// Идентификатор запроса нужен во всех записях журнала, включая
+		// сделанные другими middleware
+		apiRouter.Use(handlers.RequestLogger(log))
+		// Учитываются и запросы, отклоненные аутентификацией и проверкой
+		if m != nil {
+			apiRouter.Use(m.Middleware)
+		}
+		if authn != nil {
+			apiRouter.Use(authn.Middleware)
+			middlewares = append(middlewares, authn.RequireScopes)
+		}
+		// Проверка выполняется после аутентификации, которая подставляет
+		// имя пользователя из токена в параметры запроса
+		if validator != nil {
+			apiRouter.Use(validator.Middleware)
+		}
+
+		apiRouter.Get("/health/live", health.Live)
+		apiRouter.Get("/health/ready", health.Ready)
+
+		// Операции вне спецификации не получают api.BearerAuthScopes,
+		// поэтому строгий режим для них включается на уровне роутера
+		apiRouter.Group(func(r chi.Router) {
+			if authn != nil {
+				r.Use(authn.RequireIdentity)
+			}
+
+			r.Get("/tenders/{tenderId}/versions", myServer.GetTenderVersions)
+			r.Get("/tenders/{tenderId}/events", myServer.StreamTenderEvents)
+			r.Get("/bids/search", myServer.SearchBids)
+
+			r.Get("/organizations", myServer.GetOrganizations)
+			r.Post("/organizations/new", myServer.CreateOrganization)
+			r.Get("/organizations/{organizationId}", myServer.GetOrganization)
+			r.Patch("/organizations/{organizationId}/edit", myServer.EditOrganization)
+			r.Get("/organizations/{organizationId}/responsibles", myServer.GetOrganizationResponsibles)
+			r.Put("/organizations/{organizationId}/responsibles/{employeeUsername}", myServer.AddOrganizationResponsible)
+			r.Delete("/organizations/{organizationId}/responsibles/{employeeUsername}", myServer.RemoveOrganizationResponsible)
+			r.Post("/employees/new", myServer.CreateEmployee)
+			r.Get("/employees/{employeeUsername}", myServer.GetEmployee)
+
+			r.Get("/webhooks", myServer.GetWebhooks)
+			r.Post("/webhooks/new", myServer.CreateWebhook)
+			r.Get("/webhooks/{webhookId}", myServer.GetWebhook)
+			r.Patch("/webhooks/{webhookId}/edit", myServer.EditWebhook)
+			r.Delete("/webhooks/{webhookId}", myServer.DeleteWebhook)
+			r.Get("/webhooks/{webhookId}/deliveries", myServer.GetWebhookDeliveries)
+		})
+
+		// Маршруты спецификации регистрируются прямо в apiRouter. Монтировать
+		// результат в apiRouter нельзя: это тот же роутер, и запрос на
+		// неизвестный путь зацикливается.
+		api.HandlerWithOptions(myServer, api.ChiServerOptions{
+			BaseRouter:       apiRouter,
+			Middlewares:      middlewares,
+			ErrorHandlerFunc: handlers.ParamError,
+		})
+	})
+
+	return r
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Событие потока Server-Sent Events
+type sseEvent struct {
+	id   string
+	typ  string
+	data string
+}
+
+// Поток событий тендера, прочитанный в фоне
+type sseStream struct {
+	t      *testing.T
+	events chan sseEvent
+}
+
+// Открывает ленту событий тендера от имени username. Пустой lastEventId —
+// подключение без Last-Event-ID.
+func (a *testApp) openTenderEvents(tenderId string, username string, lastEventId string) *sseStream {
+	a.t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, a.url+"/api/tenders/"+tenderId+"/events?username="+url.QueryEscape(username), nil)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	a.t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		a.t.Fatalf("GET events as %s: %d %s", username, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	s := &sseStream{t: a.t, events: make(chan sseEvent, 16)}
+	go func() {
+		defer close(s.events)
+		var e sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ": ")
+			switch field {
+			case "id":
+				e.id = value
+			case "event":
+				e.typ = value
+			case "data":
+				e.data = value
+			case "":
+				if e.typ != "" {
+					s.events <- e
+				}
+				e = sseEvent{}
+			}
+		}
+	}()
+	return s
+}
+
+// Следующее событие потока
+func (s *sseStream) next() sseEvent {
+	s.t.Helper()
+	select {
+	case e, ok := <-s.events:
+		if !ok {
+			s.t.Fatal("event stream closed")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		s.t.Fatal("no event within 5s")
+	}
+	return sseEvent{}
+}
+
+func (s *sseStream) expect(types ...string) []sseEvent {
+	s.t.Helper()
+	var got []sseEvent
+	for _, typ := range types {
+		e := s.next()
+		if e.typ != typ {
+			s.t.Fatalf("event %d = %s %s, want %s", len(got)+1, e.typ, e.data, typ)
+		}
+		got = append(got, e)
+	}
+	return got
+}
+
+func TestTenderEvents(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.bidScenario()
+
+	app.e.GET("/api/tenders/" + s.TenderId + "/events").
+		Expect().
+		Status(http.StatusUnauthorized)
+	app.e.GET("/api/tenders/"+uuid.NewString()+"/events").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusNotFound)
+	app.e.GET("/api/tenders/"+s.TenderId+"/events").
+		WithQuery("username", s.Responsible.Username).
+		WithHeader("Last-Event-ID", "abc").
+		Expect().
+		Status(http.StatusBadRequest)
+	// Неопубликованный тендер видят только ответственные
+	draft := app.tenderScenario()
+	app.e.GET("/api/tenders/"+draft.TenderId+"/events").
+		WithQuery("username", draft.Outsider.Username).
+		Expect().
+		Status(http.StatusForbidden)
+
+	// С Last-Event-ID лента начинается с пропущенных событий: ответственный
+	// видит публикацию тендера и предложения, сторонний пользователь —
+	// только публикацию тендера
+	responsible := app.openTenderEvents(s.TenderId, s.Responsible.Username, "0")
+	history := responsible.expect("tender.status_changed", "bid.status_changed")
+	outsider := app.openTenderEvents(s.TenderId, s.Outsider.Username, "0")
+	outsider.expect("tender.status_changed")
+
+	// Переподключение с последнего полученного события не повторяет его
+	live := app.openTenderEvents(s.TenderId, s.Responsible.Username, history[0].id)
+	if e := live.next(); e.id != history[1].id {
+		t.Fatalf("resumed stream starts with %s %s, want %s", e.id, e.typ, history[1].id)
+	}
+
+	app.e.PUT("/api/bids/"+s.BidId+"/submit_decision").
+		WithQuery("username", s.Responsible.Username).
+		WithQuery("decision", "Approved").
+		Expect().
+		Status(http.StatusOK)
+
+	live.expect("bid.decision_submitted", "bid.status_changed", "tender.status_changed")
+	responsible.expect("bid.decision_submitted", "bid.status_changed", "tender.status_changed")
+	// Закрытый тендер стороннему пользователю не виден: решение ему не
+	// показывается, а поток завершается ошибкой
+	if e := outsider.next(); e.typ != "error" || !strings.Contains(e.data, "forbidden") {
+		t.Errorf("outsider got %s %s after tender was closed, want error", e.typ, e.data)
+	}
+
+	// При остановке сервера потоки завершаются
+	app.health.Drain()
+	select {
+	case _, ok := <-live.events:
+		if ok {
+			t.Error("unexpected event after drain")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("stream is still open after drain")
+	}
+}
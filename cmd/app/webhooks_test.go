@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/netguard"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/webhooks"
+)
+
+// Получатель вебхуков, который проверяет подпись и отвечает status
+type webhookReceiver struct {
+	t      *testing.T
+	mu     sync.Mutex
+	secret string
+	status int
+	got    []string // типы принятых событий
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if err := webhooks.Verify(rcv.secret, r.Header.Get(webhooks.SignatureHeader), body, time.Now(), time.Minute); err != nil {
+		rcv.t.Errorf("signature of %s: %v", r.Header.Get("X-Event-Type"), err)
+	}
+	if rcv.status == http.StatusOK {
+		rcv.got = append(rcv.got, r.Header.Get("X-Event-Type"))
+	}
+	w.WriteHeader(rcv.status)
+}
+
+func (rcv *webhookReceiver) set(status int) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.status = status
+}
+
+// Переносит события из outbox в очередь вебхуков и выполняет один проход
+// отправки, возвращая число попыток
+func (a *testApp) deliverWebhooks(worker *webhooks.Worker) int {
+	a.t.Helper()
+
+	dispatcher := &events.Dispatcher{Outbox: a.store, Sinks: []events.Sink{webhooks.Sink{Store: a.store}}, Log: worker.Log}
+	if _, err := dispatcher.Flush(context.Background()); err != nil {
+		a.t.Fatalf("Flush: %v", err)
+	}
+	n, err := worker.RunOnce(context.Background())
+	if err != nil {
+		a.t.Fatalf("RunOnce: %v", err)
+	}
+	return n
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Parallel()
+	app := newTestApp(t)
+	s := app.tenderScenario()
+
+	receiver := &webhookReceiver{t: t, status: http.StatusOK}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	// Тестовый получатель слушает loopback, на который вебхуки без
+	// разрешения не регистрируются и не отправляются
+	app.e.POST("/api/webhooks/new").
+		WithQuery("username", s.Responsible.Username).
+		WithJSON(map[string]interface{}{"organizationId": s.Org.Id, "url": srv.URL}).
+		Expect().
+		Status(http.StatusBadRequest)
+	allowed, err := netguard.ParseAllowlist("127.0.0.1")
+	if err != nil {
+		t.Fatalf("ParseAllowlist: %v", err)
+	}
+	app.store.WebhookHosts = allowed
+
+	worker := &webhooks.Worker{
+		Store:        app.store,
+		Client:       allowed.Client(time.Second),
+		Backoff:      time.Nanosecond,
+		DisableAfter: 2,
+		Log:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	// События до регистрации вебхука ему не отправляются
+	app.deliverWebhooks(worker)
+
+	app.e.POST("/api/webhooks/new").
+		WithQuery("username", s.Outsider.Username).
+		WithJSON(map[string]interface{}{"organizationId": s.Org.Id, "url": srv.URL}).
+		Expect().
+		Status(http.StatusForbidden)
+	app.e.POST("/api/webhooks/new").
+		WithQuery("username", s.Responsible.Username).
+		WithJSON(map[string]interface{}{"organizationId": s.Org.Id, "url": "ftp://example.com"}).
+		Expect().
+		Status(http.StatusBadRequest)
+	app.e.POST("/api/webhooks/new").
+		WithQuery("username", s.Responsible.Username).
+		WithJSON(map[string]interface{}{"organizationId": s.Org.Id, "url": srv.URL, "events": []string{"tender.deleted"}}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	created := app.e.POST("/api/webhooks/new").
+		WithQuery("username", s.Responsible.Username).
+		WithJSON(map[string]interface{}{"organizationId": s.Org.Id, "url": srv.URL, "events": []string{"tender.status_changed"}}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+	created.Value("enabled").Boolean().IsTrue()
+	webhookId := created.Value("id").String().Raw()
+	receiver.secret = created.Value("secret").String().NotEmpty().Raw()
+
+	list := app.e.GET("/api/webhooks").
+		WithQuery("username", s.Responsible.Username).
+		WithQuery("organizationId", s.Org.Id).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Array()
+	list.Length().IsEqual(1)
+	list.Value(0).Object().NotContainsKey("secret")
+
+	// Правка тендера не входит в типы событий вебхука
+	app.setTenderStatus(s.TenderId, "Published", s.Responsible.Username)
+	app.e.PATCH("/api/tenders/"+s.TenderId+"/edit").
+		WithQuery("username", s.Responsible.Username).
+		WithJSON(map[string]interface{}{"name": "Тендер 2"}).
+		Expect().
+		Status(http.StatusOK)
+	if n := app.deliverWebhooks(worker); n != 1 || len(receiver.got) != 1 || receiver.got[0] != "tender.status_changed" {
+		t.Fatalf("delivered %d attempts, receiver got %v; want one tender.status_changed", n, receiver.got)
+	}
+
+	delivery := app.e.GET("/api/webhooks/"+webhookId+"/deliveries").
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Array().
+		Value(0).
+		Object()
+	delivery.Value("status").String().IsEqual("DELIVERED")
+	delivery.Value("attempts").Number().IsEqual(1)
+	delivery.Value("responseStatus").Number().IsEqual(http.StatusOK)
+
+	// Неудачные попытки повторяются, после DisableAfter неудач подряд
+	// вебхук отключается
+	receiver.set(http.StatusInternalServerError)
+	app.setTenderStatus(s.TenderId, "Closed", s.Responsible.Username)
+	if n := app.deliverWebhooks(worker); n != 1 {
+		t.Fatalf("first attempt: %d attempts, want 1", n)
+	}
+	if n := app.deliverWebhooks(worker); n != 1 {
+		t.Fatalf("retry: %d attempts, want 1", n)
+	}
+	if n := app.deliverWebhooks(worker); n != 0 {
+		t.Fatalf("disabled webhook: %d attempts, want 0", n)
+	}
+
+	disabled := app.e.GET("/api/webhooks/"+webhookId).
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+	disabled.Value("enabled").Boolean().IsFalse()
+	disabled.Value("consecutiveFailures").Number().IsEqual(2)
+	disabled.Value("disabledAt").String().NotEmpty()
+
+	pending := app.e.GET("/api/webhooks/"+webhookId+"/deliveries").
+		WithQuery("username", s.Responsible.Username).
+		WithQuery("status", "PENDING").
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Array()
+	pending.Length().IsEqual(1)
+	pending.Value(0).Object().Value("attempts").Number().IsEqual(2)
+	pending.Value(0).Object().Value("responseStatus").Number().IsEqual(http.StatusInternalServerError)
+
+	// После включения доставка продолжается
+	receiver.set(http.StatusOK)
+	app.e.PATCH("/api/webhooks/"+webhookId+"/edit").
+		WithQuery("username", s.Responsible.Username).
+		WithJSON(map[string]interface{}{"enabled": true}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		Value("consecutiveFailures").Number().IsEqual(0)
+	if n := app.deliverWebhooks(worker); n != 1 || len(receiver.got) != 2 {
+		t.Fatalf("after enabling: %d attempts, receiver got %v", n, receiver.got)
+	}
+
+	app.e.DELETE("/api/webhooks/"+webhookId).
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusNoContent)
+	app.e.GET("/api/webhooks/"+webhookId).
+		WithQuery("username", s.Responsible.Username).
+		Expect().
+		Status(http.StatusNotFound)
+}
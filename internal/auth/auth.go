@@ -0,0 +1,167 @@
+// Package auth проверяет bearer-токены (JWT с подписью HMAC) и
+// сопоставляет их с сотрудником из таблицы employee.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+)
+
+// Идентификатор ключа, если в токене нет заголовка kid
+const DefaultKeyId = "default"
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrUnknownUser  = errors.New("token subject is not a known employee")
+)
+
+// Ключи подписи по идентификатору (заголовок kid)
+type Keys map[string][]byte
+
+// Разбирает ключи вида "kid1:secret1,kid2:secret2".
+// Одиночный секрет без идентификатора становится ключом DefaultKeyId.
+func ParseKeys(s string) (Keys, error) {
+	keys := Keys{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kid, secret, ok := strings.Cut(part, ":")
+		if !ok {
+			kid, secret = DefaultKeyId, part
+		}
+		if kid == "" || secret == "" {
+			return nil, fmt.Errorf("invalid key %q, expected KID:SECRET", part)
+		}
+		if _, exists := keys[kid]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", kid)
+		}
+		keys[kid] = []byte(secret)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+	return keys, nil
+}
+
+// Аутентифицированный пользователь
+type Identity struct {
+	UserId   string
+	Username string
+}
+
+// Источник сотрудников для сопоставления токена с пользователем
+type EmployeeResolver interface {
+	GetEmployeeByUsername(ctx context.Context, username string) (db.Employee, error)
+}
+
+// Проверка токенов и сопоставление их с сотрудниками
+type Authenticator struct {
+	keys      Keys
+	issuer    string
+	required  bool
+	employees EmployeeResolver
+	log       *slog.Logger
+}
+
+type Options struct {
+	// Ожидаемый издатель токена (claim iss), пустая строка - не проверяется
+	Issuer string
+	// Запрещать вызовы операций с bearerAuth без токена
+	Required bool
+	// Журнал отклоненных токенов и ошибок проверки
+	Log *slog.Logger
+}
+
+func NewAuthenticator(keys Keys, employees EmployeeResolver, opts Options) *Authenticator {
+	return &Authenticator{
+		keys:      keys,
+		issuer:    opts.Issuer,
+		required:  opts.Required,
+		employees: employees,
+		log:       opts.Log,
+	}
+}
+
+// Проверяет подпись и срок действия токена и возвращает пользователя из claim sub
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (Identity, error) {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if a.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(a.issuer))
+	}
+
+	claims := jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, &claims, a.key, parserOpts...)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return Identity{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	employee, err := a.employees.GetEmployeeByUsername(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			return Identity{}, ErrUnknownUser
+		}
+		return Identity{}, err
+	}
+
+	return Identity{UserId: employee.Id, Username: employee.Username}, nil
+}
+
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = DefaultKeyId
+	}
+	key, ok := a.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// Выпускает токен для пользователя. Используется в тестах и для
+// выдачи токенов из доверенных инструментов.
+func NewToken(keys Keys, kid string, username string, ttl time.Duration) (string, error) {
+	key, ok := keys[kid]
+	if !ok {
+		return "", fmt.Errorf("unknown key id %q", kid)
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   username,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	})
+	if kid != DefaultKeyId {
+		token.Header["kid"] = kid
+	}
+	return token.SignedString(key)
+}
+
+type identityKey struct{}
+
+// Возвращает аутентифицированного пользователя запроса, если он есть
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+func withIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
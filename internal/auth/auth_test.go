@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+)
+
+type fakeEmployees map[string]db.Employee
+
+func (f fakeEmployees) GetEmployeeByUsername(ctx context.Context, username string) (db.Employee, error) {
+	e, ok := f[username]
+	if !ok {
+		return db.Employee{}, db.ErrUserNotFound
+	}
+	return e, nil
+}
+
+func newTestAuthenticator(t *testing.T, opts Options) (*Authenticator, Keys) {
+	t.Helper()
+	keys, err := ParseKeys("secret, next:other-secret")
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	employees := fakeEmployees{
+		"user1": {Id: "550e8400-e29b-41d4-a716-446655440000", Username: "user1"},
+	}
+	opts.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewAuthenticator(keys, employees, opts), keys
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, keys := newTestAuthenticator(t, Options{})
+
+	for _, kid := range []string{DefaultKeyId, "next"} {
+		token, err := NewToken(keys, kid, "user1", time.Minute)
+		if err != nil {
+			t.Fatalf("NewToken: %v", err)
+		}
+		identity, err := a.Authenticate(context.Background(), token)
+		if err != nil {
+			t.Fatalf("kid %s: unexpected error %v", kid, err)
+		}
+		if identity.Username != "user1" || identity.UserId != "550e8400-e29b-41d4-a716-446655440000" {
+			t.Errorf("kid %s: identity = %+v", kid, identity)
+		}
+	}
+
+	expired, _ := NewToken(keys, DefaultKeyId, "user1", -time.Minute)
+	if _, err := a.Authenticate(context.Background(), expired); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expired token: error = %v, want ErrInvalidToken", err)
+	}
+
+	foreign, _ := NewToken(Keys{DefaultKeyId: []byte("wrong")}, DefaultKeyId, "user1", time.Minute)
+	if _, err := a.Authenticate(context.Background(), foreign); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("wrong key: error = %v, want ErrInvalidToken", err)
+	}
+
+	unknown, _ := NewToken(keys, DefaultKeyId, "ghost", time.Minute)
+	if _, err := a.Authenticate(context.Background(), unknown); !errors.Is(err, ErrUnknownUser) {
+		t.Errorf("unknown user: error = %v, want ErrUnknownUser", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a, keys := newTestAuthenticator(t, Options{})
+	token, _ := NewToken(keys, DefaultKeyId, "user1", time.Minute)
+
+	var gotUsername string
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUsername = r.URL.Query().Get("username")
+		if _, ok := IdentityFromContext(r.Context()); !ok {
+			t.Error("identity is missing in the request context")
+		}
+	}))
+
+	cases := []struct {
+		query string
+		code  int
+	}{
+		{"", http.StatusOK},
+		{"?username=user1", http.StatusOK},
+		{"?username=user2", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/bids/my"+c.query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		gotUsername = ""
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != c.code {
+			t.Errorf("%q: code = %d, want %d", c.query, rec.Code, c.code)
+		}
+		if c.code == http.StatusOK && gotUsername != "user1" {
+			t.Errorf("%q: username = %q, want user1", c.query, gotUsername)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/bids/my", nil)
+	req.Header.Set("Authorization", "Basic dXNlcjE6")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("non-bearer scheme: code = %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Query-параметры, которые обозначают пользователя, выполняющего запрос.
+// При наличии токена они заполняются из него и не могут указывать на другого пользователя.
+var identityParams = []string{"username", "requesterUsername"}
+
+// Middleware уровня роутера: проверяет заголовок Authorization и кладет
+// пользователя в контекст запроса. Должна выполняться до привязки параметров
+// в api.ServerInterfaceWrapper, чтобы параметр username стал необязательным.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			writeError(w, http.StatusUnauthorized, "authorization header must use the Bearer scheme")
+			return
+		}
+
+		identity, err := a.Authenticate(r.Context(), strings.TrimSpace(token))
+		if err != nil {
+			if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrUnknownUser) {
+				a.log.InfoContext(r.Context(), "Rejected bearer token", sl.Err(err))
+				writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			a.log.ErrorContext(r.Context(), "Error authenticating request", sl.Err(err))
+			if db.IsTimeout(err) {
+				writeError(w, http.StatusGatewayTimeout, "storage timeout")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		query := r.URL.Query()
+		for _, name := range identityParams {
+			value := query.Get(name)
+			if value != "" && value != identity.Username {
+				writeError(w, http.StatusForbidden, name+" does not match the authenticated user")
+				return
+			}
+		}
+		// Операции игнорируют лишние query-параметры, поэтому их можно выставлять всегда
+		for _, name := range identityParams {
+			query.Set(name, identity.Username)
+		}
+
+		r = r.WithContext(withIdentity(r.Context(), identity))
+		u := *r.URL
+		u.RawQuery = query.Encode()
+		r.URL = &u
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware операций api.ServerInterfaceWrapper. Обертка кладет в контекст
+// api.BearerAuthScopes для операций, защищенных bearerAuth; в строгом режиме
+// такие операции без токена отклоняются.
+func (a *Authenticator) RequireScopes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, protected := r.Context().Value(api.BearerAuthScopes).([]string); protected && a.required {
+			if _, ok := IdentityFromContext(r.Context()); !ok {
+				writeError(w, http.StatusUnauthorized, "bearer token is required")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware маршрутов, которые не описаны в спецификации и не проходят
+// через api.ServerInterfaceWrapper. Все они выполняются от имени
+// пользователя, поэтому в строгом режиме запросы без токена отклоняются.
+func (a *Authenticator) RequireIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := IdentityFromContext(r.Context()); !ok && a.required {
+			writeError(w, http.StatusUnauthorized, "bearer token is required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeError(w http.ResponseWriter, code int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(api.ErrorResponse{Reason: reason})
+}
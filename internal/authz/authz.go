@@ -0,0 +1,242 @@
+// Package authz описывает декларативные политики доступа к тендерам и
+// предложениям. Политика сопоставляет действию список правил: роли
+// пользователя и статусы объекта, при которых действие разрешено.
+// Роли пользователя вычисляет хранилище, сама проверка не обращается к базе.
+package authz
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Роль пользователя по отношению к объекту
+type Role string
+
+const (
+	RoleOrgResponsible Role = "org-responsible"
+	RoleBidAuthor      Role = "bid-author"
+	RoleViewer         Role = "viewer"
+	RoleAdmin          Role = "admin"
+)
+
+var knownRoles = map[Role]bool{
+	RoleOrgResponsible: true,
+	RoleBidAuthor:      true,
+	RoleViewer:         true,
+	RoleAdmin:          true,
+}
+
+// Действие с тендером, предложением, организацией или сотрудником
+type Action string
+
+const (
+	TenderCreate   Action = "tender.create"
+	TenderView     Action = "tender.view"
+	TenderEdit     Action = "tender.edit"
+	TenderRollback Action = "tender.rollback"
+	TenderHistory  Action = "tender.history"
+	TenderPublish  Action = "tender.publish"
+	TenderClose    Action = "tender.close"
+	TenderRevert   Action = "tender.revert"
+	TenderBids     Action = "tender.bids"
+	TenderReviews  Action = "tender.reviews"
+
+	BidCreate   Action = "bid.create"
+	BidView     Action = "bid.view"
+	BidEdit     Action = "bid.edit"
+	BidRollback Action = "bid.rollback"
+	BidStatus   Action = "bid.status"
+	BidDecide   Action = "bid.decide"
+	BidFeedback Action = "bid.feedback"
+
+	OrganizationCreate       Action = "organization.create"
+	OrganizationUpdate       Action = "organization.update"
+	OrganizationResponsibles Action = "organization.responsibles"
+	OrganizationWebhooks     Action = "organization.webhooks"
+	EmployeeCreate           Action = "employee.create"
+)
+
+var knownActions = map[Action]bool{
+	TenderCreate:   true,
+	TenderView:     true,
+	TenderEdit:     true,
+	TenderRollback: true,
+	TenderHistory:  true,
+	TenderPublish:  true,
+	TenderClose:    true,
+	TenderRevert:   true,
+	TenderBids:     true,
+	TenderReviews:  true,
+	BidCreate:      true,
+	BidView:        true,
+	BidEdit:        true,
+	BidRollback:    true,
+	BidStatus:      true,
+	BidDecide:      true,
+	BidFeedback:    true,
+
+	OrganizationCreate:       true,
+	OrganizationUpdate:       true,
+	OrganizationResponsibles: true,
+	OrganizationWebhooks:     true,
+	EmployeeCreate:           true,
+}
+
+// Пользователь и его роли по отношению к конкретному объекту
+type Subject struct {
+	Username string
+	Roles    []Role
+}
+
+// Есть ли у пользователя роль
+func (s Subject) Has(role Role) bool {
+	for _, r := range s.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Правило политики: действие разрешено пользователю с одной из ролей,
+// если статус объекта входит в Statuses (пустой список - любой статус).
+type Rule struct {
+	Roles    []Role   `yaml:"roles"`
+	Statuses []string `yaml:"statuses,omitempty"`
+}
+
+func (r Rule) matches(s Subject, status string) bool {
+	if len(r.Statuses) > 0 {
+		found := false
+		for _, st := range r.Statuses {
+			if st == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, role := range r.Roles {
+		if s.Has(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Политика доступа
+type Policy struct {
+	admins map[string]bool
+	rules  map[Action][]Rule
+}
+
+type policyFile struct {
+	Admins  []string          `yaml:"admins"`
+	Actions map[Action][]Rule `yaml:"actions"`
+}
+
+//go:embed policy.yaml
+var defaultPolicy []byte
+
+// Политика по умолчанию, встроенная в приложение (policy.yaml)
+func Default() *Policy {
+	p, err := Parse(defaultPolicy)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default policy: %v", err))
+	}
+	return p
+}
+
+// Загружает политику из YAML-файла
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Разбирает политику в формате YAML. Неизвестные роли и действия считаются
+// ошибкой, чтобы опечатка в конфигурации не открывала и не закрывала доступ
+// незаметно. Действие без правил запрещено всем.
+func Parse(data []byte) (*Policy, error) {
+	var f policyFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("could not parse policy: %w", err)
+	}
+
+	p := &Policy{
+		admins: map[string]bool{},
+		rules:  map[Action][]Rule{},
+	}
+	for _, username := range f.Admins {
+		if username == "" {
+			return nil, errors.New("empty admin username")
+		}
+		p.admins[username] = true
+	}
+	for action, rules := range f.Actions {
+		if !knownActions[action] {
+			return nil, fmt.Errorf("unknown action %q", action)
+		}
+		for i, rule := range rules {
+			if len(rule.Roles) == 0 {
+				return nil, fmt.Errorf("action %s: rule %d has no roles", action, i+1)
+			}
+			for _, role := range rule.Roles {
+				if !knownRoles[role] {
+					return nil, fmt.Errorf("action %s: unknown role %q", action, role)
+				}
+			}
+			for j, status := range rule.Statuses {
+				rule.Statuses[j] = strings.ToUpper(strings.TrimSpace(status))
+			}
+			p.rules[action] = append(p.rules[action], rule)
+		}
+	}
+	return p, nil
+}
+
+// Является ли пользователь администратором
+func (p *Policy) IsAdmin(username string) bool {
+	return p.admins[username]
+}
+
+// Имена администраторов в алфавитном порядке
+func (p *Policy) Admins() []string {
+	admins := make([]string, 0, len(p.admins))
+	for username := range p.admins {
+		admins = append(admins, username)
+	}
+	sort.Strings(admins)
+	return admins
+}
+
+// Разрешено ли действие пользователю для объекта в статусе status.
+// Роль admin добавляется пользователям из списка администраторов.
+func (p *Policy) Allowed(action Action, s Subject, status string) bool {
+	if p.IsAdmin(s.Username) && !s.Has(RoleAdmin) {
+		s.Roles = append(append([]Role(nil), s.Roles...), RoleAdmin)
+	}
+	for _, rule := range p.rules[action] {
+		if rule.matches(s, status) {
+			return true
+		}
+	}
+	return false
+}
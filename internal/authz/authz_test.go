@@ -0,0 +1,105 @@
+package authz
+
+import "testing"
+
+type policyCase struct {
+	action  Action
+	subject Subject
+	status  string
+	want    bool
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	p := Default()
+
+	responsible := Subject{Username: "user1", Roles: []Role{RoleViewer, RoleOrgResponsible}}
+	author := Subject{Username: "user2", Roles: []Role{RoleViewer, RoleBidAuthor}}
+	viewer := Subject{Username: "user3", Roles: []Role{RoleViewer}}
+	admin := Subject{Username: "root", Roles: []Role{RoleAdmin}}
+
+	cases := []policyCase{
+		{TenderView, viewer, "PUBLISHED", true},
+		{TenderView, viewer, "CREATED", false},
+		{TenderView, responsible, "CREATED", true},
+		{TenderEdit, responsible, "PUBLISHED", true},
+		{TenderEdit, viewer, "PUBLISHED", false},
+		{TenderHistory, viewer, "PUBLISHED", false},
+		{BidEdit, author, "CREATED", true},
+		{BidEdit, responsible, "CREATED", false},
+		{BidView, responsible, "PUBLISHED", true},
+		{BidView, responsible, "REJECTED", true},
+		{BidView, responsible, "CREATED", false},
+		{BidView, responsible, "CANCELED", false},
+		{BidView, author, "CREATED", true},
+		{BidView, viewer, "PUBLISHED", false},
+		{BidDecide, responsible, "PUBLISHED", true},
+		{BidDecide, author, "PUBLISHED", false},
+
+		// Закрытый тендер, предложение с решением или отмененное не меняются
+		{TenderRollback, responsible, "CREATED", true},
+		{TenderEdit, responsible, "CLOSED", false},
+		{TenderEdit, admin, "PUBLISHED", true},
+		{TenderEdit, admin, "CLOSED", false},
+		{TenderRollback, responsible, "CLOSED", false},
+		{TenderRollback, admin, "CLOSED", false},
+		{BidEdit, author, "PUBLISHED", true},
+		{BidStatus, author, "PUBLISHED", true},
+		{BidRollback, author, "CREATED", true},
+	}
+	for _, status := range []string{"APPROVED", "REJECTED", "CANCELED"} {
+		for _, action := range []Action{BidEdit, BidRollback, BidStatus} {
+			for _, subject := range []Subject{author, admin} {
+				cases = append(cases, policyCase{action, subject, status, false})
+			}
+		}
+	}
+	for _, c := range cases {
+		if got := p.Allowed(c.action, c.subject, c.status); got != c.want {
+			t.Errorf("%s by %v in %s = %v, want %v", c.action, c.subject.Roles, c.status, got, c.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(`
+admins: [root]
+actions:
+  tender.view:
+    - roles: [viewer]
+      statuses: [published, closed]
+  tender.edit:
+    - roles: [admin]
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	viewer := Subject{Username: "user1", Roles: []Role{RoleViewer}}
+	if !p.Allowed(TenderView, viewer, "CLOSED") {
+		t.Error("statuses must be case-insensitive")
+	}
+	if p.Allowed(TenderEdit, viewer, "CREATED") {
+		t.Error("tender.edit must be allowed only for admins")
+	}
+	if !p.Allowed(TenderEdit, Subject{Username: "root"}, "CREATED") {
+		t.Error("users from admins must have the admin role")
+	}
+	if p.Allowed(BidEdit, Subject{Username: "root"}, "CREATED") {
+		t.Error("actions without rules must be denied")
+	}
+	if admins := p.Admins(); len(admins) != 1 || admins[0] != "root" {
+		t.Errorf("Admins() = %v, want [root]", admins)
+	}
+
+	invalid := []string{
+		"actions:\n  tender.delete:\n    - roles: [admin]\n",
+		"actions:\n  tender.view:\n    - roles: [owner]\n",
+		"actions:\n  tender.view:\n    - statuses: [PUBLISHED]\n",
+		"admin: [root]\n",
+	}
+	for _, data := range invalid {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("policy %q must be rejected", data)
+		}
+	}
+}
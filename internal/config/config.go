@@ -0,0 +1,487 @@
+// Package config загружает настройки приложения. Значения берутся из
+// переменных окружения, а если задан CONFIG_FILE, то и из файла в формате
+// YAML (.yaml, .yml) или ENV (KEY=VALUE). Ключи файла совпадают с именами
+// переменных окружения, непустые переменные окружения имеют приоритет над
+// файлом. Все ошибки проверки возвращаются сразу, а не по одной.
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Хранилище данных (STORAGE)
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+type Config struct {
+	// Адрес HTTP-сервера host:port (SERVER_ADDRESS). Одиночный номер порта
+	// означает прослушивание всех интерфейсов.
+	Address string
+	// Таймауты чтения запроса и записи ответа (TIMEOUT) и простоя
+	// keep-alive соединения (IDLE_TIMEOUT)
+	Timeout     time.Duration
+	IdleTimeout time.Duration
+	// Время на завершение обрабатываемых запросов при остановке (SHUTDOWN_TIMEOUT)
+	ShutdownTimeout time.Duration
+	LogLevel        slog.Level
+
+	Storage  string
+	Database Database
+
+	// Применять миграции при старте (MIGRATE_ON_START)
+	MigrateOnStart bool
+	// Обратные переходы статуса тендера (TENDER_BACK_TRANSITIONS)
+	TenderBackTransitions string
+	// Файл политики доступа (AUTHZ_POLICY_FILE)
+	AuthzPolicyFile string
+	Auth            Auth
+	Events          Events
+	Webhooks        Webhooks
+}
+
+type Database struct {
+	// Строка подключения с параметрами размера пула
+	DSN string
+	// Размер пула соединений (DB_MAX_CONNS, DB_MIN_CONNS), 0 — по умолчанию pgxpool
+	MaxConns int
+	MinConns int
+	// Ограничение времени одной операции с базой данных (DB_QUERY_TIMEOUT).
+	// Должно быть меньше Timeout, чтобы клиент успел получить ответ 504.
+	QueryTimeout time.Duration
+	// Ожидание базы данных при старте (DB_CONNECT_ATTEMPTS,
+	// DB_CONNECT_BACKOFF, DB_CONNECT_MAX_BACKOFF)
+	ConnectAttempts   int
+	ConnectBackoff    time.Duration
+	ConnectMaxBackoff time.Duration
+}
+
+// Аутентификация по bearer-токенам (AUTH_HMAC_KEYS, AUTH_ISSUER, AUTH_REQUIRED)
+type Auth struct {
+	HMACKeys string
+	Issuer   string
+	Required bool
+}
+
+// Доставка доменных событий из outbox. Получатели включаются независимо:
+// журнал приложения (EVENTS_LOG), файл JSON Lines (EVENTS_FILE) и
+// HTTP-адрес (EVENTS_HTTP_URL). Без получателей события только
+// накапливаются в outbox.
+type Events struct {
+	Log     bool
+	File    string
+	HTTPURL string
+	// Период опроса outbox (EVENTS_POLL_INTERVAL) и размер пакета (EVENTS_BATCH_SIZE)
+	PollInterval time.Duration
+	BatchSize    int
+	// Срок хранения доставленных событий (EVENTS_RETENTION), 0 — хранить всегда
+	Retention time.Duration
+}
+
+// Включен ли хотя бы один получатель событий
+func (e Events) Enabled() bool {
+	return e.Log || e.File != "" || e.HTTPURL != ""
+}
+
+// Доставка событий вебхукам организаций (WEBHOOKS_ENABLED). Неудачная
+// попытка повторяется с паузой WEBHOOKS_BACKOFF, которая удваивается до
+// WEBHOOKS_MAX_BACKOFF, всего до WEBHOOKS_MAX_ATTEMPTS попыток. Вебхук
+// отключается после WEBHOOKS_DISABLE_AFTER неудачных попыток подряд
+// (0 — не отключать).
+type Webhooks struct {
+	Enabled      bool
+	Timeout      time.Duration
+	MaxAttempts  int
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	DisableAfter int
+	// Период опроса очереди доставки (WEBHOOKS_POLL_INTERVAL)
+	PollInterval time.Duration
+	// Непубличные хосты и подсети, на которые разрешены вебхуки, через
+	// запятую (WEBHOOKS_ALLOWED_HOSTS), например для локальной проверки
+	AllowedHosts string
+}
+
+// Загружает настройки из окружения процесса и файла CONFIG_FILE
+func Load() (Config, error) {
+	return load(os.LookupEnv)
+}
+
+func load(lookupEnv func(string) (string, bool)) (Config, error) {
+	file := map[string]string{}
+	if path, ok := lookupEnv("CONFIG_FILE"); ok && path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			return Config{}, fmt.Errorf("CONFIG_FILE: %w", err)
+		}
+	}
+
+	p := &parser{get: func(name string) string {
+		if v, ok := lookupEnv(name); ok && v != "" {
+			return v
+		}
+		return file[name]
+	}}
+
+	cfg := Config{
+		Address:         p.address("SERVER_ADDRESS", ":8080"),
+		Timeout:         p.duration("TIMEOUT", 10*time.Second),
+		IdleTimeout:     p.duration("IDLE_TIMEOUT", 60*time.Second),
+		ShutdownTimeout: p.duration("SHUTDOWN_TIMEOUT", 15*time.Second),
+		LogLevel:        p.level("LOG_LEVEL", slog.LevelInfo),
+		Storage:         p.oneOf("STORAGE", StoragePostgres, StorageMemory),
+		Database: Database{
+			MaxConns:          p.int("DB_MAX_CONNS", 0, 0),
+			MinConns:          p.int("DB_MIN_CONNS", 0, 0),
+			QueryTimeout:      p.duration("DB_QUERY_TIMEOUT", 5*time.Second),
+			ConnectAttempts:   p.int("DB_CONNECT_ATTEMPTS", 10, 1),
+			ConnectBackoff:    p.duration("DB_CONNECT_BACKOFF", 500*time.Millisecond),
+			ConnectMaxBackoff: p.duration("DB_CONNECT_MAX_BACKOFF", 10*time.Second),
+		},
+		MigrateOnStart:        p.bool("MIGRATE_ON_START", true),
+		TenderBackTransitions: p.get("TENDER_BACK_TRANSITIONS"),
+		AuthzPolicyFile:       p.get("AUTHZ_POLICY_FILE"),
+		Auth: Auth{
+			HMACKeys: p.get("AUTH_HMAC_KEYS"),
+			Issuer:   p.get("AUTH_ISSUER"),
+			Required: p.bool("AUTH_REQUIRED", false),
+		},
+		Events: Events{
+			Log:          p.bool("EVENTS_LOG", false),
+			File:         p.get("EVENTS_FILE"),
+			HTTPURL:      p.httpURL("EVENTS_HTTP_URL"),
+			PollInterval: p.duration("EVENTS_POLL_INTERVAL", time.Second),
+			BatchSize:    p.int("EVENTS_BATCH_SIZE", 100, 1),
+			Retention:    p.duration("EVENTS_RETENTION", 7*24*time.Hour),
+		},
+		Webhooks: Webhooks{
+			Enabled:      p.bool("WEBHOOKS_ENABLED", true),
+			Timeout:      p.duration("WEBHOOKS_TIMEOUT", 10*time.Second),
+			MaxAttempts:  p.int("WEBHOOKS_MAX_ATTEMPTS", 8, 1),
+			Backoff:      p.duration("WEBHOOKS_BACKOFF", 10*time.Second),
+			MaxBackoff:   p.duration("WEBHOOKS_MAX_BACKOFF", time.Hour),
+			DisableAfter: p.int("WEBHOOKS_DISABLE_AFTER", 20, 0),
+			PollInterval: p.duration("WEBHOOKS_POLL_INTERVAL", time.Second),
+			AllowedHosts: p.get("WEBHOOKS_ALLOWED_HOSTS"),
+		},
+	}
+
+	db := &cfg.Database
+	if cfg.Storage == StoragePostgres {
+		db.DSN = p.dsn()
+	}
+	if db.MaxConns > 0 && db.MinConns > db.MaxConns {
+		p.fail("DB_MIN_CONNS: must not exceed DB_MAX_CONNS")
+	}
+	if db.ConnectMaxBackoff < db.ConnectBackoff {
+		p.fail("DB_CONNECT_MAX_BACKOFF: must not be less than DB_CONNECT_BACKOFF")
+	}
+	if cfg.Timeout > 0 && db.QueryTimeout >= cfg.Timeout {
+		p.fail("DB_QUERY_TIMEOUT: must be less than TIMEOUT")
+	}
+	if cfg.Events.PollInterval == 0 {
+		p.fail("EVENTS_POLL_INTERVAL: must be positive")
+	}
+	if cfg.Webhooks.Timeout == 0 {
+		p.fail("WEBHOOKS_TIMEOUT: must be positive")
+	}
+	if cfg.Webhooks.PollInterval == 0 {
+		p.fail("WEBHOOKS_POLL_INTERVAL: must be positive")
+	}
+	if cfg.Webhooks.MaxBackoff < cfg.Webhooks.Backoff {
+		p.fail("WEBHOOKS_MAX_BACKOFF: must not be less than WEBHOOKS_BACKOFF")
+	}
+	if db.DSN != "" {
+		dsn, err := withPoolSize(db.DSN, db.MaxConns, db.MinConns)
+		if err != nil {
+			p.fail("POSTGRES_CONN: %v", err)
+		}
+		db.DSN = dsn
+	}
+
+	if err := errors.Join(p.errs...); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// Читает пары ключ-значение из файла в формате YAML или ENV
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return values, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, scanner.Err()
+}
+
+// Разбор значений с накоплением ошибок
+type parser struct {
+	get  func(name string) string
+	errs []error
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	p.errs = append(p.errs, fmt.Errorf(format, args...))
+}
+
+func (p *parser) duration(name string, def time.Duration) time.Duration {
+	v := p.get(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		p.fail("%s: invalid duration %q", name, v)
+		return def
+	}
+	if d < 0 {
+		p.fail("%s: must not be negative", name)
+		return def
+	}
+	return d
+}
+
+func (p *parser) int(name string, def, min int) int {
+	v := p.get(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min {
+		p.fail("%s: must be an integer not less than %d, got %q", name, min, v)
+		return def
+	}
+	return n
+}
+
+func (p *parser) bool(name string, def bool) bool {
+	v := p.get(name)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.fail("%s: must be true or false, got %q", name, v)
+		return def
+	}
+	return b
+}
+
+func (p *parser) level(name string, def slog.Level) slog.Level {
+	v := p.get(name)
+	if v == "" {
+		return def
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(v)); err != nil {
+		p.fail("%s: must be one of debug, info, warn, error, got %q", name, v)
+		return def
+	}
+	return level
+}
+
+// Одно из допустимых значений, первое используется по умолчанию
+func (p *parser) oneOf(name string, values ...string) string {
+	v := p.get(name)
+	if v == "" {
+		return values[0]
+	}
+	for _, allowed := range values {
+		if v == allowed {
+			return v
+		}
+	}
+	p.fail("%s: must be one of %s, got %q", name, strings.Join(values, ", "), v)
+	return values[0]
+}
+
+// Адрес host:port. Одиночный номер порта дополняется до ":port".
+func (p *parser) address(name, def string) string {
+	v := p.get(name)
+	if v == "" {
+		return def
+	}
+	if _, err := strconv.Atoi(v); err == nil {
+		v = ":" + v
+	}
+	_, port, err := net.SplitHostPort(v)
+	if err == nil {
+		_, err = strconv.ParseUint(port, 10, 16)
+	}
+	if err != nil {
+		p.fail("%s: must be host:port or a port number, got %q", name, v)
+		return def
+	}
+	return v
+}
+
+// Абсолютный адрес http или https
+func (p *parser) httpURL(name string) string {
+	v := p.get(name)
+	if v == "" {
+		return ""
+	}
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		p.fail("%s: must be an absolute http or https URL, got %q", name, v)
+		return ""
+	}
+	return v
+}
+
+// Строка подключения к PostgreSQL: POSTGRES_CONN целиком или из
+// POSTGRES_HOST, POSTGRES_PORT, POSTGRES_USERNAME, POSTGRES_PASSWORD и
+// POSTGRES_DATABASE
+func (p *parser) dsn() string {
+	if conn := p.get("POSTGRES_CONN"); conn != "" {
+		return conn
+	}
+	host := p.get("POSTGRES_HOST")
+	if host == "" {
+		p.fail("POSTGRES_CONN or POSTGRES_HOST must be set unless STORAGE=%s", StorageMemory)
+		return ""
+	}
+	port := p.get("POSTGRES_PORT")
+	if port == "" {
+		port = "5432"
+	} else if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		p.fail("POSTGRES_PORT: invalid port %q", port)
+	}
+
+	u := url.URL{
+		Scheme: "postgres",
+		Host:   net.JoinHostPort(host, port),
+		Path:   "/" + p.get("POSTGRES_DATABASE"),
+	}
+	if username := p.get("POSTGRES_USERNAME"); username != "" {
+		u.User = url.UserPassword(username, p.get("POSTGRES_PASSWORD"))
+	}
+	return u.String()
+}
+
+// Задает в строке подключения параметры размера пула pgxpool, заменяя
+// уже указанные в ней значения. Поддерживаются оба формата строки: URL и
+// key=value.
+func withPoolSize(dsn string, maxConns, minConns int) (string, error) {
+	var params [][2]string
+	if maxConns > 0 {
+		params = append(params, [2]string{"pool_max_conns", strconv.Itoa(maxConns)})
+	}
+	if minConns > 0 {
+		params = append(params, [2]string{"pool_min_conns", strconv.Itoa(minConns)})
+	}
+	if len(params) == 0 {
+		return dsn, nil
+	}
+
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid connection URL: %w", err)
+		}
+		query := u.Query()
+		for _, param := range params {
+			query.Set(param[0], param[1])
+		}
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+
+	pairs, err := parseKeyValueDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	for _, param := range params {
+		i := slices.IndexFunc(pairs, func(pair [2]string) bool { return pair[0] == param[0] })
+		if i < 0 {
+			pairs = append(pairs, param)
+		} else {
+			pairs[i][1] = param[1]
+		}
+	}
+	parts := make([]string, len(pairs))
+	for i, pair := range pairs {
+		parts[i] = pair[0] + "=" + pair[1]
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// Разбирает строку подключения в формате key=value на пары. Значения
+// сохраняются как в исходной строке, вместе с кавычками и экранированием.
+func parseKeyValueDSN(dsn string) ([][2]string, error) {
+	var pairs [][2]string
+	for s := strings.TrimSpace(dsn); s != ""; s = strings.TrimSpace(s) {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid connection string: missing \"=\" after %q", s)
+		}
+		key := strings.TrimSpace(s[:eq])
+		if strings.ContainsAny(key, " \t\n") {
+			return nil, fmt.Errorf("invalid connection string: missing \"=\" after %q", key)
+		}
+		s = strings.TrimLeft(s[eq+1:], " \t\n")
+
+		end, quoted, closed := 0, strings.HasPrefix(s, "'"), false
+		if quoted {
+			end = 1
+		}
+		for ; end < len(s) && !closed; end++ {
+			switch {
+			case s[end] == '\\':
+				end++
+			case quoted && s[end] == '\'':
+				closed = true
+			case !quoted && strings.ContainsRune(" \t\n", rune(s[end])):
+				closed = true
+				end--
+			}
+		}
+		if end > len(s) || (quoted && !closed) {
+			return nil, fmt.Errorf("invalid connection string: unterminated quoted value of %q", key)
+		}
+		pairs = append(pairs, [2]string{key, s[:end]})
+		s = s[end:]
+	}
+	return pairs, nil
+}
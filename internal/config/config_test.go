@@ -0,0 +1,141 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load(lookup(map[string]string{"POSTGRES_CONN": "postgres://localhost/tenders"}))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Address != ":8080" || cfg.Timeout != 10*time.Second || cfg.LogLevel != slog.LevelInfo {
+		t.Errorf("defaults = %q, %s, %s; want :8080, 10s, INFO", cfg.Address, cfg.Timeout, cfg.LogLevel)
+	}
+	if cfg.Storage != StoragePostgres || !cfg.MigrateOnStart || cfg.Auth.Required {
+		t.Errorf("toggles = %q, migrate %t, auth %t; want postgres, true, false", cfg.Storage, cfg.MigrateOnStart, cfg.Auth.Required)
+	}
+	if cfg.Database.DSN != "postgres://localhost/tenders" || cfg.Database.ConnectAttempts != 10 {
+		t.Errorf("database = %+v", cfg.Database)
+	}
+	if cfg.Events.Enabled() || cfg.Events.PollInterval != time.Second || cfg.Events.BatchSize != 100 || cfg.Events.Retention != 7*24*time.Hour {
+		t.Errorf("events = %+v", cfg.Events)
+	}
+	if w := cfg.Webhooks; !w.Enabled || w.MaxAttempts != 8 || w.Backoff != 10*time.Second || w.MaxBackoff != time.Hour || w.DisableAfter != 20 {
+		t.Errorf("webhooks = %+v", w)
+	}
+}
+
+func TestLoadAddress(t *testing.T) {
+	tests := map[string]string{
+		"8080":         ":8080",
+		"0.0.0.0:8080": "0.0.0.0:8080",
+		":9000":        ":9000",
+	}
+	for value, want := range tests {
+		cfg, err := load(lookup(map[string]string{"SERVER_ADDRESS": value, "STORAGE": "memory"}))
+		if err != nil || cfg.Address != want {
+			t.Errorf("SERVER_ADDRESS=%s: address = %q, %v; want %q", value, cfg.Address, err, want)
+		}
+	}
+}
+
+func TestLoadDSNFromParts(t *testing.T) {
+	cfg, err := load(lookup(map[string]string{
+		"POSTGRES_HOST":     "db",
+		"POSTGRES_USERNAME": "user",
+		"POSTGRES_PASSWORD": "p@ss",
+		"POSTGRES_DATABASE": "tenders",
+		"DB_MAX_CONNS":      "20",
+		"DB_MIN_CONNS":      "2",
+	}))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	want := "postgres://user:p%40ss@db:5432/tenders?pool_max_conns=20&pool_min_conns=2"
+	if cfg.Database.DSN != want {
+		t.Errorf("DSN = %q, want %q", cfg.Database.DSN, want)
+	}
+
+	cfg, err = load(lookup(map[string]string{"POSTGRES_CONN": "host=db dbname=tenders", "DB_MAX_CONNS": "5"}))
+	if err != nil || cfg.Database.DSN != "host=db dbname=tenders pool_max_conns=5" {
+		t.Errorf("key=value DSN = %q, %v", cfg.Database.DSN, err)
+	}
+}
+
+func TestLoadDSNPoolSizeOverride(t *testing.T) {
+	tests := map[string]string{
+		"postgres://db/tenders?pool_max_conns=50&sslmode=disable":         "postgres://db/tenders?pool_max_conns=5&sslmode=disable",
+		"host=db pool_max_conns=50 dbname=tenders":                        "host=db pool_max_conns=5 dbname=tenders",
+		"host=db password='p ss \\' pool_max_conns=' pool_max_conns = 50": "host=db password='p ss \\' pool_max_conns=' pool_max_conns=5",
+	}
+	for dsn, want := range tests {
+		cfg, err := load(lookup(map[string]string{"POSTGRES_CONN": dsn, "DB_MAX_CONNS": "5"}))
+		if err != nil || cfg.Database.DSN != want {
+			t.Errorf("POSTGRES_CONN=%s: DSN = %q, %v; want %q", dsn, cfg.Database.DSN, err, want)
+		}
+	}
+
+	for _, dsn := range []string{"host=db password='secret", "host=db dbname"} {
+		if _, err := load(lookup(map[string]string{"POSTGRES_CONN": dsn, "DB_MAX_CONNS": "5"})); err == nil {
+			t.Errorf("POSTGRES_CONN=%s: expected error", dsn)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "config.yaml")
+	envFile := filepath.Join(dir, "app.env")
+	if err := os.WriteFile(yamlFile, []byte("STORAGE: memory\nTIMEOUT: 30s\nLOG_LEVEL: debug\nMIGRATE_ON_START: false\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(envFile, []byte("# comment\nSTORAGE=memory\nexport TIMEOUT=\"30s\"\nLOG_LEVEL=debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{yamlFile, envFile} {
+		cfg, err := load(lookup(map[string]string{"CONFIG_FILE": file, "LOG_LEVEL": "warn"}))
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		// Переменные окружения имеют приоритет над файлом
+		if cfg.Storage != StorageMemory || cfg.Timeout != 30*time.Second || cfg.LogLevel != slog.LevelWarn {
+			t.Errorf("%s: config = %q, %s, %s; want memory, 30s, WARN", file, cfg.Storage, cfg.Timeout, cfg.LogLevel)
+		}
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	_, err := load(lookup(map[string]string{
+		"SERVER_ADDRESS":    "localhost",
+		"TIMEOUT":           "ten",
+		"DB_QUERY_TIMEOUT":  "20s",
+		"DB_MAX_CONNS":      "-1",
+		"LOG_LEVEL":         "verbose",
+		"AUTH_REQUIRED":     "yes",
+		"EVENTS_HTTP_URL":   "localhost:9000/events",
+		"EVENTS_BATCH_SIZE": "0",
+		"WEBHOOKS_BACKOFF":  "2h",
+	}))
+	if err == nil {
+		t.Fatal("load succeeded, want validation errors")
+	}
+	for _, name := range []string{"SERVER_ADDRESS", "TIMEOUT", "DB_QUERY_TIMEOUT", "DB_MAX_CONNS", "LOG_LEVEL", "AUTH_REQUIRED", "POSTGRES_HOST", "EVENTS_HTTP_URL", "EVENTS_BATCH_SIZE", "WEBHOOKS_MAX_BACKOFF"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error does not mention %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Права пользователя по отношению к конкретному тендеру
+type TenderAccess struct {
+	UserId  uuid.UUID
+	Status  TenderStatus
+	Subject authz.Subject
+}
+
+// Права пользователя по отношению к конкретному предложению
+type BidAccess struct {
+	UserId       uuid.UUID
+	Status       BidStatus
+	TenderStatus TenderStatus
+	Subject      authz.Subject
+}
+
+// Пользователь с ролью viewer и дополнительными ролями, для которых выполнено условие
+func newSubject(username string, roles map[authz.Role]bool) authz.Subject {
+	subject := authz.Subject{Username: username, Roles: []authz.Role{authz.RoleViewer}}
+	for _, role := range []authz.Role{authz.RoleOrgResponsible, authz.RoleBidAuthor} {
+		if roles[role] {
+			subject.Roles = append(subject.Roles, role)
+		}
+	}
+	return subject
+}
+
+func getUserId(ctx context.Context, q querier, username string) (uuid.UUID, error) {
+	var userId uuid.UUID
+	err := q.QueryRow(ctx, `SELECT id FROM employee WHERE username = $1`, username).Scan(&userId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, ErrUserNotFound
+		}
+		return uuid.Nil, fmt.Errorf("retrieving user: %w", err)
+	}
+	return userId, nil
+}
+
+// Получение статуса тендера и ролей пользователя по отношению к нему
+func getTenderAccess(ctx context.Context, q querier, tenderId string, username string) (TenderAccess, error) {
+	userId, err := getUserId(ctx, q, username)
+	if err != nil {
+		return TenderAccess{}, err
+	}
+
+	var status string
+	var isResponsible bool
+	query := `
+        SELECT t.status, EXISTS(
+            SELECT 1
+            FROM organization_responsible r
+            WHERE r.user_id = $2 AND r.organization_id = t.organization_id
+        )
+        FROM tenders t
+        WHERE t.id = $1
+    `
+	err = q.QueryRow(ctx, query, tenderId, userId).Scan(&status, &isResponsible)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return TenderAccess{}, ErrTenderNotFound
+		}
+		return TenderAccess{}, err
+	}
+
+	return TenderAccess{
+		UserId:  userId,
+		Status:  TenderStatus(status),
+		Subject: newSubject(username, map[authz.Role]bool{authz.RoleOrgResponsible: isResponsible}),
+	}, nil
+}
+
+// Получение статусов предложения и его тендера и ролей пользователя.
+// Автором предложения считается сам автор и ответственные за организацию
+// автора: для предложения от организации это сама организация, для
+// предложения от пользователя - организация, в которой автор является ответственным.
+func getBidAccess(ctx context.Context, q querier, bidId string, username string) (BidAccess, error) {
+	userId, err := getUserId(ctx, q, username)
+	if err != nil {
+		return BidAccess{}, err
+	}
+
+	var status, tenderStatus string
+	var isResponsible, isAuthor bool
+	query := `
+        SELECT b.status, t.status,
+            EXISTS(
+                SELECT 1
+                FROM organization_responsible r
+                WHERE r.user_id = $2 AND r.organization_id = t.organization_id
+            ),
+            b.author_id = $2 OR EXISTS(
+                SELECT 1
+                FROM organization_responsible r
+                WHERE r.user_id = $2
+                AND (
+                    r.organization_id = b.author_id
+                    OR r.organization_id IN (SELECT organization_id FROM organization_responsible WHERE user_id = b.author_id)
+                )
+            )
+        FROM bids b
+        JOIN tenders t ON t.id = b.tender_id
+        WHERE b.id = $1
+    `
+	err = q.QueryRow(ctx, query, bidId, userId).Scan(&status, &tenderStatus, &isResponsible, &isAuthor)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return BidAccess{}, ErrBidNotFound
+		}
+		return BidAccess{}, err
+	}
+
+	return BidAccess{
+		UserId:       userId,
+		Status:       BidStatus(status),
+		TenderStatus: TenderStatus(tenderStatus),
+		Subject: newSubject(username, map[authz.Role]bool{
+			authz.RoleOrgResponsible: isResponsible,
+			authz.RoleBidAuthor:      isAuthor,
+		}),
+	}, nil
+}
+
+// Проверяет по политике доступа право пользователя на действие с тендером
+func (db *DB) authorizeTender(ctx context.Context, q querier, tenderId string, username string, action authz.Action) (TenderAccess, error) {
+	access, err := getTenderAccess(ctx, q, tenderId, username)
+	if err != nil {
+		db.logAccessError(ctx, "Error checking permission on tender", err, slog.String("username", username), slog.String("tender_id", tenderId))
+		return TenderAccess{}, err
+	}
+	if !db.Policy.Allowed(action, access.Subject, string(access.Status)) {
+		db.Log.InfoContext(ctx, "Action on tender is not allowed", slog.String("username", username), slog.String("action", string(action)), slog.String("tender_id", tenderId))
+		return TenderAccess{}, ErrForbidden
+	}
+	return access, nil
+}
+
+// Проверяет по политике доступа право пользователя на действие с предложением
+func (db *DB) authorizeBid(ctx context.Context, q querier, bidId string, username string, action authz.Action) (BidAccess, error) {
+	access, err := getBidAccess(ctx, q, bidId, username)
+	if err != nil {
+		db.logAccessError(ctx, "Error checking permission on bid", err, slog.String("username", username), slog.String("bid_id", bidId))
+		return BidAccess{}, err
+	}
+	if !db.Policy.Allowed(action, access.Subject, string(access.Status)) {
+		db.Log.InfoContext(ctx, "Action on bid is not allowed", slog.String("username", username), slog.String("action", string(action)), slog.String("bid_id", bidId))
+		return BidAccess{}, ErrForbidden
+	}
+	return access, nil
+}
+
+// Проверяет по политике доступа право пользователя подать предложение от
+// имени автора authorId: роль bid-author у самого автора и у ответственных
+// за организацию автора, как и для существующего предложения.
+func (db *DB) authorizeBidCreate(ctx context.Context, q querier, authorId string, username string) error {
+	userId, err := getUserId(ctx, q, username)
+	if err != nil {
+		db.logAccessError(ctx, "Error checking permission to create bid", err, slog.String("username", username), slog.String("author_id", authorId))
+		return err
+	}
+
+	var isAuthor bool
+	query := `
+        SELECT $1::uuid = $2 OR EXISTS(
+            SELECT 1
+            FROM organization_responsible r
+            WHERE r.user_id = $2
+            AND (
+                r.organization_id = $1::uuid
+                OR r.organization_id IN (SELECT organization_id FROM organization_responsible WHERE user_id = $1::uuid)
+            )
+        )
+    `
+	if err := q.QueryRow(ctx, query, authorId, userId).Scan(&isAuthor); err != nil {
+		db.Log.ErrorContext(ctx, "Error checking permission to create bid", slog.String("username", username), slog.String("author_id", authorId), sl.Err(err))
+		return err
+	}
+
+	subject := newSubject(username, map[authz.Role]bool{authz.RoleBidAuthor: isAuthor})
+	if !db.Policy.Allowed(authz.BidCreate, subject, "") {
+		db.Log.InfoContext(ctx, "Creating bid is not allowed", slog.String("username", username), slog.String("author_id", authorId))
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Пишет в журнал ошибку проверки доступа. Доменные исходы (пользователь
+// или объект не найден) ожидаемы и пишутся с уровнем Info, с уровнем
+// Error пишутся только сбои хранилища.
+func (db *DB) logAccessError(ctx context.Context, msg string, err error, attrs ...slog.Attr) {
+	level := slog.LevelError
+	if KindOf(err) != KindInternal {
+		level = slog.LevelInfo
+	}
+	db.Log.LogAttrs(ctx, level, msg, append(attrs, sl.Err(err))...)
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+func TestLogAccessError(t *testing.T) {
+	tests := []struct {
+		err   error
+		level string
+	}{
+		{ErrUserNotFound, "INFO"},
+		{ErrTenderNotFound, "INFO"},
+		{fmt.Errorf("checking: %w", ErrBidNotFound), "INFO"},
+		{errors.New("connection reset"), "ERROR"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		db := &DB{Log: sl.New(&buf, slog.LevelDebug)}
+		db.logAccessError(context.Background(), "Error checking permission on tender", tt.err, slog.String("tender_id", "t1"))
+
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("invalid record %q: %v", buf.String(), err)
+		}
+		if record["level"] != tt.level || record["error"] != tt.err.Error() || record["tender_id"] != "t1" {
+			t.Errorf("record for %v = %v, want level %s", tt.err, record, tt.level)
+		}
+	}
+}
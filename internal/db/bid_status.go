@@ -0,0 +1,103 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+)
+
+// Статус предложения в том виде, в котором он хранится в базе (enum bid_status)
+type BidStatus string
+
+const (
+	BidStatusCreated   BidStatus = "CREATED"
+	BidStatusPublished BidStatus = "PUBLISHED"
+	BidStatusCanceled  BidStatus = "CANCELED"
+	BidStatusApproved  BidStatus = "APPROVED"
+	BidStatusRejected  BidStatus = "REJECTED"
+)
+
+var (
+	ErrInvalidBidStatus     = newError(KindValidation, "invalid bid status")
+	ErrInvalidBidTransition = newError(KindValidation, "invalid bid status transition")
+)
+
+// Допустимые переходы между статусами предложения.
+// APPROVED и REJECTED выставляются только по решениям ответственных,
+// CANCELED, APPROVED и REJECTED - конечные статусы.
+var bidTransitions = map[BidStatus][]BidStatus{
+	BidStatusCreated:   {BidStatusPublished, BidStatusCanceled},
+	BidStatusPublished: {BidStatusCanceled, BidStatusApproved, BidStatusRejected},
+	BidStatusCanceled:  {},
+	BidStatusApproved:  {},
+	BidStatusRejected:  {},
+}
+
+// Статусы, которые автор может выставить вручную через смену статуса
+var manualBidStatuses = map[BidStatus]bool{
+	BidStatusPublished: true,
+	BidStatusCanceled:  true,
+}
+
+// Предложения, которые видят ответственные за организацию тендера:
+// опубликованные и с решением. Черновики и отмененные видит только автор.
+func (s BidStatus) visibleToTender() bool {
+	switch s {
+	case BidStatusPublished, BidStatusApproved, BidStatusRejected:
+		return true
+	}
+	return false
+}
+
+// Ошибка недопустимого перехода между статусами предложения
+type BidTransitionError struct {
+	From BidStatus
+	To   BidStatus
+}
+
+func (e *BidTransitionError) Error() string {
+	if e.From == e.To {
+		return fmt.Sprintf("bid is already in status %s", e.To)
+	}
+	return fmt.Sprintf("bid status cannot be changed from %s to %s", e.From, e.To)
+}
+
+func (e *BidTransitionError) Unwrap() error {
+	return ErrInvalidBidTransition
+}
+
+// Приводит статус из API (Published) или из базы (PUBLISHED) к BidStatus
+func ParseBidStatus(status api.BidStatus) (BidStatus, error) {
+	s := BidStatus(strings.ToUpper(string(status)))
+	if _, ok := bidTransitions[s]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrInvalidBidStatus, status)
+	}
+	return s, nil
+}
+
+func (s BidStatus) CanTransitionTo(to BidStatus) bool {
+	for _, allowed := range bidTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Проверяет переход между статусами предложения
+func CheckBidTransition(from BidStatus, to BidStatus) error {
+	if !from.CanTransitionTo(to) {
+		return &BidTransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+// Проверяет переход, который автор запрашивает через смену статуса.
+// Согласование и отклонение через смену статуса недоступны.
+func CheckManualBidTransition(from BidStatus, to BidStatus) error {
+	if !manualBidStatuses[to] {
+		return fmt.Errorf("%w: status %s can only be set by a decision", ErrInvalidBidTransition, to)
+	}
+	return CheckBidTransition(from, to)
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+)
+
+func TestParseBidStatus(t *testing.T) {
+	status, err := ParseBidStatus(api.BidStatusPublished)
+	if err != nil || status != BidStatusPublished {
+		t.Fatalf("ParseBidStatus(Published) = %q, %v", status, err)
+	}
+
+	status, err = ParseBidStatus("CANCELED")
+	if err != nil || status != BidStatusCanceled {
+		t.Fatalf("ParseBidStatus(CANCELED) = %q, %v", status, err)
+	}
+
+	if _, err := ParseBidStatus("Submitted"); !errors.Is(err, ErrInvalidBidStatus) {
+		t.Fatalf("ParseBidStatus(Submitted) error = %v, want ErrInvalidBidStatus", err)
+	}
+}
+
+func TestCheckBidTransition(t *testing.T) {
+	tests := []struct {
+		from, to BidStatus
+		allowed  bool
+	}{
+		{BidStatusCreated, BidStatusPublished, true},
+		{BidStatusCreated, BidStatusCanceled, true},
+		{BidStatusCreated, BidStatusApproved, false},
+		{BidStatusPublished, BidStatusCanceled, true},
+		{BidStatusPublished, BidStatusApproved, true},
+		{BidStatusPublished, BidStatusRejected, true},
+		{BidStatusPublished, BidStatusPublished, false},
+		{BidStatusCanceled, BidStatusPublished, false},
+		{BidStatusApproved, BidStatusRejected, false},
+		{BidStatusRejected, BidStatusPublished, false},
+	}
+
+	for _, tt := range tests {
+		err := CheckBidTransition(tt.from, tt.to)
+		if tt.allowed && err != nil {
+			t.Errorf("%s -> %s: unexpected error %v", tt.from, tt.to, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrInvalidBidTransition) {
+			t.Errorf("%s -> %s: error = %v, want ErrInvalidBidTransition", tt.from, tt.to, err)
+		}
+	}
+}
+
+func TestCheckManualBidTransition(t *testing.T) {
+	if err := CheckManualBidTransition(BidStatusCreated, BidStatusPublished); err != nil {
+		t.Fatalf("Created -> Published: unexpected error %v", err)
+	}
+
+	if err := CheckManualBidTransition(BidStatusPublished, BidStatusApproved); !errors.Is(err, ErrInvalidBidTransition) {
+		t.Fatalf("Published -> Approved: error = %v, want ErrInvalidBidTransition", err)
+	}
+}
@@ -0,0 +1,338 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Редактирование предложения. Незаданные поля остаются без изменений,
+// версия увеличивается, новое состояние сохраняется в историю.
+func (db *DB) EditBid(ctx context.Context, bidId string, name *string, description *string, username string) (api.Bid, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
+		return api.Bid{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	db.Log.DebugContext(ctx, "Checking permission to edit bid", slog.String("username", username), slog.String("bid_id", bidId))
+	if _, err := db.authorizeBid(ctx, tx, bidId, username, authz.BidEdit); err != nil {
+		return api.Bid{}, err
+	}
+
+	var updatedBid api.Bid
+	var createdAt time.Time
+
+	query := `
+        UPDATE bids
+        SET name = COALESCE($1, name), description = COALESCE($2, description),
+            version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $3
+        RETURNING id, name, description, tender_id, author_id, author_type, status, version, created_at
+    `
+	err = tx.QueryRow(ctx, query, name, description, bidId).Scan(
+		&updatedBid.Id,
+		&updatedBid.Name,
+		&updatedBid.Description,
+		&updatedBid.TenderId,
+		&updatedBid.AuthorId,
+		&updatedBid.AuthorType,
+		&updatedBid.Status,
+		&updatedBid.Version,
+		&createdAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return api.Bid{}, ErrBidNotFound
+		}
+		db.Log.ErrorContext(ctx, "Error updating bid", slog.String("bid_id", bidId), sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	err = db.insertBidVersion(ctx, tx, bidId, username, BidChangeEdited)
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	updatedBid.CreatedAt = createdAt.Format(time.RFC3339)
+
+	err = db.insertEvent(ctx, tx, events.BidEdited, events.BidChange{Bid: updatedBid, Username: username})
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	db.Log.InfoContext(ctx, "Successfully updated bid", slog.String("bid_id", updatedBid.Id), slog.Any("version", updatedBid.Version))
+	return updatedBid, nil
+}
+
+// Откат предложения к версии из истории. Откат считается новой правкой.
+func (db *DB) RollbackBid(ctx context.Context, bidId string, version int, username string) (api.Bid, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	db.Log.InfoContext(ctx, "Rolling back bid", slog.String("bid_id", bidId), slog.Any("version", version), slog.String("username", username))
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
+		return api.Bid{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := db.authorizeBid(ctx, tx, bidId, username, authz.BidRollback); err != nil {
+		return api.Bid{}, err
+	}
+
+	var updatedBid api.Bid
+	var createdAt time.Time
+
+	query := `
+        UPDATE bids b
+        SET name = v.name, description = v.description,
+            version = b.version + 1, updated_at = CURRENT_TIMESTAMP
+        FROM bid_versions v
+        WHERE b.id = $1 AND v.bid_id = b.id AND v.version = $2
+        RETURNING b.id, b.name, b.description, b.tender_id, b.author_id, b.author_type, b.status, b.version, b.created_at
+    `
+	err = tx.QueryRow(ctx, query, bidId, version).Scan(
+		&updatedBid.Id,
+		&updatedBid.Name,
+		&updatedBid.Description,
+		&updatedBid.TenderId,
+		&updatedBid.AuthorId,
+		&updatedBid.AuthorType,
+		&updatedBid.Status,
+		&updatedBid.Version,
+		&createdAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			db.Log.InfoContext(ctx, "Version of bid not found", slog.Any("version", version), slog.String("bid_id", bidId))
+			return api.Bid{}, ErrVersionNotFound
+		}
+		db.Log.ErrorContext(ctx, "Error updating bid during rollback", slog.String("bid_id", bidId), sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	err = db.insertBidVersion(ctx, tx, bidId, username, BidChangeRolledBack)
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	updatedBid.CreatedAt = createdAt.Format(time.RFC3339)
+
+	err = db.insertEvent(ctx, tx, events.BidRolledBack, events.BidChange{Bid: updatedBid, Username: username})
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	db.Log.InfoContext(ctx, "Successfully rolled back bid", slog.String("bid_id", bidId), slog.Any("version", version), slog.Any("new_version", updatedBid.Version))
+	return updatedBid, nil
+}
+
+// Поля сортировки предложений в запросах с псевдонимом b
+var bidSortColumns = sortColumns{
+	SortByName:      {expr: `b.name COLLATE "C"`, typ: "text"},
+	SortByCreatedAt: {expr: "b.created_at", typ: "timestamp"},
+	SortByUpdatedAt: {expr: "COALESCE(b.updated_at, b.created_at)", typ: "timestamp"},
+}
+
+const bidColumns = "b.id, b.name, b.description, b.tender_id, b.author_id, b.author_type, b.status, b.version, b.created_at"
+
+func scanBid(rows pgx.Rows, key *string) (api.Bid, string, error) {
+	var b api.Bid
+	var createdAt time.Time
+
+	err := rows.Scan(
+		&b.Id,
+		&b.Name,
+		&b.Description,
+		&b.TenderId,
+		&b.AuthorId,
+		&b.AuthorType,
+		&b.Status,
+		&b.Version,
+		&createdAt,
+		key,
+	)
+	b.CreatedAt = createdAt.Format(time.RFC3339)
+	return b, b.Id, err
+}
+
+// Получение страницы списка предложений для тендера.
+// Ответственные за организацию тендера видят опубликованные и рассмотренные
+// предложения, авторы - свои предложения в любом статусе. Остальным
+// пользователям доступ запрещен.
+func (db *DB) GetBidsForTender(ctx context.Context, tenderId string, username string, page Page) ([]api.Bid, PageInfo, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	access, err := getTenderAccess(ctx, db.Pool, tenderId, username)
+	if err != nil {
+		db.logAccessError(ctx, "Error checking access to bids of tender", err, slog.String("username", username), slog.String("tender_id", tenderId))
+		return nil, PageInfo{}, err
+	}
+	userId := access.UserId
+	seeAll := db.Policy.Allowed(authz.TenderBids, access.Subject, string(access.Status))
+
+	var hasOwnBids bool
+	query := `
+        SELECT EXISTS(
+            SELECT 1 FROM bids b
+            WHERE b.tender_id = $1
+            AND (b.author_id = $2 OR b.author_id IN (SELECT organization_id FROM organization_responsible WHERE user_id = $2))
+        )
+    `
+	err = db.Pool.QueryRow(ctx, query, tenderId, userId).Scan(&hasOwnBids)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error checking access to bids of tender", slog.String("username", username), slog.String("tender_id", tenderId), sl.Err(err))
+		return nil, PageInfo{}, err
+	}
+	if !seeAll && !hasOwnBids {
+		db.Log.InfoContext(ctx, "Viewing bids of tender is not allowed", slog.String("username", username), slog.String("tender_id", tenderId))
+		return nil, PageInfo{}, ErrForbidden
+	}
+
+	bids, info, err := queryPage(ctx, db.Pool, pageQuery{
+		columns: bidColumns,
+		from: `
+        FROM bids b
+        WHERE b.tender_id = $1
+        AND (
+            ($3 AND b.status IN ('PUBLISHED', 'APPROVED', 'REJECTED'))
+            OR b.author_id = $2
+            OR b.author_id IN (SELECT organization_id FROM organization_responsible WHERE user_id = $2)
+        )`,
+		args: []interface{}{tenderId, userId, seeAll},
+		sort: bidSortColumns,
+		id:   "b.id",
+	}, page, scanBid)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error getting bids of tender", slog.String("tender_id", tenderId), sl.Err(err))
+		return nil, PageInfo{}, err
+	}
+
+	db.Log.DebugContext(ctx, "Successfully retrieved bids of tender", slog.Int("count", len(bids)), slog.String("tender_id", tenderId), slog.String("username", username))
+	return bids, info, nil
+}
+
+// Получение текущего статуса предложения. Статус доступен автору и
+// ответственным за организацию, которой принадлежит тендер.
+func (db *DB) GetBidStatus(ctx context.Context, bidId string, username string) (string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	access, err := db.authorizeBid(ctx, db.Pool, bidId, username, authz.BidView)
+	if err != nil {
+		return "", err
+	}
+
+	db.Log.DebugContext(ctx, "Successfully retrieved status of bid", slog.String("bid_id", bidId), slog.String("status", string(access.Status)))
+	return string(access.Status), nil
+}
+
+// Изменение статуса предложения автором. Переход проверяется
+// по машине состояний предложения, как и у тендера, смена статуса
+// создает новую версию.
+func (db *DB) UpdateBidStatus(ctx context.Context, bidId string, status api.BidStatus, username string) (api.Bid, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	newStatus, err := ParseBidStatus(status)
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
+		return api.Bid{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `SELECT 1 FROM bids WHERE id = $1 FOR UPDATE`, bidId)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error locking bid", slog.String("bid_id", bidId), sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	db.Log.DebugContext(ctx, "Checking permission to update bid", slog.String("username", username), slog.String("bid_id", bidId))
+	access, err := db.authorizeBid(ctx, tx, bidId, username, authz.BidStatus)
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	if err := CheckManualBidTransition(access.Status, newStatus); err != nil {
+		db.Log.InfoContext(ctx, "Rejected status change of bid", slog.String("bid_id", bidId), sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	var updatedBid api.Bid
+	var createdAt time.Time
+	query := `
+        UPDATE bids
+        SET status = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $2
+        RETURNING id, name, description, tender_id, author_id, author_type, status, version, created_at
+    `
+	err = tx.QueryRow(ctx, query, string(newStatus), bidId).Scan(
+		&updatedBid.Id,
+		&updatedBid.Name,
+		&updatedBid.Description,
+		&updatedBid.TenderId,
+		&updatedBid.AuthorId,
+		&updatedBid.AuthorType,
+		&updatedBid.Status,
+		&updatedBid.Version,
+		&createdAt,
+	)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error updating status of bid", slog.String("bid_id", bidId), sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	err = db.insertBidVersion(ctx, tx, bidId, username, BidChangeStatus)
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	updatedBid.CreatedAt = createdAt.Format(time.RFC3339)
+
+	err = db.insertEvent(ctx, tx, events.BidStatusChanged, events.BidStatusChange{
+		Bid:            updatedBid,
+		PreviousStatus: string(access.Status),
+		Username:       username,
+	})
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	db.Log.InfoContext(ctx, "Successfully updated status of bid", slog.String("bid_id", bidId), slog.String("status", string(updatedBid.Status)))
+	return updatedBid, nil
+}
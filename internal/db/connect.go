@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Повторные попытки подключения к базе данных при старте приложения.
+// Интервал между попытками удваивается от InitialBackoff до MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+	}
+}
+
+// Пауза перед попыткой attempt + 1 после неудачной попытки attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// Подключается к базе данных, повторяя попытки по policy, пока база не
+// станет доступна. Ожидание прерывается отменой ctx.
+func ConnectWithRetry(ctx context.Context, conn string, policy RetryPolicy, log *slog.Logger) (*DB, error) {
+	for attempt := 1; ; attempt++ {
+		db, err := NewDB(ctx, conn, log)
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= policy.MaxAttempts {
+			return nil, fmt.Errorf("database is not available after %d attempts: %w", attempt, err)
+		}
+
+		delay := policy.backoff(attempt)
+		log.WarnContext(ctx, "Database is not available",
+			slog.Int("attempt", attempt),
+			slog.Int("max_attempts", policy.MaxAttempts),
+			slog.Duration("retry_in", delay),
+			sl.Err(err),
+		)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
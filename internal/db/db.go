@@ -4,11 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	_ "github.com/jackc/pgconn"
 	_ "github.com/jackc/pgx/stdlib"
 	"github.com/jackc/pgx/v4"
@@ -16,139 +15,164 @@ import (
 	_ "github.com/lib/pq"
 
 	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/netguard"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
 )
 
 type DB struct {
-	Pool *pgxpool.Pool
+	Pool         *pgxpool.Pool
+	TenderStates *TenderStateMachine
+	Policy       *authz.Policy
+	Log          *slog.Logger
+	// Непубличные адреса, на которые разрешено регистрировать вебхуки
+	WebhookHosts *netguard.Allowlist
+
+	// Ограничение времени одной операции с базой данных. Отсчитывается от
+	// вызова метода и сокращает дедлайн контекста запроса, если он дальше.
+	// Ноль - без ограничения.
+	QueryTimeout time.Duration
 }
 
 var (
-	ErrForbidden      = errors.New("forbidden")
-	ErrUserNotFound   = errors.New("user not found")
-	ErrTenderNotFound = errors.New("tender not found")
-	ErrBidNotFound    = errors.New("bid not found")
+	ErrForbidden       = newError(KindForbidden, "forbidden")
+	ErrUserNotFound    = newError(KindUnauthorized, "user not found")
+	ErrTenderNotFound  = newError(KindNotFound, "tender not found")
+	ErrBidNotFound     = newError(KindNotFound, "bid not found")
+	ErrVersionNotFound = newError(KindNotFound, "version not found")
+	ErrAuthorNotFound  = newError(KindNotFound, "author not found")
+
+	ErrDecisionNotAllowed       = newError(KindValidation, "decision is not allowed for this bid")
+	ErrDecisionAlreadySubmitted = newError(KindValidation, "decision already submitted")
+	ErrFeedbackNotAllowed       = newError(KindForbidden, "feedback is allowed only for published bids or bids with a decision")
 )
 
-func NewDB(ctx context.Context, conn string) (*DB, error) {
-	time.Sleep(time.Second)
+// Подключается к базе данных и проверяет соединение.
+// Для ожидания запуска базы используется ConnectWithRetry.
+func NewDB(ctx context.Context, conn string, log *slog.Logger) (*DB, error) {
 	pool, err := pgxpool.Connect(ctx, conn)
 	if err != nil {
 		return nil, err
 	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	tenderStates, err := NewTenderStateMachine()
+	if err != nil {
+		return nil, err
+	}
 	return &DB{
-		Pool: pool,
+		Pool:         pool,
+		TenderStates: tenderStates,
+		Policy:       authz.Default(),
+		Log:          log,
 	}, nil
 }
 
-func (db *DB) GetTenders(filters api.GetTendersParams) ([]api.Tender, error) {
-	var tenders []api.Tender
-	var queryBuilder strings.Builder
-	var args []interface{}
-	var argCount int
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.QueryTimeout)
+}
 
-	queryBuilder.WriteString(`
-        SELECT id, name, description, organization_id, service_type, status, version, created_at
-        FROM tenders
-        WHERE 1=1
-    `)
+// Вызвана ли ошибка истечением дедлайна операции с хранилищем
+func IsTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
 
-	if filters.ServiceType != nil && len(*filters.ServiceType) > 0 {
-		argCount++
-		queryBuilder.WriteString(fmt.Sprintf(" AND service_type = ANY($%d)", argCount))
-		args = append(args, *filters.ServiceType)
-	}
+// Поля сортировки тендеров в запросах с псевдонимом t
+var tenderSortColumns = sortColumns{
+	SortByName:      {expr: `t.name COLLATE "C"`, typ: "text"},
+	SortByCreatedAt: {expr: "t.created_at", typ: "timestamp"},
+	SortByUpdatedAt: {expr: "COALESCE(t.updated_at, t.created_at)", typ: "timestamp"},
+}
 
-	if filters.Limit != nil {
-		argCount++
-		queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", argCount))
-		args = append(args, *filters.Limit)
-	}
+const tenderColumns = "t.id, t.name, t.description, t.organization_id, t.service_type, t.status, t.version, t.created_at"
 
-	if filters.Offset != nil {
-		argCount++
-		queryBuilder.WriteString(fmt.Sprintf(" OFFSET $%d", argCount))
-		args = append(args, *filters.Offset)
-	}
+func scanTender(rows pgx.Rows, key *string) (api.Tender, string, error) {
+	var t api.Tender
+	var createdAt time.Time
 
-	query := queryBuilder.String()
+	err := rows.Scan(
+		&t.Id,
+		&t.Name,
+		&t.Description,
+		&t.OrganizationId,
+		&t.ServiceType,
+		&t.Status,
+		&t.Version,
+		&createdAt,
+		key,
+	)
+	t.CreatedAt = createdAt.Format(time.RFC3339)
+	return t, t.Id, err
+}
 
-	log.Printf("Executing query to get tenders: %s with args: %v", query, args)
+// Получение страницы списка тендеров с фильтром по видам услуг
+func (db *DB) GetTenders(ctx context.Context, serviceTypes []api.TenderServiceType, page Page) ([]api.Tender, PageInfo, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 
-	rows, err := db.Pool.Query(context.Background(), query, args...)
-	if err != nil {
-		log.Printf("Error executing query to get tenders: %v", err)
-		return nil, err
+	query := pageQuery{
+		columns: tenderColumns,
+		from:    "FROM tenders t WHERE 1=1",
+		sort:    tenderSortColumns,
+		id:      "t.id",
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var t api.Tender
-		var createdAt time.Time
-
-		err := rows.Scan(
-			&t.Id,
-			&t.Name,
-			&t.Description,
-			&t.OrganizationId,
-			&t.ServiceType,
-			&t.Status,
-			&t.Version,
-			&createdAt,
-		)
-		if err != nil {
-			log.Printf("Error scanning row: %v", err)
-			return nil, err
-		}
-
-		t.CreatedAt = createdAt.Format(time.RFC3339)
-		tenders = append(tenders, t)
+	if len(serviceTypes) > 0 {
+		query.from += " AND t.service_type = ANY($1)"
+		query.args = append(query.args, serviceTypes)
 	}
 
-	if err := rows.Err(); err != nil {
-		log.Printf("Error after processing rows: %v", err)
-		return nil, err
+	tenders, info, err := queryPage(ctx, db.Pool, query, page, scanTender)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error getting tenders", sl.Err(err))
+		return nil, PageInfo{}, err
 	}
 
-	log.Printf("Successfully retrieved %d tenders", len(tenders))
-
-	return tenders, nil
+	db.Log.DebugContext(ctx, "Successfully retrieved tenders", slog.Int("count", len(tenders)))
+	return tenders, info, nil
 }
 
 // Создание нового тендера
-func (db *DB) CreateTender(tender api.Tender, creatorUsername string) (api.Tender, error) {
-	tx, err := db.Pool.Begin(context.Background())
+func (db *DB) CreateTender(ctx context.Context, tender api.Tender, creatorUsername string) (api.Tender, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		log.Printf("Error starting transaction: %v", err)
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
 		return api.Tender{}, fmt.Errorf("could not start transaction: %v", err)
 	}
-	defer func() {
-		if err != nil {
-			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
-				log.Printf("Error rolling back transaction: %v", rollbackErr)
-			}
-		}
-	}()
+	defer tx.Rollback(ctx)
 
-	var employeeExists bool
-	checkEmployeeQuery := `SELECT EXISTS(SELECT 1 FROM employee WHERE username = $1)`
-	err = tx.QueryRow(context.Background(), checkEmployeeQuery, creatorUsername).Scan(&employeeExists)
+	userId, err := getUserId(ctx, tx, creatorUsername)
 	if err != nil {
-		log.Printf("Error checking employee existence: %v", err)
-		return api.Tender{}, fmt.Errorf("could not check employee existence: %v", err)
-	}
-	if !employeeExists {
-		return api.Tender{}, fmt.Errorf("employee does not exist")
+		return api.Tender{}, err
 	}
 
-	var organizationExists bool
-	checkOrganizationQuery := `SELECT EXISTS(SELECT 1 FROM organization WHERE id = $1)`
-	err = tx.QueryRow(context.Background(), checkOrganizationQuery, tender.OrganizationId).Scan(&organizationExists)
+	var organizationExists, isResponsible bool
+	checkOrganizationQuery := `
+        SELECT
+            EXISTS(SELECT 1 FROM organization WHERE id = $1),
+            EXISTS(SELECT 1 FROM organization_responsible WHERE organization_id = $1 AND user_id = $2)
+    `
+	err = tx.QueryRow(ctx, checkOrganizationQuery, tender.OrganizationId, userId).Scan(&organizationExists, &isResponsible)
 	if err != nil {
-		log.Printf("Error checking organization existence: %v", err)
+		db.Log.ErrorContext(ctx, "Error checking organization existence", sl.Err(err))
 		return api.Tender{}, fmt.Errorf("could not check organization existence: %v", err)
 	}
 	if !organizationExists {
-		return api.Tender{}, fmt.Errorf("organization does not exist")
+		return api.Tender{}, ErrOrganizationNotFound
+	}
+
+	subject := newSubject(creatorUsername, map[authz.Role]bool{authz.RoleOrgResponsible: isResponsible})
+	if !db.Policy.Allowed(authz.TenderCreate, subject, "") {
+		db.Log.InfoContext(ctx, "Creating tenders for organization is not allowed", slog.String("username", creatorUsername), slog.String("organization_id", tender.OrganizationId))
+		return api.Tender{}, ErrForbidden
 	}
 
 	query := `
@@ -160,7 +184,7 @@ func (db *DB) CreateTender(tender api.Tender, creatorUsername string) (api.Tende
 	var createdTender api.Tender
 	var createdAt time.Time
 
-	err = tx.QueryRow(context.Background(), query,
+	err = tx.QueryRow(ctx, query,
 		tender.Name,
 		tender.Description,
 		tender.OrganizationId,
@@ -180,118 +204,89 @@ func (db *DB) CreateTender(tender api.Tender, creatorUsername string) (api.Tende
 	)
 
 	if err != nil {
-		log.Printf("Error creating tender: %v", err)
-		return api.Tender{}, ErrForbidden
+		db.Log.ErrorContext(ctx, "Error creating tender", sl.Err(err))
+		return api.Tender{}, fmt.Errorf("could not create tender: %w", err)
 	}
 
-	if err := tx.Commit(context.Background()); err != nil {
-		log.Printf("Error committing transaction: %v", err)
-		return api.Tender{}, fmt.Errorf("could not commit transaction: %v", err)
+	err = db.insertTenderVersion(ctx, tx, createdTender.Id, creatorUsername, TenderChangeCreated)
+	if err != nil {
+		return api.Tender{}, err
 	}
 
 	createdTender.CreatedAt = createdAt.Format(time.RFC3339)
 
-	log.Printf("Tender created successfully: %v", createdTender)
-	return createdTender, nil
-}
-
-func (db *DB) GetUserTenders(username string, limit int32, offset int32) ([]api.Tender, error) {
-	var tenders []api.Tender
-
-	query := `
-        SELECT id, name, description, organization_id, service_type, status, version, created_at
-        FROM tenders
-        WHERE organization_id = (
-            SELECT organization_id
-            FROM employee
-            WHERE username = $1
-        )
-        ORDER BY created_at DESC
-    `
-
-	if limit > 0 && offset >= 0 {
-		query += " LIMIT $2 OFFSET $3"
-	}
-
-	var rows pgx.Rows
-	var err error
-	if limit > 0 && offset >= 0 {
-		rows, err = db.Pool.Query(context.Background(), query, username, limit, offset)
-	} else {
-		rows, err = db.Pool.Query(context.Background(), query, username)
-	}
-
+	err = db.insertEvent(ctx, tx, events.TenderCreated, events.TenderChange{Tender: createdTender, Username: creatorUsername})
 	if err != nil {
-		log.Printf("Error executing query: %v", err)
-		return nil, err
+		return api.Tender{}, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var t api.Tender
-		var createdAt time.Time
-
-		err := rows.Scan(
-			&t.Id,
-			&t.Name,
-			&t.Description,
-			&t.OrganizationId,
-			&t.ServiceType,
-			&t.Status,
-			&t.Version,
-			&createdAt,
-		)
-		if err != nil {
-			log.Printf("Error scanning row: %v", err)
-			return nil, err
-		}
 
-		t.CreatedAt = createdAt.Format(time.RFC3339)
-		tenders = append(tenders, t)
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return api.Tender{}, fmt.Errorf("could not commit transaction: %v", err)
 	}
 
-	if err := rows.Err(); err != nil {
-		log.Printf("Error after processing rows: %v", err)
-		return nil, err
-	}
+	db.Log.InfoContext(ctx, "Tender created", slog.String("tender_id", createdTender.Id), slog.String("username", creatorUsername))
+	return createdTender, nil
+}
 
-	log.Printf("Successfully retrieved %d tenders for user %s", len(tenders), username)
+// Тендеры организации, за которую отвечает пользователь
+func (db *DB) GetUserTenders(ctx context.Context, username string, page Page) ([]api.Tender, PageInfo, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tenders, info, err := queryPage(ctx, db.Pool, pageQuery{
+		columns: tenderColumns,
+		from: `
+        FROM tenders t
+        WHERE t.organization_id IN (
+            SELECT r.organization_id
+            FROM organization_responsible r
+            JOIN employee e ON e.id = r.user_id
+            WHERE e.username = $1
+        )`,
+		args: []interface{}{username},
+		sort: tenderSortColumns,
+		id:   "t.id",
+	}, page, scanTender)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error getting tenders of user", slog.String("username", username), sl.Err(err))
+		return nil, PageInfo{}, err
+	}
 
-	return tenders, nil
+	db.Log.DebugContext(ctx, "Successfully retrieved tenders of user", slog.Int("count", len(tenders)), slog.String("username", username))
+	return tenders, info, nil
 }
 
-func (db *DB) EditTender(tenderId string, name string, description string, serviceType string, creatorUsername string) (api.Tender, error) {
-	tx, err := db.Pool.Begin(context.Background())
+func (db *DB) EditTender(ctx context.Context, tenderId string, name string, description string, serviceType string, creatorUsername string) (api.Tender, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		log.Printf("Error starting transaction: %v", err)
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
 		return api.Tender{}, err
 	}
-	defer tx.Rollback(context.Background())
+	defer tx.Rollback(ctx)
 
 	var updatedTender api.Tender
 	var createdAt time.Time
 
-	log.Printf("Checking permission for user %s to edit tender %s", creatorUsername, tenderId)
-	hasPermission, err := db.CheckUserTenderPermission(tenderId, creatorUsername, "edit")
-	if err != nil {
-		log.Printf("Error checking permission for user %s on tender %s: %v", creatorUsername, tenderId, err)
+	db.Log.DebugContext(ctx, "Checking permission to edit tender", slog.String("username", creatorUsername), slog.String("tender_id", tenderId))
+	if _, err := db.authorizeTender(ctx, tx, tenderId, creatorUsername, authz.TenderEdit); err != nil {
 		return api.Tender{}, err
 	}
-	if !hasPermission {
-		log.Printf("User %s does not have permission to view tender %s", creatorUsername, tenderId)
-		return api.Tender{}, ErrForbidden
-	}
 
 	query := `
         UPDATE tenders
-        SET name = $1, description = $2, service_type = $3, version = version + 1
+        SET name = COALESCE(NULLIF($1, ''), name), description = COALESCE(NULLIF($2, ''), description),
+            service_type = COALESCE(NULLIF($3, ''), service_type), version = version + 1, updated_at = CURRENT_TIMESTAMP
         WHERE id = $4
         RETURNING id, name, description, organization_id, service_type, status, version, created_at
     `
 
-	log.Printf("Editing tender: id=%s, name=%s, description=%s, serviceType=%s", tenderId, name, description, serviceType)
+	db.Log.DebugContext(ctx, "Editing tender", slog.String("tender_id", tenderId), slog.String("username", creatorUsername))
 
-	err = db.Pool.QueryRow(context.Background(), query, name, description, serviceType, tenderId).Scan(
+	err = tx.QueryRow(ctx, query, name, description, serviceType, tenderId).Scan(
 		&updatedTender.Id,
 		&updatedTender.Name,
 		&updatedTender.Description,
@@ -302,136 +297,181 @@ func (db *DB) EditTender(tenderId string, name string, description string, servi
 		&createdAt,
 	)
 	if err != nil {
-		log.Printf("Error updating tender with id=%s: %v", tenderId, err)
+		db.Log.ErrorContext(ctx, "Error updating tender", slog.String("tender_id", tenderId), sl.Err(err))
+		return api.Tender{}, err
+	}
+
+	err = db.insertTenderVersion(ctx, tx, tenderId, creatorUsername, TenderChangeEdited)
+	if err != nil {
 		return api.Tender{}, err
 	}
 
 	updatedTender.CreatedAt = createdAt.Format(time.RFC3339)
 
-	err = tx.Commit(context.Background())
+	err = db.insertEvent(ctx, tx, events.TenderEdited, events.TenderChange{Tender: updatedTender, Username: creatorUsername})
+	if err != nil {
+		return api.Tender{}, err
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
-		log.Printf("Error committing transaction: %v", err)
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
 		return api.Tender{}, err
 	}
 
-	log.Printf("Successfully updated tender with id=%s", updatedTender.Id)
+	db.Log.InfoContext(ctx, "Successfully updated tender", slog.String("tender_id", updatedTender.Id))
 
 	return updatedTender, nil
 }
 
-func (db *DB) RollbackTender(tenderId string, version int, username string) (api.Tender, error) {
-	log.Printf("Rolling back tender %s to version %d by user %s", tenderId, version, username)
+// Откат тендера к версии из истории. Откат считается новой правкой:
+// параметры исторической версии копируются в тендер, версия увеличивается.
+func (db *DB) RollbackTender(ctx context.Context, tenderId string, version int, username string) (api.Tender, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 
-	var updatedTender api.Tender
-	var existingTender api.Tender
-	var createdAt time.Time
+	db.Log.InfoContext(ctx, "Rolling back tender", slog.String("tender_id", tenderId), slog.Any("version", version), slog.String("username", username))
 
-	query := `
-        SELECT id, name, description, service_type, status, version, created_at
-        FROM tenders
-        WHERE id = $1 AND version = $2
-    `
-	err := db.Pool.QueryRow(context.Background(), query, tenderId, version).Scan(
-		&existingTender.Id,
-		&existingTender.Name,
-		&existingTender.Description,
-		&existingTender.ServiceType,
-		&existingTender.Status,
-		&existingTender.Version,
-		&createdAt,
-	)
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			log.Printf("No tender found with id %s and version %d", tenderId, version)
-			return api.Tender{}, ErrTenderNotFound
-		}
-		log.Printf("Error retrieving tender %s with version %d: %v", tenderId, version, err)
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
 		return api.Tender{}, err
 	}
+	defer tx.Rollback(ctx)
 
-	log.Printf("Tender found for rollback: %v", existingTender)
+	if _, err := db.authorizeTender(ctx, tx, tenderId, username, authz.TenderRollback); err != nil {
+		return api.Tender{}, err
+	}
 
-	query = `
-        UPDATE tenders
-        SET name = $1, description = $2, service_type = $3, version = version + 1
-        WHERE id = $4
-        RETURNING id, name, description, service_type, status, version, created_at
+	var updatedTender api.Tender
+	var createdAt time.Time
+
+	query := `
+        UPDATE tenders t
+        SET name = v.name, description = v.description, service_type = v.service_type,
+            version = t.version + 1, updated_at = CURRENT_TIMESTAMP
+        FROM tender_versions v
+        WHERE t.id = $1 AND v.tender_id = t.id AND v.version = $2
+        RETURNING t.id, t.name, t.description, t.organization_id, t.service_type, t.status, t.version, t.created_at
     `
-	err = db.Pool.QueryRow(context.Background(), query, existingTender.Name, existingTender.Description, existingTender.ServiceType, tenderId).Scan(
+	err = tx.QueryRow(ctx, query, tenderId, version).Scan(
 		&updatedTender.Id,
 		&updatedTender.Name,
 		&updatedTender.Description,
+		&updatedTender.OrganizationId,
 		&updatedTender.ServiceType,
 		&updatedTender.Status,
 		&updatedTender.Version,
 		&createdAt,
 	)
 	if err != nil {
-		log.Printf("Error updating tender %s during rollback: %v", tenderId, err)
+		if err == pgx.ErrNoRows {
+			db.Log.InfoContext(ctx, "Version of tender not found", slog.Any("version", version), slog.String("tender_id", tenderId))
+			return api.Tender{}, ErrVersionNotFound
+		}
+		db.Log.ErrorContext(ctx, "Error updating tender during rollback", slog.String("tender_id", tenderId), sl.Err(err))
+		return api.Tender{}, err
+	}
+
+	err = db.insertTenderVersion(ctx, tx, tenderId, username, TenderChangeRolledBack)
+	if err != nil {
 		return api.Tender{}, err
 	}
 
 	updatedTender.CreatedAt = createdAt.Format(time.RFC3339)
 
-	log.Printf("Successfully rolled back tender %s to version %d", tenderId, updatedTender.Version)
+	err = db.insertEvent(ctx, tx, events.TenderRolledBack, events.TenderChange{Tender: updatedTender, Username: username})
+	if err != nil {
+		return api.Tender{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return api.Tender{}, err
+	}
+
+	db.Log.InfoContext(ctx, "Successfully rolled back tender", slog.String("tender_id", tenderId), slog.Any("version", version), slog.Any("new_version", updatedTender.Version))
 	return updatedTender, nil
 }
 
-func (db *DB) GetTenderStatus(tenderId string, username string) (string, error) {
-	log.Printf("Checking permission for user %s to view tender %s", username, tenderId)
-	hasPermission, err := db.CheckUserTenderPermission(tenderId, username, "edit")
+func (db *DB) GetTenderStatus(ctx context.Context, tenderId string, username string) (string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	db.Log.DebugContext(ctx, "Checking permission to view tender", slog.String("username", username), slog.String("tender_id", tenderId))
+	access, err := db.authorizeTender(ctx, db.Pool, tenderId, username, authz.TenderView)
 	if err != nil {
-		log.Printf("Error checking permission for user %s on tender %s: %v", username, tenderId, err)
 		return "", err
 	}
-	if !hasPermission {
-		log.Printf("User %s does not have permission to view tender %s", username, tenderId)
-		return "", ErrForbidden
+
+	db.Log.DebugContext(ctx, "Successfully retrieved status of tender", slog.String("tender_id", tenderId), slog.String("status", string(access.Status)))
+	return string(access.Status), nil
+}
+
+// Изменение статуса тендера по машине состояний.
+// Повторная установка текущего статуса ничего не меняет и не увеличивает версию.
+func (db *DB) UpdateTenderStatus(ctx context.Context, tenderId string, status api.TenderStatus, username string) (api.Tender, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	newStatus, err := ParseTenderStatus(status)
+	if err != nil {
+		return api.Tender{}, err
 	}
 
-	var status string
-	query := `
-        SELECT status
-        FROM tenders
-        WHERE id = $1
-    `
-	err = db.Pool.QueryRow(context.Background(), query, tenderId).Scan(&status)
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			log.Printf("Tender with id %s not found", tenderId)
-			return "", ErrTenderNotFound
-		}
-		log.Printf("Error retrieving tender status for id %s: %v", tenderId, err)
-		return "", err
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
+		return api.Tender{}, err
 	}
+	defer tx.Rollback(ctx)
 
-	log.Printf("Successfully retrieved status for tender %s: %s", tenderId, status)
-	return status, nil
-}
+	_, err = tx.Exec(ctx, `SELECT 1 FROM tenders WHERE id = $1 FOR UPDATE`, tenderId)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error locking tender", slog.String("tender_id", tenderId), sl.Err(err))
+		return api.Tender{}, err
+	}
 
-func (db *DB) UpdateTenderStatus(tenderId string, status api.TenderStatus, username string) (api.Tender, error) {
-	log.Printf("Checking permission for user %s to update tender %s", username, tenderId)
-	hasPermission, err := db.CheckUserTenderPermission(tenderId, username, "edit")
+	db.Log.DebugContext(ctx, "Checking permission to update tender", slog.String("username", username), slog.String("tender_id", tenderId))
+	access, err := getTenderAccess(ctx, tx, tenderId, username)
 	if err != nil {
-		log.Printf("Error checking permission for user %s on tender %s: %v", username, tenderId, err)
+		db.logAccessError(ctx, "Error checking permission on tender", err, slog.String("username", username), slog.String("tender_id", tenderId))
 		return api.Tender{}, err
 	}
-	if !hasPermission {
-		log.Printf("User %s does not have permission to update tender %s", username, tenderId)
-		return api.Tender{}, ErrForbidden
+
+	if access.Status == newStatus {
+		if !db.Policy.Allowed(authz.TenderView, access.Subject, string(access.Status)) {
+			return api.Tender{}, ErrForbidden
+		}
+		db.Log.InfoContext(ctx, "Tender is already in status", slog.String("tender_id", tenderId), slog.String("status", string(newStatus)))
+		return db.selectTender(ctx, tx, tenderId)
 	}
 
-	updatedStatus := strings.ToUpper(string(status))
+	// Недопустимый переход раскрывается только тем, кто управляет тендером:
+	// историю тендера видят ответственные в любом статусе, а правка закрытого
+	// тендера запрещена
+	action, err := db.TenderStates.Transition(access.Status, newStatus)
+	if err != nil {
+		if !db.Policy.Allowed(authz.TenderHistory, access.Subject, string(access.Status)) {
+			return api.Tender{}, ErrForbidden
+		}
+		db.Log.InfoContext(ctx, "Rejected status change of tender", slog.String("tender_id", tenderId), sl.Err(err))
+		return api.Tender{}, err
+	}
+	if !db.Policy.Allowed(action, access.Subject, string(access.Status)) {
+		db.Log.InfoContext(ctx, "Status change of tender is not allowed", slog.String("username", username), slog.String("tender_id", tenderId), slog.String("from", string(access.Status)), slog.String("to", string(newStatus)))
+		return api.Tender{}, ErrForbidden
+	}
 
 	var updatedTender api.Tender
 	var createdAt time.Time
 	query := `
         UPDATE tenders
-        SET status = $1, version = version + 1
+        SET status = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
         WHERE id = $2
         RETURNING id, name, description, organization_id, service_type, status, version, created_at
     `
-	err = db.Pool.QueryRow(context.Background(), query, updatedStatus, tenderId).Scan(
+	err = tx.QueryRow(ctx, query, string(newStatus), tenderId).Scan(
 		&updatedTender.Id,
 		&updatedTender.Name,
 		&updatedTender.Description,
@@ -442,122 +482,147 @@ func (db *DB) UpdateTenderStatus(tenderId string, status api.TenderStatus, usern
 		&createdAt,
 	)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			log.Printf("Tender with id %s not found", tenderId)
-			return api.Tender{}, ErrTenderNotFound
-		}
-		log.Printf("Error updating status for tender %s: %v", tenderId, err)
+		db.Log.ErrorContext(ctx, "Error updating status of tender", slog.String("tender_id", tenderId), sl.Err(err))
 		return api.Tender{}, err
 	}
 
-	updatedTender.CreatedAt = createdAt.Format(time.RFC3339)
-	log.Printf("Successfully updated status for tender %s to %s", tenderId, updatedTender.Status)
-	return updatedTender, nil
-}
-
-func (db *DB) GetUserBids(limit int32, offset int32, username string) ([]api.Bid, error) {
-	var bids []api.Bid
+	err = db.insertTenderVersion(ctx, tx, tenderId, username, TenderChangeStatus)
+	if err != nil {
+		return api.Tender{}, err
+	}
 
-	query := `
-        SELECT id, name, description, tender_id, author_id, author_type, status, version, created_at
-        FROM bids
-        WHERE author_id = (
-            SELECT id
-            FROM employee
-            WHERE username = $1
-        )
-        ORDER BY created_at DESC
-    `
+	updatedTender.CreatedAt = createdAt.Format(time.RFC3339)
 
-	if limit > 0 && offset >= 0 {
-		query += " LIMIT $2 OFFSET $3"
+	err = db.insertEvent(ctx, tx, events.TenderStatusChanged, events.TenderStatusChange{
+		Tender:         updatedTender,
+		PreviousStatus: string(access.Status),
+		Username:       username,
+	})
+	if err != nil {
+		return api.Tender{}, err
 	}
 
-	var rows pgx.Rows
-	var err error
-	if limit > 0 && offset >= 0 {
-		rows, err = db.Pool.Query(context.Background(), query, username, limit, offset)
-	} else {
-		rows, err = db.Pool.Query(context.Background(), query, username)
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return api.Tender{}, err
 	}
+	db.Log.InfoContext(ctx, "Successfully updated status of tender", slog.String("tender_id", tenderId), slog.String("status", string(updatedTender.Status)))
+	return updatedTender, nil
+}
 
+// Получение тендера по идентификатору
+func (db *DB) selectTender(ctx context.Context, q querier, tenderId string) (api.Tender, error) {
+	var tender api.Tender
+	var createdAt time.Time
+	query := `
+        SELECT id, name, description, organization_id, service_type, status, version, created_at
+        FROM tenders
+        WHERE id = $1
+    `
+	err := q.QueryRow(ctx, query, tenderId).Scan(
+		&tender.Id,
+		&tender.Name,
+		&tender.Description,
+		&tender.OrganizationId,
+		&tender.ServiceType,
+		&tender.Status,
+		&tender.Version,
+		&createdAt,
+	)
 	if err != nil {
-		log.Printf("Error executing query: %v", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var b api.Bid
-		var createdAt time.Time
-
-		err := rows.Scan(
-			&b.Id,
-			&b.Name,
-			&b.Description,
-			&b.TenderId,
-			&b.AuthorId,
-			&b.AuthorType,
-			&b.Status,
-			&b.Version,
-			&createdAt,
-		)
-		if err != nil {
-			log.Printf("Error scanning row: %v", err)
-			return nil, err
+		if err == pgx.ErrNoRows {
+			return api.Tender{}, ErrTenderNotFound
 		}
-
-		b.CreatedAt = createdAt.Format(time.RFC3339)
-
-		bids = append(bids, b)
+		db.Log.ErrorContext(ctx, "Error retrieving tender", slog.String("tender_id", tenderId), sl.Err(err))
+		return api.Tender{}, err
 	}
 
-	if err := rows.Err(); err != nil {
-		log.Printf("Error after processing rows: %v", err)
-		return nil, err
-	}
+	tender.CreatedAt = createdAt.Format(time.RFC3339)
+	return tender, nil
+}
 
-	log.Printf("Successfully retrieved %d bids for user %s", len(bids), username)
+func (db *DB) GetUserBids(ctx context.Context, username string, page Page) ([]api.Bid, PageInfo, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	bids, info, err := queryPage(ctx, db.Pool, pageQuery{
+		columns: bidColumns,
+		from:    "FROM bids b WHERE b.author_id = (SELECT id FROM employee WHERE username = $1)",
+		args:    []interface{}{username},
+		sort:    bidSortColumns,
+		id:      "b.id",
+	}, page, scanBid)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error getting bids of user", slog.String("username", username), sl.Err(err))
+		return nil, PageInfo{}, err
+	}
 
-	return bids, nil
+	db.Log.DebugContext(ctx, "Successfully retrieved bids of user", slog.Int("count", len(bids)), slog.String("username", username))
+	return bids, info, nil
 }
 
-func (db *DB) CreateBid(bid api.Bid) (api.Bid, error) {
-	tx, err := db.Pool.Begin(context.Background())
+// Создание предложения пользователем username от имени автора bid.AuthorId.
+// Предложение пользователя без username создает сам автор.
+func (db *DB) CreateBid(ctx context.Context, bid api.Bid, username string) (api.Bid, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		log.Printf("Error starting transaction: %v", err)
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
 		return api.Bid{}, err
 	}
-	defer tx.Rollback(context.Background())
+	defer tx.Rollback(ctx)
 
 	var tenderExists bool
-	err = tx.QueryRow(context.Background(), `
+	err = tx.QueryRow(ctx, `
 		SELECT EXISTS(SELECT 1 FROM tenders WHERE id = $1)
 	`, bid.TenderId).Scan(&tenderExists)
 	if err != nil {
-		log.Printf("Error checking tender existence: %v", err)
+		db.Log.ErrorContext(ctx, "Error checking tender existence", sl.Err(err))
 		return api.Bid{}, err
 	}
 	if !tenderExists {
-		return api.Bid{}, fmt.Errorf("tender does not exist")
+		return api.Bid{}, ErrTenderNotFound
 	}
 
 	var authorExists bool
+	var authorUsername string
 	if bid.AuthorType == "USER" {
-		err = tx.QueryRow(context.Background(), `
-			SELECT EXISTS(SELECT 1 FROM employee WHERE id = $1)
-		`, bid.AuthorId).Scan(&authorExists)
+		err = tx.QueryRow(ctx, `
+			SELECT username FROM employee WHERE id = $1
+		`, bid.AuthorId).Scan(&authorUsername)
+		authorExists = err == nil
+		if err == pgx.ErrNoRows {
+			err = nil
+		}
 	} else if bid.AuthorType == "ORGANIZATION" {
-		err = tx.QueryRow(context.Background(), `
+		err = tx.QueryRow(ctx, `
 			SELECT EXISTS(SELECT 1 FROM organization WHERE id = $1)
 		`, bid.AuthorId).Scan(&authorExists)
 	}
 	if err != nil {
-		log.Printf("Error checking author existence: %v", err)
+		db.Log.ErrorContext(ctx, "Error checking author existence", sl.Err(err))
 		return api.Bid{}, err
 	}
+	// Автор-пользователь выполняет запрос сам, поэтому его отсутствие
+	// означает неизвестного пользователя, а не ненайденный объект
+	if !authorExists && bid.AuthorType == "USER" {
+		return api.Bid{}, ErrUserNotFound
+	}
 	if !authorExists {
-		return api.Bid{}, fmt.Errorf("author does not exist")
+		return api.Bid{}, ErrOrganizationNotFound
+	}
+
+	if username == "" {
+		username = authorUsername
+	}
+	if username == "" {
+		db.Log.InfoContext(ctx, "Creator of organization bid is not specified", slog.String("author_id", string(bid.AuthorId)))
+		return api.Bid{}, ErrUserNotFound
+	}
+	if err := db.authorizeBidCreate(ctx, tx, string(bid.AuthorId), username); err != nil {
+		return api.Bid{}, err
 	}
 
 	query := `
@@ -571,7 +636,7 @@ func (db *DB) CreateBid(bid api.Bid) (api.Bid, error) {
 
 	updatedAuthorType := strings.ToUpper(string(bid.AuthorType))
 
-	err = db.Pool.QueryRow(context.Background(), query,
+	err = tx.QueryRow(ctx, query,
 		bid.Name,
 		bid.Description,
 		bid.TenderId,
@@ -592,97 +657,43 @@ func (db *DB) CreateBid(bid api.Bid) (api.Bid, error) {
 	)
 
 	if err != nil {
-		log.Printf("Error creating bid: %v", err)
+		db.Log.ErrorContext(ctx, "Error creating bid", sl.Err(err))
 		return api.Bid{}, err
 	}
 
 	createdBid.CreatedAt = createdAt.Format(time.RFC3339)
 
-	err = tx.Commit(context.Background())
+	err = db.insertBidVersion(ctx, tx, createdBid.Id, username, BidChangeCreated)
 	if err != nil {
-		log.Printf("Error committing transaction: %v", err)
 		return api.Bid{}, err
 	}
 
-	log.Printf("Bid created successfully: %v", createdBid)
-	return createdBid, nil
-}
-
-func (db *DB) CheckUserTenderPermission(tenderId api.TenderId, username api.Username, action string) (bool, error) {
-	// Получаем идентификатор пользователя
-	var userId uuid.UUID
-	query := `SELECT id FROM employee WHERE username = $1`
-	err := db.Pool.QueryRow(context.Background(), query, username).Scan(&userId)
+	err = db.insertEvent(ctx, tx, events.BidCreated, events.BidChange{Bid: createdBid, Username: username})
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return false, ErrUserNotFound // Пользователь не найден
-		}
-		return false, err
+		return api.Bid{}, err
 	}
 
-	// Получаем статус тендера
-	var tenderStatus string
-	query = `SELECT status FROM tenders WHERE id = $1`
-	err = db.Pool.QueryRow(context.Background(), query, tenderId).Scan(&tenderStatus)
+	err = tx.Commit(ctx)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return false, ErrTenderNotFound // Тендер не найден
-		}
-		return false, err
-	}
-
-	// Определяем права пользователя в зависимости от действия
-	switch action {
-	case "create":
-		// Для создания тендера, пользователь должен быть ответственным за организацию
-		var isResponsible bool
-		query = `SELECT COUNT(*) > 0 
-                 FROM organization_responsible 
-                 WHERE user_id = $1 
-                 AND organization_id = (SELECT organization_id FROM tenders WHERE id = $2)`
-		err = db.Pool.QueryRow(context.Background(), query, userId, tenderId).Scan(&isResponsible)
-		if err != nil {
-			return false, err
-		}
-		return isResponsible, nil
-
-	case "publish":
-		// Публикация доступна любому авторизованному пользователю
-		return true, nil
-
-	case "close":
-		// Закрытие тендера доступно только ответственным за организацию
-		var isResponsible bool
-		query = `SELECT COUNT(*) > 0 
-                 FROM organization_responsible 
-                 WHERE user_id = $1 
-                 AND organization_id = (SELECT organization_id FROM tenders WHERE id = $2)`
-		err = db.Pool.QueryRow(context.Background(), query, userId, tenderId).Scan(&isResponsible)
-		if err != nil {
-			return false, err
-		}
-		return isResponsible, nil
-
-	case "edit":
-		// Редактирование доступно ответственным за организацию или если тендер находится в статусе "PUBLISHED"
-		var isResponsible bool
-		query = `SELECT COUNT(*) > 0 
-                 FROM organization_responsible 
-                 WHERE user_id = $1 
-                 AND organization_id = (SELECT organization_id FROM tenders WHERE id = $2)`
-		err = db.Pool.QueryRow(context.Background(), query, userId, tenderId).Scan(&isResponsible)
-		if err != nil {
-			return false, err
-		}
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return api.Bid{}, err
+	}
 
-		if tenderStatus == "PUBLISHED" || isResponsible {
-			return true, nil
-		}
-		return false, nil
+	db.Log.InfoContext(ctx, "Bid created", slog.String("bid_id", createdBid.Id), slog.String("tender_id", createdBid.TenderId))
+	return createdBid, nil
+}
 
-	default:
-		return false, fmt.Errorf("unknown action: %s", action)
-	}
+// Общий интерфейс пула соединений и транзакции для запросов одной строки
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Проверяет, что пул может выдать рабочее соединение с базой данных
+func (db *DB) Ping(ctx context.Context) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.Pool.Ping(ctx)
 }
 
 func (db *DB) Close() {
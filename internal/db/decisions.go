@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Максимальный размер кворума для согласования предложения
+const maxDecisionQuorum = 3
+
+// Отправка решения по предложению.
+// Решения принимаются только по опубликованным предложениям. Любое
+// отклонение сразу переводит предложение в REJECTED. Предложение
+// согласуется, когда число согласований достигает кворума
+// min(3, количество ответственных за организацию тендера); в той же
+// транзакции тендер закрывается.
+func (db *DB) SubmitBidDecision(ctx context.Context, bidId string, decision api.BidDecision, username string) (api.Bid, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
+		return api.Bid{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	userId, err := getUserId(ctx, tx, username)
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	// Блокируем предложение и тендер, чтобы параллельные решения не обошли кворум
+	var bidStatus, tenderStatus string
+	var tenderId, organizationId uuid.UUID
+	query := `
+        SELECT b.status, t.id, t.organization_id, t.status
+        FROM bids b
+        JOIN tenders t ON t.id = b.tender_id
+        WHERE b.id = $1
+        FOR UPDATE OF b, t
+    `
+	err = tx.QueryRow(ctx, query, bidId).Scan(&bidStatus, &tenderId, &organizationId, &tenderStatus)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			db.Log.InfoContext(ctx, "Bid not found", slog.String("bid_id", bidId))
+			return api.Bid{}, ErrBidNotFound
+		}
+		db.Log.ErrorContext(ctx, "Error retrieving bid", slog.String("bid_id", bidId), sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	if _, err := db.authorizeBid(ctx, tx, bidId, username, authz.BidDecide); err != nil {
+		return api.Bid{}, err
+	}
+
+	if TenderStatus(tenderStatus) != TenderStatusPublished {
+		db.Log.InfoContext(ctx, "Decision is not allowed for bid of unpublished tender", slog.String("bid_id", bidId), slog.String("tender_id", tenderId.String()), slog.String("tender_status", tenderStatus))
+		return api.Bid{}, ErrDecisionNotAllowed
+	}
+
+	updatedDecision := strings.ToUpper(string(decision))
+
+	// Решение допустимо, только если предложение может перейти в соответствующий статус
+	decisionStatus := BidStatusApproved
+	if updatedDecision == "REJECTED" {
+		decisionStatus = BidStatusRejected
+	}
+	if err := CheckBidTransition(BidStatus(bidStatus), decisionStatus); err != nil {
+		db.Log.InfoContext(ctx, "Decision is not allowed for bid", slog.String("bid_id", bidId), sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	tag, err := tx.Exec(ctx, `
+        INSERT INTO bid_decisions (bid_id, user_id, decision)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (bid_id, user_id) DO NOTHING
+    `, bidId, userId, updatedDecision)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error saving decision on bid", slog.String("bid_id", bidId), sl.Err(err))
+		return api.Bid{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		db.Log.InfoContext(ctx, "Decision for bid is already submitted", slog.String("username", username), slog.String("bid_id", bidId))
+		return api.Bid{}, ErrDecisionAlreadySubmitted
+	}
+
+	var newStatus BidStatus
+	if decisionStatus == BidStatusRejected {
+		newStatus = BidStatusRejected
+	} else {
+		var approvals, quorum int
+		query = `
+            SELECT
+                (SELECT COUNT(*) FROM bid_decisions WHERE bid_id = $1 AND decision = 'APPROVED'),
+                (SELECT LEAST($3::int, COUNT(*)) FROM organization_responsible WHERE organization_id = $2)
+        `
+		err = tx.QueryRow(ctx, query, bidId, organizationId, maxDecisionQuorum).Scan(&approvals, &quorum)
+		if err != nil {
+			db.Log.ErrorContext(ctx, "Error counting decisions for bid", slog.String("bid_id", bidId), sl.Err(err))
+			return api.Bid{}, err
+		}
+		db.Log.InfoContext(ctx, "Counted approvals of bid", slog.String("bid_id", bidId), slog.Int("approvals", approvals), slog.Int("quorum", quorum))
+		if approvals >= quorum {
+			newStatus = BidStatusApproved
+		}
+	}
+
+	if newStatus != "" {
+		_, err = tx.Exec(ctx, `UPDATE bids SET status = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, string(newStatus), bidId)
+		if err != nil {
+			db.Log.ErrorContext(ctx, "Error updating status of bid", slog.String("bid_id", bidId), sl.Err(err))
+			return api.Bid{}, err
+		}
+		err = db.insertBidVersion(ctx, tx, bidId, username, BidChangeStatus)
+		if err != nil {
+			return api.Bid{}, err
+		}
+	}
+
+	if newStatus == BidStatusApproved {
+		if _, err := db.TenderStates.Transition(TenderStatus(tenderStatus), TenderStatusClosed); err != nil {
+			db.Log.ErrorContext(ctx, "Cannot close tender after approval of bid", slog.String("tender_id", tenderId.String()), slog.String("bid_id", bidId), sl.Err(err))
+			return api.Bid{}, err
+		}
+		_, err = tx.Exec(ctx, `
+            UPDATE tenders
+            SET status = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $2
+        `, string(TenderStatusClosed), tenderId)
+		if err != nil {
+			db.Log.ErrorContext(ctx, "Error closing tender", slog.String("tender_id", tenderId.String()), sl.Err(err))
+			return api.Bid{}, err
+		}
+		err = db.insertTenderVersion(ctx, tx, tenderId.String(), username, TenderChangeStatus)
+		if err != nil {
+			return api.Bid{}, err
+		}
+		db.Log.InfoContext(ctx, "Tender closed after approval of bid", slog.String("tender_id", tenderId.String()), slog.String("bid_id", bidId))
+	}
+
+	var bid api.Bid
+	var createdAt time.Time
+	query = `
+        SELECT id, name, description, tender_id, author_id, author_type, status, version, created_at
+        FROM bids
+        WHERE id = $1
+    `
+	err = tx.QueryRow(ctx, query, bidId).Scan(
+		&bid.Id,
+		&bid.Name,
+		&bid.Description,
+		&bid.TenderId,
+		&bid.AuthorId,
+		&bid.AuthorType,
+		&bid.Status,
+		&bid.Version,
+		&createdAt,
+	)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error retrieving bid", slog.String("bid_id", bidId), sl.Err(err))
+		return api.Bid{}, err
+	}
+	bid.CreatedAt = createdAt.Format(time.RFC3339)
+
+	// События записываются в порядке последствий решения: само решение,
+	// затем смена статуса предложения и закрытие тендера
+	err = db.insertEvent(ctx, tx, events.BidDecisionSubmitted, events.BidDecision{Bid: bid, Decision: updatedDecision, Username: username})
+	if err != nil {
+		return api.Bid{}, err
+	}
+	if newStatus != "" {
+		err = db.insertEvent(ctx, tx, events.BidStatusChanged, events.BidStatusChange{Bid: bid, PreviousStatus: bidStatus, Username: username})
+		if err != nil {
+			return api.Bid{}, err
+		}
+	}
+	if newStatus == BidStatusApproved {
+		tender, err := db.selectTender(ctx, tx, tenderId.String())
+		if err != nil {
+			return api.Bid{}, err
+		}
+		err = db.insertEvent(ctx, tx, events.TenderStatusChanged, events.TenderStatusChange{Tender: tender, PreviousStatus: tenderStatus, Username: username})
+		if err != nil {
+			return api.Bid{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	db.Log.InfoContext(ctx, "Decision on bid recorded", slog.String("decision", string(updatedDecision)), slog.String("username", username), slog.String("bid_id", bidId))
+	return bid, nil
+}
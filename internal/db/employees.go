@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v4"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Сотрудник (таблица employee)
+type Employee struct {
+	Id        string `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+// Чтение сотрудника из строки с колонками id, username, first_name, last_name
+func scanEmployee(row pgx.Row) (Employee, error) {
+	var e Employee
+	var firstName, lastName *string
+	if err := row.Scan(&e.Id, &e.Username, &firstName, &lastName); err != nil {
+		return Employee{}, err
+	}
+	if firstName != nil {
+		e.FirstName = *firstName
+	}
+	if lastName != nil {
+		e.LastName = *lastName
+	}
+	return e, nil
+}
+
+func (db *DB) selectEmployee(ctx context.Context, q querier, username string) (Employee, error) {
+	query := `
+        SELECT id, username, first_name, last_name
+        FROM employee
+        WHERE username = $1
+    `
+	e, err := scanEmployee(q.QueryRow(ctx, query, username))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return Employee{}, ErrUserNotFound
+		}
+		db.Log.ErrorContext(ctx, "Error retrieving employee", slog.String("username", username), sl.Err(err))
+		return Employee{}, err
+	}
+	return e, nil
+}
+
+// Получение сотрудника по username
+func (db *DB) GetEmployeeByUsername(ctx context.Context, username string) (Employee, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.selectEmployee(ctx, db.Pool, username)
+}
+
+// Регистрация сотрудника
+func (db *DB) CreateEmployee(ctx context.Context, employee Employee, username string) (Employee, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if err := db.authorizeOrganization(ctx, db.Pool, "", username, authz.EmployeeCreate); err != nil {
+		return Employee{}, err
+	}
+
+	query := `
+        INSERT INTO employee (username, first_name, last_name)
+        VALUES ($1, NULLIF($2, ''), NULLIF($3, ''))
+        RETURNING id, username, first_name, last_name
+    `
+	created, err := scanEmployee(db.Pool.QueryRow(ctx, query, employee.Username, employee.FirstName, employee.LastName))
+	if err != nil {
+		if isUniqueViolation(err, "") {
+			return Employee{}, ErrUsernameTaken
+		}
+		db.Log.ErrorContext(ctx, "Error creating employee", slog.String("employee_username", employee.Username), sl.Err(err))
+		return Employee{}, err
+	}
+
+	db.Log.InfoContext(ctx, "Employee registered", slog.String("employee_username", created.Username), slog.String("username", username))
+	return created, nil
+}
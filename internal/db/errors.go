@@ -0,0 +1,46 @@
+package db
+
+import "errors"
+
+// Категория ошибки хранилища. По ней обработчики выбирают код ответа,
+// не перечисляя конкретные ошибки.
+type ErrorKind int
+
+const (
+	// Непредвиденная ошибка: сбой базы данных, таймаут и т.п.
+	KindInternal ErrorKind = iota
+	// Некорректные данные запроса
+	KindValidation
+	// Пользователь, выполняющий запрос, не существует
+	KindUnauthorized
+	// Недостаточно прав
+	KindForbidden
+	// Объект не найден
+	KindNotFound
+	// Операция противоречит текущему состоянию объекта
+	KindConflict
+)
+
+// Доменная ошибка хранилища. Текст ошибки можно показывать пользователю.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(kind ErrorKind, message string) error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Категория err или ее причины. Для ошибок, не являющихся доменными,
+// возвращается KindInternal.
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
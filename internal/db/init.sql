@@ -1,77 +0,0 @@
-CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
-
-CREATE TABLE employee (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    username VARCHAR(50) UNIQUE NOT NULL,
-    first_name VARCHAR(50),
-    last_name VARCHAR(50),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE TYPE organization_type AS ENUM (
-    'IE',
-    'LLC',
-    'JSC'
-);
-
-CREATE TABLE organization (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    name VARCHAR(100) NOT NULL,
-    description TEXT,
-    type organization_type,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE TABLE organization_responsible (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    organization_id UUID REFERENCES organization(id) ON DELETE CASCADE,
-    user_id UUID REFERENCES employee(id) ON DELETE CASCADE
-);
-
---Хранение и параметры тендеров
-CREATE TYPE tender_status AS ENUM (
-    'CREATED',
-    'PUBLISHED',
-    'CLOSED'
-);
-
-CREATE TABLE tenders (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    name VARCHAR(100) NOT NULL,
-    description TEXT,
-    organization_id UUID NOT NULL REFERENCES organization(id) ON DELETE CASCADE,
-    service_type VARCHAR(50),
-    status tender_status DEFAULT 'CREATED',
-    version INT DEFAULT 1,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    creator_username VARCHAR(50) NOT NULL
-);
-
---Хранение и параметры ставок
-CREATE TYPE bid_status AS ENUM (
-    'CREATED',
-    'PUBLISHED',
-    'CANCELED'
-);
-
-CREATE TYPE bid_author_type AS ENUM (
-    'USER',
-    'ORGANIZATION'
-);
-
-CREATE TABLE bids (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    name VARCHAR(100) NOT NULL,
-    description TEXT,
-    tender_id UUID NOT NULL REFERENCES tenders(id) ON DELETE CASCADE,
-    author_id UUID NOT NULL,
-    author_type bid_author_type NOT NULL,
-    status bid_status DEFAULT 'CREATED',
-    version INT DEFAULT 1,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
@@ -0,0 +1,1094 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/netguard"
+)
+
+// Хранилище в памяти процесса с той же семантикой, что и DB: права по
+// политике доступа, машины состояний, история версий и кворум решений.
+// Каждая операция выполняется атомарно под общей блокировкой; при ошибке
+// состояние не меняется, как при откате транзакции в PostgreSQL.
+type Memory struct {
+	TenderStates *TenderStateMachine
+	Policy       *authz.Policy
+	// Непубличные адреса, на которые разрешено регистрировать вебхуки
+	WebhookHosts *netguard.Allowlist
+
+	mu             sync.Mutex
+	employees      []Employee
+	organizations  []*Organization
+	responsibles   map[string]string // сотрудник -> организация
+	tenders        []*memTender
+	tenderVersions map[string][]TenderVersion
+	bids           []*memBid
+	bidVersions    map[string][]memBidVersion
+	decisions      map[string]map[string]string // предложение -> сотрудник -> решение
+	reviews        []memReview
+	outbox         []*memEvent
+	lastEventId    int64
+	webhooks       []*memWebhook
+	deliveries     []*memWebhookDelivery
+
+	// Обработка outbox выполняется по одному вызову ProcessOutbox
+	outboxMu sync.Mutex
+}
+
+type memTender struct {
+	api.Tender
+	creatorUsername string
+	updatedAt       time.Time
+}
+
+func (t *memTender) sortKey() memSortKey {
+	return memSortKey{id: t.Id, name: t.Name, createdAt: parseCreatedAt(t.CreatedAt), updatedAt: t.updatedAt}
+}
+
+type memBid struct {
+	api.Bid
+	updatedAt time.Time
+}
+
+func (b *memBid) sortKey() memSortKey {
+	return memSortKey{id: b.Id, name: b.Name, createdAt: parseCreatedAt(b.CreatedAt), updatedAt: b.updatedAt}
+}
+
+type memBidVersion struct {
+	version     int32
+	name        string
+	description string
+	changedBy   string
+	changeType  string
+}
+
+type memReview struct {
+	api.BidReview
+	bidId      string
+	reviewerId string
+}
+
+// Создает пустое хранилище в памяти с политикой доступа и машиной
+// состояний тендера по умолчанию
+func NewMemory() *Memory {
+	tenderStates, err := NewTenderStateMachine()
+	if err != nil {
+		panic(err)
+	}
+	return &Memory{
+		TenderStates:   tenderStates,
+		Policy:         authz.Default(),
+		responsibles:   map[string]string{},
+		tenderVersions: map[string][]TenderVersion{},
+		bidVersions:    map[string][]memBidVersion{},
+		decisions:      map[string]map[string]string{},
+	}
+}
+
+func (m *Memory) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (m *Memory) Close() {}
+
+// Захватывает блокировку хранилища, если запрос еще не отменен
+func (m *Memory) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	return nil
+}
+
+func memNow() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// Применяет limit и offset так же, как LIMIT/OFFSET в запросах DB
+func memPage[T any](items []T, limit int32, offset int32) []T {
+	items = items[min(int(max(offset, 0)), len(items)):]
+	return items[:min(int(max(limit, 0)), len(items))]
+}
+
+// Добавляет сотрудника без проверки прав. Используется для наполнения
+// хранилища в тестах и демонстрациях.
+func (m *Memory) SeedEmployee(username string, firstName string, lastName string) Employee {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := Employee{Id: uuid.NewString(), Username: username, FirstName: firstName, LastName: lastName}
+	m.employees = append(m.employees, e)
+	return e
+}
+
+// Добавляет организацию без проверки прав
+func (m *Memory) SeedOrganization(name string, orgType OrganizationType) Organization {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o := &Organization{Id: uuid.NewString(), Name: name, Type: orgType, CreatedAt: memNow()}
+	m.organizations = append(m.organizations, o)
+	return *o
+}
+
+// Назначает сотрудника ответственным за организацию без проверки прав
+func (m *Memory) SeedResponsible(organizationId string, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, err := m.employee(username)
+	if err != nil {
+		return err
+	}
+	if m.organization(organizationId) == nil {
+		return ErrOrganizationNotFound
+	}
+	if current, ok := m.responsibles[e.Id]; ok && current != organizationId {
+		return ErrAlreadyResponsible
+	}
+	m.responsibles[e.Id] = organizationId
+	return nil
+}
+
+func (m *Memory) employee(username string) (Employee, error) {
+	for _, e := range m.employees {
+		if e.Username == username {
+			return e, nil
+		}
+	}
+	return Employee{}, ErrUserNotFound
+}
+
+func (m *Memory) employeeById(id string) (Employee, bool) {
+	for _, e := range m.employees {
+		if e.Id == id {
+			return e, true
+		}
+	}
+	return Employee{}, false
+}
+
+func (m *Memory) organization(id string) *Organization {
+	for _, o := range m.organizations {
+		if o.Id == id {
+			return o
+		}
+	}
+	return nil
+}
+
+func (m *Memory) tender(id string) *memTender {
+	for _, t := range m.tenders {
+		if t.Id == id {
+			return t
+		}
+	}
+	return nil
+}
+
+func (m *Memory) bid(id string) *memBid {
+	for _, b := range m.bids {
+		if b.Id == id {
+			return b
+		}
+	}
+	return nil
+}
+
+// Является ли сотрудник ответственным за организацию
+func (m *Memory) isResponsible(userId string, organizationId string) bool {
+	org, ok := m.responsibles[userId]
+	return ok && org == organizationId
+}
+
+// Является ли сотрудник автором предложения: сам автор, ответственный за
+// организацию-автора или ответственный за ту же организацию, что и автор
+func (m *Memory) isBidAuthor(userId string, b *memBid) bool {
+	if b.AuthorId == userId {
+		return true
+	}
+	org, ok := m.responsibles[userId]
+	if !ok {
+		return false
+	}
+	if org == b.AuthorId {
+		return true
+	}
+	authorOrg, ok := m.responsibles[b.AuthorId]
+	return ok && authorOrg == org
+}
+
+func (m *Memory) tenderAccess(tenderId string, username string) (TenderAccess, *memTender, error) {
+	e, err := m.employee(username)
+	if err != nil {
+		return TenderAccess{}, nil, err
+	}
+	t := m.tender(tenderId)
+	if t == nil {
+		return TenderAccess{}, nil, ErrTenderNotFound
+	}
+	return TenderAccess{
+		UserId:  uuid.MustParse(e.Id),
+		Status:  TenderStatus(t.Status),
+		Subject: newSubject(username, map[authz.Role]bool{authz.RoleOrgResponsible: m.isResponsible(e.Id, t.OrganizationId)}),
+	}, t, nil
+}
+
+func (m *Memory) authorizeTender(tenderId string, username string, action authz.Action) (TenderAccess, *memTender, error) {
+	access, t, err := m.tenderAccess(tenderId, username)
+	if err != nil {
+		return TenderAccess{}, nil, err
+	}
+	if !m.Policy.Allowed(action, access.Subject, string(access.Status)) {
+		return TenderAccess{}, nil, ErrForbidden
+	}
+	return access, t, nil
+}
+
+func (m *Memory) authorizeBid(bidId string, username string, action authz.Action) (BidAccess, *memBid, error) {
+	e, err := m.employee(username)
+	if err != nil {
+		return BidAccess{}, nil, err
+	}
+	b := m.bid(bidId)
+	if b == nil {
+		return BidAccess{}, nil, ErrBidNotFound
+	}
+	t := m.tender(b.TenderId)
+	access := BidAccess{
+		UserId:       uuid.MustParse(e.Id),
+		Status:       BidStatus(b.Status),
+		TenderStatus: TenderStatus(t.Status),
+		Subject: newSubject(username, map[authz.Role]bool{
+			authz.RoleOrgResponsible: m.isResponsible(e.Id, t.OrganizationId),
+			authz.RoleBidAuthor:      m.isBidAuthor(e.Id, b),
+		}),
+	}
+	if !m.Policy.Allowed(action, access.Subject, string(access.Status)) {
+		return BidAccess{}, nil, ErrForbidden
+	}
+	return access, b, nil
+}
+
+func (m *Memory) authorizeOrganization(organizationId string, username string, action authz.Action) error {
+	e, err := m.employee(username)
+	if err != nil {
+		return err
+	}
+	if organizationId != "" && m.organization(organizationId) == nil {
+		return ErrOrganizationNotFound
+	}
+
+	subject := newSubject(username, map[authz.Role]bool{authz.RoleOrgResponsible: m.isResponsible(e.Id, organizationId)})
+	if !m.Policy.Allowed(action, subject, "") {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (m *Memory) addTenderVersion(t *memTender, changedBy string, changeType string) {
+	t.updatedAt = time.Now()
+	m.tenderVersions[t.Id] = append(m.tenderVersions[t.Id], TenderVersion{
+		TenderId:    t.Id,
+		Version:     t.Version,
+		Name:        t.Name,
+		Description: t.Description,
+		ServiceType: string(t.ServiceType),
+		Status:      string(t.Status),
+		ChangedBy:   changedBy,
+		ChangeType:  changeType,
+		CreatedAt:   memNow(),
+	})
+}
+
+func (m *Memory) addBidVersion(b *memBid, changedBy string, changeType string) {
+	b.updatedAt = time.Now()
+	m.bidVersions[b.Id] = append(m.bidVersions[b.Id], memBidVersion{
+		version:     b.Version,
+		name:        b.Name,
+		description: b.Description,
+		changedBy:   changedBy,
+		changeType:  changeType,
+	})
+}
+
+func (m *Memory) GetTenders(ctx context.Context, serviceTypes []api.TenderServiceType, page Page) ([]api.Tender, PageInfo, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, PageInfo{}, err
+	}
+	defer m.mu.Unlock()
+
+	var tenders []*memTender
+	for _, t := range m.tenders {
+		if len(serviceTypes) == 0 || slices.Contains(serviceTypes, t.ServiceType) {
+			tenders = append(tenders, t)
+		}
+	}
+	return memTenderPage(tenders, page)
+}
+
+// Страница тендеров в порядке page.Sort
+func memTenderPage(tenders []*memTender, page Page) ([]api.Tender, PageInfo, error) {
+	tenders, info, err := memSortedPage(tenders, page, (*memTender).sortKey)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	result := []api.Tender{}
+	for _, t := range tenders {
+		result = append(result, t.Tender)
+	}
+	return result, info, nil
+}
+
+// Страница предложений в порядке page.Sort
+func memBidPage(bids []*memBid, page Page) ([]api.Bid, PageInfo, error) {
+	bids, info, err := memSortedPage(bids, page, (*memBid).sortKey)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	result := []api.Bid{}
+	for _, b := range bids {
+		result = append(result, b.Bid)
+	}
+	return result, info, nil
+}
+
+// Поиск выполняется по словам названия и описания с приближенным
+// сравнением словоформ (см. textSearch), а не по словарям PostgreSQL
+func (m *Memory) SearchTenders(ctx context.Context, query string, serviceTypes []api.TenderServiceType, limit int32, offset int32) ([]TenderSearchResult, error) {
+	search, err := newTextSearch(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	results := []TenderSearchResult{}
+	for i := len(m.tenders) - 1; i >= 0; i-- {
+		t := m.tenders[i]
+		if len(serviceTypes) > 0 && !slices.Contains(serviceTypes, t.ServiceType) {
+			continue
+		}
+		rank, snippet, ok := search.match(t.Name, t.Description)
+		if ok {
+			results = append(results, TenderSearchResult{Tender: t.Tender, Rank: rank, Snippet: snippet})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+	return memPage(results, limit, offset), nil
+}
+
+func (m *Memory) GetUserTenders(ctx context.Context, username string, page Page) ([]api.Tender, PageInfo, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, PageInfo{}, err
+	}
+	defer m.mu.Unlock()
+
+	var tenders []*memTender
+	if e, err := m.employee(username); err == nil {
+		for _, t := range m.tenders {
+			if m.isResponsible(e.Id, t.OrganizationId) {
+				tenders = append(tenders, t)
+			}
+		}
+	}
+	return memTenderPage(tenders, page)
+}
+
+func (m *Memory) CreateTender(ctx context.Context, tender api.Tender, creatorUsername string) (api.Tender, error) {
+	if err := m.lock(ctx); err != nil {
+		return api.Tender{}, err
+	}
+	defer m.mu.Unlock()
+
+	e, err := m.employee(creatorUsername)
+	if err != nil {
+		return api.Tender{}, err
+	}
+	if m.organization(tender.OrganizationId) == nil {
+		return api.Tender{}, ErrOrganizationNotFound
+	}
+	subject := newSubject(creatorUsername, map[authz.Role]bool{authz.RoleOrgResponsible: m.isResponsible(e.Id, tender.OrganizationId)})
+	if !m.Policy.Allowed(authz.TenderCreate, subject, "") {
+		return api.Tender{}, ErrForbidden
+	}
+
+	t := &memTender{Tender: tender, creatorUsername: creatorUsername}
+	t.Id = uuid.NewString()
+	t.Status = api.TenderStatus(strings.ToUpper(string(t.Status)))
+	t.CreatedAt = memNow()
+	m.tenders = append(m.tenders, t)
+	m.addTenderVersion(t, creatorUsername, TenderChangeCreated)
+	m.addEvent(events.TenderCreated, events.TenderChange{Tender: t.Tender, Username: creatorUsername})
+	return t.Tender, nil
+}
+
+func (m *Memory) EditTender(ctx context.Context, tenderId string, name string, description string, serviceType string, username string) (api.Tender, error) {
+	if err := m.lock(ctx); err != nil {
+		return api.Tender{}, err
+	}
+	defer m.mu.Unlock()
+
+	_, t, err := m.authorizeTender(tenderId, username, authz.TenderEdit)
+	if err != nil {
+		return api.Tender{}, err
+	}
+
+	if name != "" {
+		t.Name = name
+	}
+	if description != "" {
+		t.Description = description
+	}
+	if serviceType != "" {
+		t.ServiceType = api.TenderServiceType(serviceType)
+	}
+	t.Version++
+	m.addTenderVersion(t, username, TenderChangeEdited)
+	m.addEvent(events.TenderEdited, events.TenderChange{Tender: t.Tender, Username: username})
+	return t.Tender, nil
+}
+
+func (m *Memory) RollbackTender(ctx context.Context, tenderId string, version int, username string) (api.Tender, error) {
+	if err := m.lock(ctx); err != nil {
+		return api.Tender{}, err
+	}
+	defer m.mu.Unlock()
+
+	_, t, err := m.authorizeTender(tenderId, username, authz.TenderRollback)
+	if err != nil {
+		return api.Tender{}, err
+	}
+
+	for _, v := range m.tenderVersions[tenderId] {
+		if int(v.Version) == version {
+			t.Name = v.Name
+			t.Description = v.Description
+			t.ServiceType = api.TenderServiceType(v.ServiceType)
+			t.Version++
+			m.addTenderVersion(t, username, TenderChangeRolledBack)
+			m.addEvent(events.TenderRolledBack, events.TenderChange{Tender: t.Tender, Username: username})
+			return t.Tender, nil
+		}
+	}
+	return api.Tender{}, ErrVersionNotFound
+}
+
+func (m *Memory) GetTenderStatus(ctx context.Context, tenderId string, username string) (string, error) {
+	if err := m.lock(ctx); err != nil {
+		return "", err
+	}
+	defer m.mu.Unlock()
+
+	access, _, err := m.authorizeTender(tenderId, username, authz.TenderView)
+	if err != nil {
+		return "", err
+	}
+	return string(access.Status), nil
+}
+
+func (m *Memory) UpdateTenderStatus(ctx context.Context, tenderId string, status api.TenderStatus, username string) (api.Tender, error) {
+	newStatus, err := ParseTenderStatus(status)
+	if err != nil {
+		return api.Tender{}, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return api.Tender{}, err
+	}
+	defer m.mu.Unlock()
+
+	access, t, err := m.tenderAccess(tenderId, username)
+	if err != nil {
+		return api.Tender{}, err
+	}
+
+	if access.Status == newStatus {
+		if !m.Policy.Allowed(authz.TenderView, access.Subject, string(access.Status)) {
+			return api.Tender{}, ErrForbidden
+		}
+		return t.Tender, nil
+	}
+
+	action, err := m.TenderStates.Transition(access.Status, newStatus)
+	if err != nil {
+		if !m.Policy.Allowed(authz.TenderHistory, access.Subject, string(access.Status)) {
+			return api.Tender{}, ErrForbidden
+		}
+		return api.Tender{}, err
+	}
+	if !m.Policy.Allowed(action, access.Subject, string(access.Status)) {
+		return api.Tender{}, ErrForbidden
+	}
+
+	t.Status = api.TenderStatus(newStatus)
+	t.Version++
+	m.addTenderVersion(t, username, TenderChangeStatus)
+	m.addEvent(events.TenderStatusChanged, events.TenderStatusChange{
+		Tender:         t.Tender,
+		PreviousStatus: string(access.Status),
+		Username:       username,
+	})
+	return t.Tender, nil
+}
+
+func (m *Memory) GetTenderVersions(ctx context.Context, tenderId string, username string) ([]TenderVersion, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	if _, _, err := m.authorizeTender(tenderId, username, authz.TenderHistory); err != nil {
+		return nil, err
+	}
+	return append([]TenderVersion{}, m.tenderVersions[tenderId]...), nil
+}
+
+func (m *Memory) GetUserBids(ctx context.Context, username string, page Page) ([]api.Bid, PageInfo, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, PageInfo{}, err
+	}
+	defer m.mu.Unlock()
+
+	var bids []*memBid
+	// Как и в DB, у неизвестного пользователя просто нет предложений
+	if e, err := m.employee(username); err == nil {
+		for _, b := range m.bids {
+			if b.AuthorId == e.Id {
+				bids = append(bids, b)
+			}
+		}
+	}
+	return memBidPage(bids, page)
+}
+
+func (m *Memory) CreateBid(ctx context.Context, bid api.Bid, username string) (api.Bid, error) {
+	if err := m.lock(ctx); err != nil {
+		return api.Bid{}, err
+	}
+	defer m.mu.Unlock()
+
+	if m.tender(bid.TenderId) == nil {
+		return api.Bid{}, ErrTenderNotFound
+	}
+	authorType := api.BidAuthorType(strings.ToUpper(string(bid.AuthorType)))
+	authorExists := false
+	var author Employee
+	switch authorType {
+	case "USER":
+		author, authorExists = m.employeeById(bid.AuthorId)
+	case "ORGANIZATION":
+		authorExists = m.organization(bid.AuthorId) != nil
+	}
+	if !authorExists && authorType == "USER" {
+		return api.Bid{}, ErrUserNotFound
+	}
+	if !authorExists {
+		return api.Bid{}, ErrOrganizationNotFound
+	}
+
+	if username == "" {
+		username = author.Username
+	}
+	if username == "" {
+		return api.Bid{}, ErrUserNotFound
+	}
+	e, err := m.employee(username)
+	if err != nil {
+		return api.Bid{}, err
+	}
+	b := &memBid{Bid: bid}
+	b.AuthorType = authorType
+	subject := newSubject(username, map[authz.Role]bool{authz.RoleBidAuthor: m.isBidAuthor(e.Id, b)})
+	if !m.Policy.Allowed(authz.BidCreate, subject, "") {
+		return api.Bid{}, ErrForbidden
+	}
+
+	b.Id = uuid.NewString()
+	b.CreatedAt = memNow()
+	m.bids = append(m.bids, b)
+	m.addBidVersion(b, username, BidChangeCreated)
+	m.addEvent(events.BidCreated, events.BidChange{Bid: b.Bid, Username: username})
+	return b.Bid, nil
+}
+
+func (m *Memory) EditBid(ctx context.Context, bidId string, name *string, description *string, username string) (api.Bid, error) {
+	if err := m.lock(ctx); err != nil {
+		return api.Bid{}, err
+	}
+	defer m.mu.Unlock()
+
+	_, b, err := m.authorizeBid(bidId, username, authz.BidEdit)
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	if name != nil {
+		b.Name = *name
+	}
+	if description != nil {
+		b.Description = *description
+	}
+	b.Version++
+	m.addBidVersion(b, username, BidChangeEdited)
+	m.addEvent(events.BidEdited, events.BidChange{Bid: b.Bid, Username: username})
+	return b.Bid, nil
+}
+
+func (m *Memory) RollbackBid(ctx context.Context, bidId string, version int, username string) (api.Bid, error) {
+	if err := m.lock(ctx); err != nil {
+		return api.Bid{}, err
+	}
+	defer m.mu.Unlock()
+
+	_, b, err := m.authorizeBid(bidId, username, authz.BidRollback)
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	for _, v := range m.bidVersions[bidId] {
+		if int(v.version) == version {
+			b.Name = v.name
+			b.Description = v.description
+			b.Version++
+			m.addBidVersion(b, username, BidChangeRolledBack)
+			m.addEvent(events.BidRolledBack, events.BidChange{Bid: b.Bid, Username: username})
+			return b.Bid, nil
+		}
+	}
+	return api.Bid{}, ErrVersionNotFound
+}
+
+func (m *Memory) GetBidsForTender(ctx context.Context, tenderId string, username string, page Page) ([]api.Bid, PageInfo, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, PageInfo{}, err
+	}
+	defer m.mu.Unlock()
+
+	access, _, err := m.tenderAccess(tenderId, username)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	userId := access.UserId.String()
+	seeAll := m.Policy.Allowed(authz.TenderBids, access.Subject, string(access.Status))
+
+	// Собственными считаются предложения пользователя и его организации
+	own := func(b *memBid) bool {
+		if b.AuthorId == userId {
+			return true
+		}
+		org, ok := m.responsibles[userId]
+		return ok && b.AuthorId == org
+	}
+
+	hasOwnBids := false
+	for _, b := range m.bids {
+		if b.TenderId == tenderId && own(b) {
+			hasOwnBids = true
+			break
+		}
+	}
+	if !seeAll && !hasOwnBids {
+		return nil, PageInfo{}, ErrForbidden
+	}
+
+	var bids []*memBid
+	for _, b := range m.bids {
+		if b.TenderId != tenderId {
+			continue
+		}
+		if (seeAll && BidStatus(b.Status).visibleToTender()) || own(b) {
+			bids = append(bids, b)
+		}
+	}
+	return memBidPage(bids, page)
+}
+
+func (m *Memory) SearchBids(ctx context.Context, query string, username string, limit int32, offset int32) ([]BidSearchResult, error) {
+	search, err := newTextSearch(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	e, err := m.employee(username)
+	if err != nil {
+		return nil, err
+	}
+	statuses := responsibleBidTenderStatuses(m.Policy, username)
+	org, isResponsible := m.responsibles[e.Id]
+
+	results := []BidSearchResult{}
+	for i := len(m.bids) - 1; i >= 0; i-- {
+		b := m.bids[i]
+		visible := b.AuthorId == e.Id || (isResponsible && b.AuthorId == org)
+		if t := m.tender(b.TenderId); !visible && t != nil && isResponsible && t.OrganizationId == org {
+			visible = BidStatus(b.Status).visibleToTender() && slices.Contains(statuses, string(t.Status))
+		}
+		if !visible {
+			continue
+		}
+		rank, snippet, ok := search.match(b.Name, b.Description)
+		if ok {
+			results = append(results, BidSearchResult{Bid: b.Bid, Rank: rank, Snippet: snippet})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+	return memPage(results, limit, offset), nil
+}
+
+func (m *Memory) GetBidStatus(ctx context.Context, bidId string, username string) (string, error) {
+	if err := m.lock(ctx); err != nil {
+		return "", err
+	}
+	defer m.mu.Unlock()
+
+	access, _, err := m.authorizeBid(bidId, username, authz.BidView)
+	if err != nil {
+		return "", err
+	}
+	return string(access.Status), nil
+}
+
+func (m *Memory) UpdateBidStatus(ctx context.Context, bidId string, status api.BidStatus, username string) (api.Bid, error) {
+	newStatus, err := ParseBidStatus(status)
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return api.Bid{}, err
+	}
+	defer m.mu.Unlock()
+
+	access, b, err := m.authorizeBid(bidId, username, authz.BidStatus)
+	if err != nil {
+		return api.Bid{}, err
+	}
+	if err := CheckManualBidTransition(access.Status, newStatus); err != nil {
+		return api.Bid{}, err
+	}
+
+	b.Status = api.BidStatus(newStatus)
+	b.Version++
+	m.addBidVersion(b, username, BidChangeStatus)
+	m.addEvent(events.BidStatusChanged, events.BidStatusChange{
+		Bid:            b.Bid,
+		PreviousStatus: string(access.Status),
+		Username:       username,
+	})
+	return b.Bid, nil
+}
+
+func (m *Memory) SubmitBidDecision(ctx context.Context, bidId string, decision api.BidDecision, username string) (api.Bid, error) {
+	if err := m.lock(ctx); err != nil {
+		return api.Bid{}, err
+	}
+	defer m.mu.Unlock()
+
+	e, err := m.employee(username)
+	if err != nil {
+		return api.Bid{}, err
+	}
+	b := m.bid(bidId)
+	if b == nil {
+		return api.Bid{}, ErrBidNotFound
+	}
+	t := m.tender(b.TenderId)
+
+	if _, _, err := m.authorizeBid(bidId, username, authz.BidDecide); err != nil {
+		return api.Bid{}, err
+	}
+	if TenderStatus(t.Status) != TenderStatusPublished {
+		return api.Bid{}, ErrDecisionNotAllowed
+	}
+
+	updatedDecision := strings.ToUpper(string(decision))
+	decisionStatus := BidStatusApproved
+	if updatedDecision == "REJECTED" {
+		decisionStatus = BidStatusRejected
+	}
+	if err := CheckBidTransition(BidStatus(b.Status), decisionStatus); err != nil {
+		return api.Bid{}, err
+	}
+	if _, ok := m.decisions[bidId][e.Id]; ok {
+		return api.Bid{}, ErrDecisionAlreadySubmitted
+	}
+
+	// Все проверки выполняются до изменений, чтобы ошибка не оставила
+	// частично записанное решение
+	var newStatus BidStatus
+	if decisionStatus == BidStatusRejected {
+		newStatus = BidStatusRejected
+	} else {
+		approvals := 1
+		for _, d := range m.decisions[bidId] {
+			if d == "APPROVED" {
+				approvals++
+			}
+		}
+		quorum := 0
+		for _, org := range m.responsibles {
+			if org == t.OrganizationId {
+				quorum++
+			}
+		}
+		if quorum > maxDecisionQuorum {
+			quorum = maxDecisionQuorum
+		}
+		if approvals >= quorum {
+			newStatus = BidStatusApproved
+		}
+	}
+	if newStatus == BidStatusApproved {
+		if _, err := m.TenderStates.Transition(TenderStatus(t.Status), TenderStatusClosed); err != nil {
+			return api.Bid{}, err
+		}
+	}
+
+	if m.decisions[bidId] == nil {
+		m.decisions[bidId] = map[string]string{}
+	}
+	m.decisions[bidId][e.Id] = updatedDecision
+	previousBidStatus, previousTenderStatus := string(b.Status), string(t.Status)
+	if newStatus != "" {
+		b.Status = api.BidStatus(newStatus)
+		b.Version++
+		m.addBidVersion(b, username, BidChangeStatus)
+	}
+	if newStatus == BidStatusApproved {
+		t.Status = api.TenderStatus(TenderStatusClosed)
+		t.Version++
+		m.addTenderVersion(t, username, TenderChangeStatus)
+	}
+
+	m.addEvent(events.BidDecisionSubmitted, events.BidDecision{Bid: b.Bid, Decision: updatedDecision, Username: username})
+	if newStatus != "" {
+		m.addEvent(events.BidStatusChanged, events.BidStatusChange{Bid: b.Bid, PreviousStatus: previousBidStatus, Username: username})
+	}
+	if newStatus == BidStatusApproved {
+		m.addEvent(events.TenderStatusChanged, events.TenderStatusChange{Tender: t.Tender, PreviousStatus: previousTenderStatus, Username: username})
+	}
+	return b.Bid, nil
+}
+
+func (m *Memory) SubmitBidFeedback(ctx context.Context, bidId string, feedback string, username string) (api.Bid, error) {
+	if err := m.lock(ctx); err != nil {
+		return api.Bid{}, err
+	}
+	defer m.mu.Unlock()
+
+	access, b, err := m.authorizeBid(bidId, username, authz.BidFeedback)
+	if err != nil {
+		return api.Bid{}, err
+	}
+	if !BidStatus(b.Status).visibleToTender() {
+		return api.Bid{}, ErrFeedbackNotAllowed
+	}
+
+	m.reviews = append(m.reviews, memReview{
+		BidReview:  api.BidReview{Id: uuid.NewString(), Description: feedback, CreatedAt: memNow()},
+		bidId:      bidId,
+		reviewerId: access.UserId.String(),
+	})
+	m.addEvent(events.BidFeedbackSubmitted, events.BidFeedback{Bid: b.Bid, Feedback: feedback, Username: username})
+	return b.Bid, nil
+}
+
+func (m *Memory) GetBidReviews(ctx context.Context, tenderId string, authorUsername string, requesterUsername string, page Page) ([]api.BidReview, PageInfo, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, PageInfo{}, err
+	}
+	defer m.mu.Unlock()
+
+	if _, _, err := m.authorizeTender(tenderId, requesterUsername, authz.TenderReviews); err != nil {
+		return nil, PageInfo{}, err
+	}
+	author, err := m.employee(authorUsername)
+	if err != nil {
+		return nil, PageInfo{}, ErrAuthorNotFound
+	}
+
+	reviews := []api.BidReview{}
+	for _, r := range m.reviews {
+		b := m.bid(r.bidId)
+		if b != nil && b.AuthorType == "USER" && b.AuthorId == author.Id {
+			reviews = append(reviews, r.BidReview)
+		}
+	}
+	return memSortedPage(reviews, page, func(r api.BidReview) memSortKey {
+		return memSortKey{id: r.Id, createdAt: parseCreatedAt(r.CreatedAt)}
+	})
+}
+
+func (m *Memory) GetEmployeeByUsername(ctx context.Context, username string) (Employee, error) {
+	if err := m.lock(ctx); err != nil {
+		return Employee{}, err
+	}
+	defer m.mu.Unlock()
+
+	return m.employee(username)
+}
+
+func (m *Memory) CreateEmployee(ctx context.Context, employee Employee, username string) (Employee, error) {
+	if err := m.lock(ctx); err != nil {
+		return Employee{}, err
+	}
+	defer m.mu.Unlock()
+
+	if err := m.authorizeOrganization("", username, authz.EmployeeCreate); err != nil {
+		return Employee{}, err
+	}
+	if _, err := m.employee(employee.Username); err == nil {
+		return Employee{}, ErrUsernameTaken
+	}
+
+	employee.Id = uuid.NewString()
+	m.employees = append(m.employees, employee)
+	return employee, nil
+}
+
+func (m *Memory) CreateOrganization(ctx context.Context, org Organization, username string) (Organization, error) {
+	if err := m.lock(ctx); err != nil {
+		return Organization{}, err
+	}
+	defer m.mu.Unlock()
+
+	if err := m.authorizeOrganization("", username, authz.OrganizationCreate); err != nil {
+		return Organization{}, err
+	}
+
+	o := org
+	o.Id = uuid.NewString()
+	o.CreatedAt = memNow()
+	m.organizations = append(m.organizations, &o)
+	return o, nil
+}
+
+func (m *Memory) GetOrganizations(ctx context.Context, page Page) ([]Organization, PageInfo, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, PageInfo{}, err
+	}
+	defer m.mu.Unlock()
+
+	organizations := []Organization{}
+	for _, o := range m.organizations {
+		organizations = append(organizations, *o)
+	}
+	return memSortedPage(organizations, page, func(o Organization) memSortKey {
+		return memSortKey{id: o.Id, name: o.Name, createdAt: parseCreatedAt(o.CreatedAt)}
+	})
+}
+
+func (m *Memory) GetOrganization(ctx context.Context, organizationId string) (Organization, error) {
+	if err := m.lock(ctx); err != nil {
+		return Organization{}, err
+	}
+	defer m.mu.Unlock()
+
+	o := m.organization(organizationId)
+	if o == nil {
+		return Organization{}, ErrOrganizationNotFound
+	}
+	return *o, nil
+}
+
+func (m *Memory) UpdateOrganization(ctx context.Context, organizationId string, name *string, description *string, orgType *OrganizationType, username string) (Organization, error) {
+	if err := m.lock(ctx); err != nil {
+		return Organization{}, err
+	}
+	defer m.mu.Unlock()
+
+	if err := m.authorizeOrganization(organizationId, username, authz.OrganizationUpdate); err != nil {
+		return Organization{}, err
+	}
+
+	o := m.organization(organizationId)
+	if name != nil {
+		o.Name = *name
+	}
+	if description != nil {
+		o.Description = *description
+	}
+	if orgType != nil {
+		o.Type = *orgType
+	}
+	return *o, nil
+}
+
+func (m *Memory) GetOrganizationResponsibles(ctx context.Context, organizationId string) ([]Employee, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	if m.organization(organizationId) == nil {
+		return nil, ErrOrganizationNotFound
+	}
+
+	employees := []Employee{}
+	for _, e := range m.employees {
+		if m.isResponsible(e.Id, organizationId) {
+			employees = append(employees, e)
+		}
+	}
+	sort.SliceStable(employees, func(i, j int) bool { return employees[i].Username < employees[j].Username })
+	return employees, nil
+}
+
+func (m *Memory) AddOrganizationResponsible(ctx context.Context, organizationId string, employeeUsername string, username string) (Employee, error) {
+	if err := m.lock(ctx); err != nil {
+		return Employee{}, err
+	}
+	defer m.mu.Unlock()
+
+	if err := m.authorizeOrganization(organizationId, username, authz.OrganizationResponsibles); err != nil {
+		return Employee{}, err
+	}
+	e, err := m.employee(employeeUsername)
+	if err != nil {
+		return Employee{}, ErrEmployeeNotFound
+	}
+	if current, ok := m.responsibles[e.Id]; ok {
+		if current == organizationId {
+			return e, nil
+		}
+		return Employee{}, ErrAlreadyResponsible
+	}
+
+	m.responsibles[e.Id] = organizationId
+	return e, nil
+}
+
+func (m *Memory) RemoveOrganizationResponsible(ctx context.Context, organizationId string, employeeUsername string, username string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if err := m.authorizeOrganization(organizationId, username, authz.OrganizationResponsibles); err != nil {
+		return err
+	}
+	e, err := m.employee(employeeUsername)
+	if err != nil || !m.isResponsible(e.Id, organizationId) {
+		return ErrResponsibleNotFound
+	}
+
+	delete(m.responsibles, e.Id)
+	return nil
+}
@@ -0,0 +1,327 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+)
+
+// Организация с двумя ответственными, опубликованный тендер и
+// опубликованное предложение стороннего сотрудника
+func seedMemoryTender(t *testing.T) (*Memory, api.Tender, api.Bid) {
+	t.Helper()
+
+	m := NewMemory()
+	org := m.SeedOrganization("Avito", OrganizationTypeLLC)
+	m.SeedEmployee("alice", "Alice", "A")
+	m.SeedEmployee("bob", "Bob", "B")
+	author := m.SeedEmployee("carol", "Carol", "C")
+	for _, username := range []string{"alice", "bob"} {
+		if err := m.SeedResponsible(org.Id, username); err != nil {
+			t.Fatalf("SeedResponsible(%s): %v", username, err)
+		}
+	}
+
+	tender, err := m.CreateTender(context.Background(), api.Tender{
+		Name:           "Delivery",
+		Description:    "Delivery of goods",
+		OrganizationId: org.Id,
+		ServiceType:    api.Delivery,
+		Status:         api.TenderStatus(TenderStatusCreated),
+		Version:        1,
+	}, "alice")
+	if err != nil {
+		t.Fatalf("CreateTender: %v", err)
+	}
+	if tender, err = m.UpdateTenderStatus(context.Background(), tender.Id, api.TenderStatus(TenderStatusPublished), "alice"); err != nil {
+		t.Fatalf("UpdateTenderStatus: %v", err)
+	}
+
+	bid, err := m.CreateBid(context.Background(), api.Bid{
+		Name:       "Offer",
+		TenderId:   tender.Id,
+		AuthorId:   author.Id,
+		AuthorType: "User",
+		Status:     api.BidStatus(BidStatusCreated),
+		Version:    1,
+	}, "")
+	if err != nil {
+		t.Fatalf("CreateBid: %v", err)
+	}
+	if bid, err = m.UpdateBidStatus(context.Background(), bid.Id, api.BidStatus(BidStatusPublished), "carol"); err != nil {
+		t.Fatalf("UpdateBidStatus: %v", err)
+	}
+	return m, tender, bid
+}
+
+func TestMemoryTenderPermissions(t *testing.T) {
+	m, tender, _ := seedMemoryTender(t)
+
+	if _, err := m.EditTender(context.Background(), tender.Id, "Other", "", "", "carol"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("EditTender by non-responsible: error = %v, want ErrForbidden", err)
+	}
+	if _, err := m.EditTender(context.Background(), tender.Id, "Other", "", "", "nobody"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("EditTender by unknown user: error = %v, want ErrUserNotFound", err)
+	}
+	if _, err := m.GetTenderStatus(context.Background(), "00000000-0000-0000-0000-000000000000", "alice"); !errors.Is(err, ErrTenderNotFound) {
+		t.Errorf("GetTenderStatus of unknown tender: error = %v, want ErrTenderNotFound", err)
+	}
+	if status, err := m.GetTenderStatus(context.Background(), tender.Id, "carol"); err != nil || status != string(TenderStatusPublished) {
+		t.Errorf("GetTenderStatus by viewer = %q, %v; want PUBLISHED", status, err)
+	}
+}
+
+func TestMemoryTenderVersions(t *testing.T) {
+	m, tender, _ := seedMemoryTender(t)
+
+	edited, err := m.EditTender(context.Background(), tender.Id, "Express delivery", "", "", "bob")
+	if err != nil {
+		t.Fatalf("EditTender: %v", err)
+	}
+	if edited.Version != 3 || edited.Description != tender.Description {
+		t.Errorf("EditTender = version %d, description %q; want 3, %q", edited.Version, edited.Description, tender.Description)
+	}
+
+	rolledBack, err := m.RollbackTender(context.Background(), tender.Id, 1, "alice")
+	if err != nil {
+		t.Fatalf("RollbackTender: %v", err)
+	}
+	if rolledBack.Version != 4 || rolledBack.Name != "Delivery" || rolledBack.Status != tender.Status {
+		t.Errorf("RollbackTender = %+v, want version 4 with the original name and the current status", rolledBack)
+	}
+	if _, err := m.RollbackTender(context.Background(), tender.Id, 10, "alice"); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("RollbackTender to unknown version: error = %v, want ErrVersionNotFound", err)
+	}
+
+	versions, err := m.GetTenderVersions(context.Background(), tender.Id, "alice")
+	if err != nil {
+		t.Fatalf("GetTenderVersions: %v", err)
+	}
+	want := []string{TenderChangeCreated, TenderChangeStatus, TenderChangeEdited, TenderChangeRolledBack}
+	if len(versions) != len(want) {
+		t.Fatalf("GetTenderVersions returned %d versions, want %d", len(versions), len(want))
+	}
+	for i, v := range versions {
+		if v.Version != int32(i+1) || v.ChangeType != want[i] {
+			t.Errorf("version %d = %d %s, want %d %s", i, v.Version, v.ChangeType, i+1, want[i])
+		}
+	}
+}
+
+func TestMemoryBidVersions(t *testing.T) {
+	m, _, bid := seedMemoryTender(t)
+
+	if _, err := m.EditBid(context.Background(), bid.Id, nil, nil, "carol"); err != nil {
+		t.Fatalf("EditBid: %v", err)
+	}
+	// Первую версию предложения пользователя создает его автор
+	want := []memBidVersion{
+		{version: 1, changedBy: "carol", changeType: BidChangeCreated},
+		{version: 2, changedBy: "carol", changeType: BidChangeStatus},
+		{version: 3, changedBy: "carol", changeType: BidChangeEdited},
+	}
+	versions := m.bidVersions[bid.Id]
+	if len(versions) != len(want) {
+		t.Fatalf("bid has %d versions, want %d", len(versions), len(want))
+	}
+	for i, v := range versions {
+		if v.version != want[i].version || v.changedBy != want[i].changedBy || v.changeType != want[i].changeType {
+			t.Errorf("version %d = %d %q %s, want %d %q %s", i, v.version, v.changedBy, v.changeType, want[i].version, want[i].changedBy, want[i].changeType)
+		}
+	}
+}
+
+func TestMemoryBidDecisionQuorum(t *testing.T) {
+	m, tender, bid := seedMemoryTender(t)
+
+	if _, err := m.SubmitBidDecision(context.Background(), bid.Id, "Approved", "carol"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("decision by bid author: error = %v, want ErrForbidden", err)
+	}
+
+	got, err := m.SubmitBidDecision(context.Background(), bid.Id, "Approved", "alice")
+	if err != nil {
+		t.Fatalf("first approval: %v", err)
+	}
+	if got.Status != api.BidStatus(BidStatusPublished) {
+		t.Errorf("status after first approval = %s, want PUBLISHED", got.Status)
+	}
+	if _, err := m.SubmitBidDecision(context.Background(), bid.Id, "Approved", "alice"); !errors.Is(err, ErrDecisionAlreadySubmitted) {
+		t.Errorf("repeated decision: error = %v, want ErrDecisionAlreadySubmitted", err)
+	}
+
+	got, err = m.SubmitBidDecision(context.Background(), bid.Id, "Approved", "bob")
+	if err != nil {
+		t.Fatalf("second approval: %v", err)
+	}
+	if got.Status != api.BidStatus(BidStatusApproved) {
+		t.Errorf("status after quorum = %s, want APPROVED", got.Status)
+	}
+	if status, err := m.GetTenderStatus(context.Background(), tender.Id, "alice"); err != nil || status != string(TenderStatusClosed) {
+		t.Errorf("tender status after approval = %q, %v; want CLOSED", status, err)
+	}
+}
+
+func TestMemoryBidRejection(t *testing.T) {
+	m, tender, bid := seedMemoryTender(t)
+
+	got, err := m.SubmitBidDecision(context.Background(), bid.Id, "Rejected", "bob")
+	if err != nil {
+		t.Fatalf("SubmitBidDecision: %v", err)
+	}
+	if got.Status != api.BidStatus(BidStatusRejected) {
+		t.Errorf("status after rejection = %s, want REJECTED", got.Status)
+	}
+	if _, err := m.SubmitBidDecision(context.Background(), bid.Id, "Approved", "alice"); !errors.Is(err, ErrInvalidBidTransition) {
+		t.Errorf("approval of rejected bid: error = %v, want ErrInvalidBidTransition", err)
+	}
+	if status, _ := m.GetTenderStatus(context.Background(), tender.Id, "alice"); status != string(TenderStatusPublished) {
+		t.Errorf("tender status after rejection = %q, want PUBLISHED", status)
+	}
+}
+
+func TestMemoryCanceledContext(t *testing.T) {
+	m, tender, _ := seedMemoryTender(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.EditTender(ctx, tender.Id, "Other", "", "", "alice"); !errors.Is(err, context.Canceled) {
+		t.Errorf("EditTender with canceled context: error = %v, want context.Canceled", err)
+	}
+	if status, _ := m.GetTenderStatus(context.Background(), tender.Id, "alice"); status != string(TenderStatusPublished) {
+		t.Errorf("tender status after canceled edit = %q, want PUBLISHED", status)
+	}
+}
+
+func TestTextSearch(t *testing.T) {
+	search, err := newTextSearch("Асфальт, ремонт")
+	if err != nil {
+		t.Fatalf("newTextSearch: %v", err)
+	}
+
+	rank, snippet, ok := search.match("Ремонт дорог", "Укладка асфальта во дворе")
+	if !ok {
+		t.Fatal("match failed, want word forms to match")
+	}
+	if want := "<b>Ремонт</b> дорог. Укладка <b>асфальта</b> во дворе"; snippet != want {
+		t.Errorf("snippet = %q, want %q", snippet, want)
+	}
+	if want := float32(nameMatchWeight + descriptionMatchWeight); rank != want {
+		t.Errorf("rank = %v, want %v", rank, want)
+	}
+
+	// Все слова запроса обязательны, слова с другим суффиксом не совпадают
+	if _, _, ok := search.match("Ремонт офиса", "Покраска стен"); ok {
+		t.Error("match succeeded without the second word")
+	}
+	if _, _, ok := search.match("Асфальтирование и ремонтные работы", ""); ok {
+		t.Error("match succeeded for words with a different stem")
+	}
+	if _, err := newTextSearch(" - "); !errors.Is(err, ErrEmptySearchQuery) {
+		t.Errorf("newTextSearch(\" - \") = %v, want ErrEmptySearchQuery", err)
+	}
+}
+
+func TestMemoryOutbox(t *testing.T) {
+	m, tender, bid := seedMemoryTender(t)
+
+	for _, username := range []string{"alice", "bob"} {
+		if _, err := m.SubmitBidDecision(context.Background(), bid.Id, "Approved", username); err != nil {
+			t.Fatalf("SubmitBidDecision(%s): %v", username, err)
+		}
+	}
+	// Неудачная операция не записывает событие
+	if _, err := m.EditTender(context.Background(), tender.Id, "Other", "", "", "carol"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("EditTender by non-responsible: error = %v, want ErrForbidden", err)
+	}
+
+	var got []events.Type
+	n, err := m.ProcessOutbox(context.Background(), 0, 100, func(ctx context.Context, batch []events.Event) []int64 {
+		var ids []int64
+		for _, e := range batch {
+			got = append(got, e.Type)
+			if e.TenderId != tender.Id {
+				t.Errorf("event %d %s: tenderId = %s, want %s", e.Id, e.Type, e.TenderId, tender.Id)
+			}
+			// Последнее событие не доставлено и должно остаться в outbox
+			if e.Id < int64(len(batch)) {
+				ids = append(ids, e.Id)
+			}
+		}
+		return ids
+	})
+	want := []events.Type{
+		events.TenderCreated, events.TenderStatusChanged, events.BidCreated, events.BidStatusChanged,
+		events.BidDecisionSubmitted, events.BidDecisionSubmitted, events.BidStatusChanged, events.TenderStatusChanged,
+	}
+	if err != nil || n != len(want) || !slices.Equal(got, want) {
+		t.Fatalf("ProcessOutbox = %d, %v, events %v; want %v", n, err, got, want)
+	}
+
+	var closed events.TenderStatusChange
+	n, err = m.ProcessOutbox(context.Background(), 0, 100, func(ctx context.Context, batch []events.Event) []int64 {
+		if err := batch[0].Decode(&closed); err != nil {
+			t.Errorf("Decode: %v", err)
+		}
+		return []int64{batch[0].Id}
+	})
+	if err != nil || n != 1 || closed.PreviousStatus != string(TenderStatusPublished) || closed.Tender.Status != api.TenderStatus(TenderStatusClosed) {
+		t.Errorf("pending event = %d, %v, %+v; want tender closed from PUBLISHED", n, err, closed)
+	}
+	if n, err := m.ProcessOutbox(context.Background(), 0, 100, nil); err != nil || n != 0 {
+		t.Errorf("ProcessOutbox after delivery = %d, %v; want 0", n, err)
+	}
+
+	// Доставленные события удаляются по истечении срока хранения, а
+	// нумерация новых событий продолжается
+	if n, err := m.PruneOutbox(context.Background(), time.Hour); err != nil || n != 0 {
+		t.Errorf("PruneOutbox(1h) = %d, %v; want nothing pruned", n, err)
+	}
+	if n, err := m.PruneOutbox(context.Background(), 0); err != nil || n != len(want) {
+		t.Errorf("PruneOutbox(0) = %d, %v; want %d", n, err, len(want))
+	}
+	next := tender
+	next.Id, next.Name, next.Status = "", "Next", api.TenderStatus(TenderStatusCreated)
+	if _, err := m.CreateTender(context.Background(), next, "alice"); err != nil {
+		t.Fatalf("CreateTender: %v", err)
+	}
+	var ids []int64
+	m.ProcessOutbox(context.Background(), 0, 100, func(ctx context.Context, batch []events.Event) []int64 {
+		for _, e := range batch {
+			ids = append(ids, e.Id)
+		}
+		return ids
+	})
+	if !slices.Equal(ids, []int64{int64(len(want) + 1)}) {
+		t.Errorf("events after pruning = %v, want [%d]", ids, len(want)+1)
+	}
+}
+
+func TestWebhookAudience(t *testing.T) {
+	bid := api.Bid{Id: "bid-1", TenderId: "tender-1", AuthorType: "ORGANIZATION", AuthorId: "author-org", Status: "CREATED"}
+	audience := func(typ events.Type, status api.BidStatus) []string {
+		b := bid
+		b.Status = status
+		e, err := events.New(typ, events.BidChange{Bid: b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return webhookAudience(e, "tender-org")
+	}
+
+	// Черновик предложения видит только организация-автор, опубликованное —
+	// и организация тендера; решения автору не показываются
+	if got := audience(events.BidCreated, "CREATED"); !slices.Equal(got, []string{"author-org"}) {
+		t.Errorf("created bid audience = %v", got)
+	}
+	if got := audience(events.BidStatusChanged, "PUBLISHED"); !slices.Equal(got, []string{"tender-org", "author-org"}) {
+		t.Errorf("published bid audience = %v", got)
+	}
+	if got := audience(events.BidDecisionSubmitted, "PUBLISHED"); !slices.Equal(got, []string{"tender-org"}) {
+		t.Errorf("decision audience = %v", got)
+	}
+}
@@ -0,0 +1,358 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+var (
+	ErrOrganizationNotFound    = newError(KindNotFound, "organization not found")
+	ErrInvalidOrganizationType = newError(KindValidation, "invalid organization type")
+	ErrEmployeeNotFound        = newError(KindNotFound, "employee not found")
+	ErrUsernameTaken           = newError(KindConflict, "username is already taken")
+	ErrAlreadyResponsible      = newError(KindConflict, "user is already responsible for another organization")
+	ErrResponsibleNotFound     = newError(KindNotFound, "user is not responsible for the organization")
+)
+
+// Тип организации (enum organization_type)
+type OrganizationType string
+
+const (
+	OrganizationTypeIE  OrganizationType = "IE"
+	OrganizationTypeLLC OrganizationType = "LLC"
+	OrganizationTypeJSC OrganizationType = "JSC"
+)
+
+// Приводит тип организации к значению enum organization_type
+func ParseOrganizationType(s string) (OrganizationType, error) {
+	t := OrganizationType(strings.ToUpper(strings.TrimSpace(s)))
+	switch t {
+	case OrganizationTypeIE, OrganizationTypeLLC, OrganizationTypeJSC:
+		return t, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrInvalidOrganizationType, s)
+}
+
+// Организация (таблица organization)
+type Organization struct {
+	Id          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Type        OrganizationType `json:"type"`
+	CreatedAt   string           `json:"createdAt"`
+}
+
+// Код ошибки PostgreSQL unique_violation
+const uniqueViolation = "23505"
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && (constraint == "" || pgErr.ConstraintName == constraint)
+}
+
+// Проверяет по политике доступа право пользователя на управление организацией.
+// Для действий без конкретной организации organizationId пустой. Пользователь,
+// в том числе администратор из политики, должен быть сотрудником: только
+// сотруднику можно выпустить токен.
+func (db *DB) authorizeOrganization(ctx context.Context, q querier, organizationId string, username string, action authz.Action) error {
+	var isEmployee, isResponsible bool
+	query := `
+        SELECT
+            EXISTS(SELECT 1 FROM employee WHERE username = $1),
+            EXISTS(
+                SELECT 1
+                FROM organization_responsible r
+                JOIN employee e ON e.id = r.user_id
+                WHERE e.username = $1 AND r.organization_id::text = $2
+            )
+    `
+	err := q.QueryRow(ctx, query, username, organizationId).Scan(&isEmployee, &isResponsible)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error checking permission on organization", slog.String("username", username), slog.String("organization_id", organizationId), sl.Err(err))
+		return err
+	}
+	if !isEmployee {
+		db.Log.InfoContext(ctx, "User not found", slog.String("username", username))
+		return ErrUserNotFound
+	}
+
+	if organizationId != "" {
+		if _, err := db.selectOrganization(ctx, q, organizationId); err != nil {
+			return err
+		}
+	}
+
+	subject := newSubject(username, map[authz.Role]bool{authz.RoleOrgResponsible: isResponsible})
+	if !db.Policy.Allowed(action, subject, "") {
+		db.Log.InfoContext(ctx, "Action on organization is not allowed", slog.String("username", username), slog.String("action", string(action)), slog.String("organization_id", organizationId))
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (db *DB) selectOrganization(ctx context.Context, q querier, organizationId string) (Organization, error) {
+	var o Organization
+	var description *string
+	var createdAt time.Time
+	query := `
+        SELECT id, name, description, type, created_at
+        FROM organization
+        WHERE id = $1
+    `
+	err := q.QueryRow(ctx, query, organizationId).Scan(&o.Id, &o.Name, &description, &o.Type, &createdAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return Organization{}, ErrOrganizationNotFound
+		}
+		db.Log.ErrorContext(ctx, "Error retrieving organization", slog.String("organization_id", organizationId), sl.Err(err))
+		return Organization{}, err
+	}
+	if description != nil {
+		o.Description = *description
+	}
+	o.CreatedAt = createdAt.Format(time.RFC3339)
+	return o, nil
+}
+
+// Создание организации
+func (db *DB) CreateOrganization(ctx context.Context, org Organization, username string) (Organization, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if err := db.authorizeOrganization(ctx, db.Pool, "", username, authz.OrganizationCreate); err != nil {
+		return Organization{}, err
+	}
+
+	var created Organization
+	var description *string
+	var createdAt time.Time
+	query := `
+        INSERT INTO organization (name, description, type)
+        VALUES ($1, $2, $3)
+        RETURNING id, name, description, type, created_at
+    `
+	err := db.Pool.QueryRow(ctx, query, org.Name, org.Description, string(org.Type)).Scan(
+		&created.Id,
+		&created.Name,
+		&description,
+		&created.Type,
+		&createdAt,
+	)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error creating organization", sl.Err(err))
+		return Organization{}, err
+	}
+	if description != nil {
+		created.Description = *description
+	}
+	created.CreatedAt = createdAt.Format(time.RFC3339)
+
+	db.Log.InfoContext(ctx, "Organization created", slog.String("organization_id", created.Id), slog.String("username", username))
+	return created, nil
+}
+
+// Поля сортировки организаций в запросах с псевдонимом o
+var organizationSortColumns = sortColumns{
+	SortByName:      {expr: `o.name COLLATE "C"`, typ: "text"},
+	SortByCreatedAt: {expr: "o.created_at", typ: "timestamp"},
+}
+
+// Получение страницы списка организаций
+func (db *DB) GetOrganizations(ctx context.Context, page Page) ([]Organization, PageInfo, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return queryPage(ctx, db.Pool, pageQuery{
+		columns: "o.id, o.name, o.description, o.type, o.created_at",
+		from:    "FROM organization o WHERE 1=1",
+		sort:    organizationSortColumns,
+		id:      "o.id",
+	}, page, func(rows pgx.Rows, key *string) (Organization, string, error) {
+		var o Organization
+		var description *string
+		var createdAt time.Time
+
+		err := rows.Scan(&o.Id, &o.Name, &description, &o.Type, &createdAt, key)
+		if description != nil {
+			o.Description = *description
+		}
+		o.CreatedAt = createdAt.Format(time.RFC3339)
+		return o, o.Id, err
+	})
+}
+
+// Получение организации по идентификатору
+func (db *DB) GetOrganization(ctx context.Context, organizationId string) (Organization, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.selectOrganization(ctx, db.Pool, organizationId)
+}
+
+// Изменение организации. Незаданные поля остаются без изменений.
+func (db *DB) UpdateOrganization(ctx context.Context, organizationId string, name *string, description *string, orgType *OrganizationType, username string) (Organization, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if err := db.authorizeOrganization(ctx, db.Pool, organizationId, username, authz.OrganizationUpdate); err != nil {
+		return Organization{}, err
+	}
+
+	var typeArg *string
+	if orgType != nil {
+		t := string(*orgType)
+		typeArg = &t
+	}
+
+	query := `
+        UPDATE organization
+        SET name = COALESCE($1, name), description = COALESCE($2, description),
+            type = COALESCE($3::organization_type, type), updated_at = CURRENT_TIMESTAMP
+        WHERE id = $4
+    `
+	_, err := db.Pool.Exec(ctx, query, name, description, typeArg, organizationId)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error updating organization", slog.String("organization_id", organizationId), sl.Err(err))
+		return Organization{}, err
+	}
+
+	db.Log.InfoContext(ctx, "Organization updated", slog.String("organization_id", organizationId), slog.String("username", username))
+	return db.selectOrganization(ctx, db.Pool, organizationId)
+}
+
+// Получение ответственных за организацию
+func (db *DB) GetOrganizationResponsibles(ctx context.Context, organizationId string) ([]Employee, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := db.selectOrganization(ctx, db.Pool, organizationId); err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT e.id, e.username, e.first_name, e.last_name
+        FROM organization_responsible r
+        JOIN employee e ON e.id = r.user_id
+        WHERE r.organization_id = $1
+        ORDER BY e.username
+    `
+	rows, err := db.Pool.Query(ctx, query, organizationId)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error executing query", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	employees := []Employee{}
+	for rows.Next() {
+		e, err := scanEmployee(rows)
+		if err != nil {
+			db.Log.ErrorContext(ctx, "Error scanning row", sl.Err(err))
+			return nil, err
+		}
+		employees = append(employees, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		db.Log.ErrorContext(ctx, "Error after processing rows", sl.Err(err))
+		return nil, err
+	}
+
+	return employees, nil
+}
+
+// Назначение сотрудника ответственным за организацию. Сотрудник может быть
+// ответственным только в одной организации; повторное назначение в ту же
+// организацию ничего не меняет.
+func (db *DB) AddOrganizationResponsible(ctx context.Context, organizationId string, employeeUsername string, username string) (Employee, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
+		return Employee{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := db.authorizeOrganization(ctx, tx, organizationId, username, authz.OrganizationResponsibles); err != nil {
+		return Employee{}, err
+	}
+
+	employee, err := db.selectEmployee(ctx, tx, employeeUsername)
+	if err != nil {
+		if err == ErrUserNotFound {
+			return Employee{}, ErrEmployeeNotFound
+		}
+		return Employee{}, err
+	}
+
+	var currentOrganizationId string
+	err = tx.QueryRow(ctx, `SELECT organization_id FROM organization_responsible WHERE user_id = $1`, employee.Id).Scan(&currentOrganizationId)
+	switch {
+	case err == nil && currentOrganizationId == organizationId:
+		return employee, nil
+	case err == nil:
+		db.Log.InfoContext(ctx, "User is already responsible for another organization", slog.String("employee_username", employeeUsername), slog.String("organization_id", currentOrganizationId))
+		return Employee{}, ErrAlreadyResponsible
+	case err != pgx.ErrNoRows:
+		db.Log.ErrorContext(ctx, "Error retrieving organization of user", slog.String("employee_username", employeeUsername), sl.Err(err))
+		return Employee{}, err
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO organization_responsible (organization_id, user_id)
+        VALUES ($1, $2)
+    `, organizationId, employee.Id)
+	if err != nil {
+		if isUniqueViolation(err, "organization_responsible_user_unique") {
+			return Employee{}, ErrAlreadyResponsible
+		}
+		db.Log.ErrorContext(ctx, "Error assigning responsible to organization", slog.String("employee_username", employeeUsername), slog.String("organization_id", organizationId), sl.Err(err))
+		return Employee{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return Employee{}, err
+	}
+
+	db.Log.InfoContext(ctx, "Responsible assigned to organization", slog.String("employee_username", employeeUsername), slog.String("organization_id", organizationId), slog.String("username", username))
+	return employee, nil
+}
+
+// Снятие сотрудника с роли ответственного за организацию
+func (db *DB) RemoveOrganizationResponsible(ctx context.Context, organizationId string, employeeUsername string, username string) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if err := db.authorizeOrganization(ctx, db.Pool, organizationId, username, authz.OrganizationResponsibles); err != nil {
+		return err
+	}
+
+	tag, err := db.Pool.Exec(ctx, `
+        DELETE FROM organization_responsible r
+        USING employee e
+        WHERE e.id = r.user_id AND e.username = $2 AND r.organization_id = $1
+    `, organizationId, employeeUsername)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error removing responsible from organization", slog.String("employee_username", employeeUsername), slog.String("organization_id", organizationId), sl.Err(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrResponsibleNotFound
+	}
+
+	db.Log.InfoContext(ctx, "Responsible removed from organization", slog.String("employee_username", employeeUsername), slog.String("organization_id", organizationId), slog.String("username", username))
+	return nil
+}
@@ -0,0 +1,17 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseOrganizationType(t *testing.T) {
+	for _, s := range []string{"IE", "llc", " JSC "} {
+		if _, err := ParseOrganizationType(s); err != nil {
+			t.Errorf("ParseOrganizationType(%q): unexpected error %v", s, err)
+		}
+	}
+	if _, err := ParseOrganizationType("LTD"); !errors.Is(err, ErrInvalidOrganizationType) {
+		t.Errorf("ParseOrganizationType(LTD): error = %v, want ErrInvalidOrganizationType", err)
+	}
+}
@@ -0,0 +1,311 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+var (
+	_ events.Outbox = (*DB)(nil)
+	_ events.Outbox = (*Memory)(nil)
+)
+
+// Срок аренды обработки outbox. Обработка пакета ограничена этим сроком,
+// чтобы другой экземпляр не начал доставлять те же события, пока
+// получатели еще работают.
+const outboxLease = 5 * time.Minute
+
+// Размер порции при удалении старых событий
+const outboxPruneChunk = 1000
+
+// Ключ advisory-блокировки, под которой транзакции записывают события в
+// outbox
+const outboxLockKey int64 = 6105_2024_0002
+
+// Записывает событие в outbox. Вызывается в той же транзакции, что и
+// изменение, поэтому событие сохраняется тогда и только тогда, когда
+// сохраняется изменение.
+//
+// До конца транзакции держится блокировка outbox, поэтому Id событий
+// выдаются в порядке фиксации транзакций: когда событие видно читателю,
+// события с меньшим Id уже зафиксированы или отменены. Иначе чтение по
+// условию id > afterId пропускало бы события транзакции, которая получила
+// Id раньше, а зафиксировалась позже. Событие записывается последним
+// изменением транзакции, поэтому блокировка держится недолго.
+func (db *DB) insertEvent(ctx context.Context, tx pgx.Tx, typ events.Type, payload events.Payload) error {
+	e, err := events.New(typ, payload)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, outboxLockKey); err != nil {
+		db.Log.ErrorContext(ctx, "Error locking outbox", sl.Err(err))
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+        INSERT INTO outbox (event_type, aggregate_type, aggregate_id, tender_id, payload)
+        VALUES ($1, $2, $3, $4, $5)
+    `, string(e.Type), string(e.AggregateType), e.AggregateId, e.TenderId, []byte(e.Payload))
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error saving event", slog.String("event_type", string(e.Type)), slog.String("aggregate_type", string(e.AggregateType)), slog.String("aggregate_id", e.AggregateId), sl.Err(err))
+		return err
+	}
+	return nil
+}
+
+// Передает handle недоставленные события и отмечает доставленные.
+// Пакет выбирается в короткой транзакции, которая берет аренду outbox;
+// если аренду держит другой экземпляр, вызов ничего не делает. handle
+// выполняется вне транзакции и без соединения из пула, не дольше срока
+// аренды. Доставленные события отмечаются второй транзакцией, которая
+// освобождает аренду.
+func (db *DB) ProcessOutbox(ctx context.Context, afterId int64, limit int, handle func(ctx context.Context, batch []events.Event) []int64) (int, error) {
+	owner := uuid.NewString()
+	batch, err := db.claimOutbox(ctx, owner, afterId, limit)
+	if err != nil || len(batch) == 0 {
+		return 0, err
+	}
+
+	handleCtx, cancel := context.WithTimeout(ctx, outboxLease)
+	delivered := handle(handleCtx, batch)
+	cancel()
+
+	// Итог записывается и при остановке сервиса, чтобы не доставлять
+	// события повторно
+	if err := db.completeOutbox(context.WithoutCancel(ctx), owner, delivered); err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
+
+// Берет аренду outbox для owner и выбирает пакет событий. Если событий
+// нет, аренда сразу освобождается.
+func (db *DB) claimOutbox(ctx context.Context, owner string, afterId int64, limit int) ([]events.Event, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Аренда другого экземпляра, срок которой не истек, не перезаписывается
+	var leased bool
+	err = tx.QueryRow(ctx, `
+        INSERT INTO outbox_lease (id, owner, expires_at)
+        VALUES (TRUE, $1, CURRENT_TIMESTAMP + make_interval(secs => $2))
+        ON CONFLICT (id) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+        WHERE outbox_lease.expires_at < CURRENT_TIMESTAMP
+        RETURNING TRUE
+    `, owner, outboxLease.Seconds()).Scan(&leased)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error leasing outbox", sl.Err(err))
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+        SELECT id, event_type, aggregate_type, aggregate_id, tender_id, payload, created_at
+        FROM outbox
+        WHERE delivered_at IS NULL AND id > $1
+        ORDER BY id
+        LIMIT $2
+    `, afterId, limit)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error executing query", sl.Err(err))
+		return nil, err
+	}
+	batch, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return nil, err
+	}
+	return batch, nil
+}
+
+// Отмечает доставленные события и освобождает аренду owner
+func (db *DB) completeOutbox(ctx context.Context, owner string, delivered []int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if len(delivered) > 0 {
+		_, err = tx.Exec(ctx, `UPDATE outbox SET delivered_at = CURRENT_TIMESTAMP WHERE id = ANY($1)`, delivered)
+		if err != nil {
+			db.Log.ErrorContext(ctx, "Error marking events as delivered", sl.Err(err))
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM outbox_lease WHERE owner = $1`, owner); err != nil {
+		db.Log.ErrorContext(ctx, "Error releasing outbox lease", sl.Err(err))
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return err
+	}
+	return nil
+}
+
+// Удаляет события, доставленные раньше, чем retention назад. Удаление
+// идет порциями, чтобы не держать долгих блокировок.
+func (db *DB) PruneOutbox(ctx context.Context, retention time.Duration) (int, error) {
+	total := 0
+	for {
+		n, err := db.pruneOutboxChunk(ctx, retention)
+		total += n
+		if err != nil || n < outboxPruneChunk {
+			return total, err
+		}
+	}
+}
+
+func (db *DB) pruneOutboxChunk(ctx context.Context, retention time.Duration) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := db.Pool.Exec(ctx, `
+        DELETE FROM outbox
+        WHERE id IN (
+            SELECT id FROM outbox
+            WHERE delivered_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+            ORDER BY id
+            LIMIT $2
+        )
+    `, retention.Seconds(), outboxPruneChunk)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error pruning outbox", sl.Err(err))
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func scanEvents(rows pgx.Rows) ([]events.Event, error) {
+	defer rows.Close()
+
+	var result []events.Event
+	for rows.Next() {
+		var e events.Event
+		var payload []byte
+		err := rows.Scan(&e.Id, &e.Type, &e.AggregateType, &e.AggregateId, &e.TenderId, &payload, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
+		}
+		e.Payload = payload
+		e.CreatedAt = e.CreatedAt.UTC()
+		result = append(result, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("processing rows: %w", err)
+	}
+	return result, nil
+}
+
+// Событие в outbox Memory
+type memEvent struct {
+	events.Event
+	// Время доставки, нулевое у недоставленных событий
+	deliveredAt time.Time
+}
+
+// Записывает событие в outbox Memory. Вызывается под блокировкой
+// хранилища после всех проверок операции, поэтому Id событий, как и в DB,
+// выдаются в порядке фиксации изменений.
+func (m *Memory) addEvent(typ events.Type, payload events.Payload) {
+	e, err := events.New(typ, payload)
+	if err != nil {
+		panic(err)
+	}
+	m.lastEventId++
+	e.Id = m.lastEventId
+	e.CreatedAt = time.Now().UTC()
+	m.outbox = append(m.outbox, &memEvent{Event: e})
+}
+
+// События outbox с Id больше afterId. Вызывается под блокировкой хранилища.
+func (m *Memory) eventsAfter(afterId int64) []*memEvent {
+	i := sort.Search(len(m.outbox), func(i int) bool { return m.outbox[i].Id > afterId })
+	return m.outbox[i:]
+}
+
+// Обрабатывает события так же, как DB. handle вызывается без блокировки
+// хранилища, чтобы медленный получатель не задерживал запросы.
+func (m *Memory) ProcessOutbox(ctx context.Context, afterId int64, limit int, handle func(ctx context.Context, batch []events.Event) []int64) (int, error) {
+	m.outboxMu.Lock()
+	defer m.outboxMu.Unlock()
+
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	var batch []events.Event
+	for _, e := range m.eventsAfter(afterId) {
+		if len(batch) == limit {
+			break
+		}
+		if e.deliveredAt.IsZero() {
+			batch = append(batch, e.Event)
+		}
+	}
+	m.mu.Unlock()
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	delivered := handle(ctx, batch)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, id := range delivered {
+		if rest := m.eventsAfter(id - 1); len(rest) > 0 && rest[0].Id == id {
+			rest[0].deliveredAt = now
+		}
+	}
+	return len(batch), nil
+}
+
+func (m *Memory) PruneOutbox(ctx context.Context, retention time.Duration) (int, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mu.Unlock()
+
+	before := time.Now().Add(-retention)
+	kept := m.outbox[:0]
+	for _, e := range m.outbox {
+		if e.deliveredAt.IsZero() || !e.deliveredAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	pruned := len(m.outbox) - len(kept)
+	clear(m.outbox[len(kept):])
+	m.outbox = kept
+	return pruned, nil
+}
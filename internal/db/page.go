@@ -0,0 +1,351 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// Ограничения размера страницы из спецификации (paginationLimit)
+const (
+	DefaultPageLimit int32 = 5
+	MaxPageLimit     int32 = 50
+)
+
+var (
+	ErrInvalidSort   = newError(KindValidation, "invalid sort parameter")
+	ErrInvalidCursor = newError(KindValidation, "invalid cursor")
+)
+
+// Поле сортировки списка
+type SortField string
+
+const (
+	SortByName      SortField = "name"
+	SortByCreatedAt SortField = "createdAt"
+	SortByUpdatedAt SortField = "updatedAt"
+)
+
+// Поля сортировки, доступные в списках
+var (
+	TenderSortFields          = []SortField{SortByName, SortByCreatedAt, SortByUpdatedAt}
+	BidSortFields             = []SortField{SortByName, SortByCreatedAt, SortByUpdatedAt}
+	OrganizationSortFields    = []SortField{SortByName, SortByCreatedAt}
+	ReviewSortFields          = []SortField{SortByCreatedAt}
+	WebhookDeliverySortFields = []SortField{SortByCreatedAt}
+)
+
+type Sort struct {
+	Field SortField
+	Desc  bool
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return string(s.Field) + ",desc"
+	}
+	return string(s.Field) + ",asc"
+}
+
+// Разбирает значение вида "createdAt" или "createdAt,desc". Поле должно
+// входить в fields.
+func ParseSort(s string, fields []SortField) (Sort, error) {
+	field, direction, _ := strings.Cut(s, ",")
+	sort := Sort{Field: SortField(field)}
+	if !slices.Contains(fields, sort.Field) {
+		return Sort{}, fmt.Errorf("%w: unsupported field %q", ErrInvalidSort, field)
+	}
+	switch strings.ToLower(direction) {
+	case "", "asc":
+	case "desc":
+		sort.Desc = true
+	default:
+		return Sort{}, fmt.Errorf("%w: unsupported direction %q", ErrInvalidSort, direction)
+	}
+	return sort, nil
+}
+
+// Позиция в списке: значение поля сортировки и идентификатор последнего
+// элемента предыдущей страницы. Клиенту передается в закодированном виде.
+type Cursor struct {
+	Sort Sort
+	Key  string
+	Id   string
+}
+
+type cursorData struct {
+	Field SortField `json:"f"`
+	Desc  bool      `json:"d,omitempty"`
+	Key   string    `json:"k"`
+	Id    string    `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(cursorData{Field: c.Sort.Field, Desc: c.Sort.Desc, Key: c.Key, Id: c.Id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c cursorData
+	if err := json.Unmarshal(data, &c); err != nil || c.Field == "" || c.Id == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{Sort: Sort{Field: c.Field, Desc: c.Desc}, Key: c.Key, Id: c.Id}, nil
+}
+
+// Параметры страницы списка. Cursor и Offset взаимоисключающие: курсор
+// задает позицию после последнего элемента предыдущей страницы.
+type Page struct {
+	Limit     int32
+	Offset    int32
+	Cursor    *Cursor
+	Sort      Sort
+	WithTotal bool
+}
+
+// Курсор следующей страницы (пустой, если страница последняя) и общее
+// число элементов списка, если оно запрошено
+type PageInfo struct {
+	NextCursor string
+	Total      *int
+}
+
+// Выражение SQL для поля сортировки и тип, к которому приводится
+// значение курсора
+type sortColumn struct {
+	expr string
+	typ  string
+}
+
+type sortColumns map[SortField]sortColumn
+
+// Выборка страницы списка. from содержит FROM и WHERE запроса, id —
+// столбец идентификатора для однозначного порядка.
+type pageQuery struct {
+	columns string
+	from    string
+	args    []interface{}
+	sort    sortColumns
+	id      string
+}
+
+// Запрос страницы. Ключ сортировки выбирается последним столбцом в виде
+// текста, выборка содержит на одну строку больше лимита, чтобы узнать,
+// есть ли следующая страница.
+func (q pageQuery) sql(p Page) (string, []interface{}, error) {
+	column, ok := q.sort[p.Sort.Field]
+	if !ok {
+		return "", nil, ErrInvalidSort
+	}
+	direction, compare := "ASC", ">"
+	if p.Sort.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	var b strings.Builder
+	args := slices.Clone(q.args)
+	fmt.Fprintf(&b, "SELECT %s, (%s)::text %s", q.columns, column.expr, q.from)
+	if p.Cursor != nil {
+		if p.Cursor.Sort != p.Sort {
+			return "", nil, ErrInvalidCursor
+		}
+		if err := validCursor(*p.Cursor, column); err != nil {
+			return "", nil, err
+		}
+		args = append(args, p.Cursor.Key, p.Cursor.Id)
+		fmt.Fprintf(&b, " AND (%s, %s) %s ($%d::%s, $%d::uuid)", column.expr, q.id, compare, len(args)-1, column.typ, len(args))
+	}
+	fmt.Fprintf(&b, " ORDER BY %s %s, %s %s", column.expr, direction, q.id, direction)
+	args = append(args, p.Limit+1, p.Offset)
+	fmt.Fprintf(&b, " LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	return b.String(), args, nil
+}
+
+// Формат текстового представления timestamp в PostgreSQL (DateStyle ISO)
+const postgresTimestamp = "2006-01-02 15:04:05.999999"
+
+// Проверяет, что значения курсора можно привести к типам столбцов, чтобы
+// поврежденный курсор не превращался в ошибку запроса
+func validCursor(c Cursor, column sortColumn) error {
+	if _, err := uuid.Parse(c.Id); err != nil {
+		return ErrInvalidCursor
+	}
+	if column.typ == "timestamp" {
+		if _, err := time.Parse(postgresTimestamp, c.Key); err != nil {
+			return ErrInvalidCursor
+		}
+	}
+	return nil
+}
+
+func (q pageQuery) countSQL() string {
+	return "SELECT count(*) " + q.from
+}
+
+type rowsQuerier interface {
+	querier
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// Выполняет выборку страницы. scan читает столбцы строки, передавая key
+// последним аргументом rows.Scan, и возвращает элемент и его идентификатор.
+func queryPage[T any](ctx context.Context, q rowsQuerier, pq pageQuery, p Page, scan func(rows pgx.Rows, key *string) (T, string, error)) ([]T, PageInfo, error) {
+	items := []T{}
+	var info PageInfo
+
+	if p.Limit > 0 {
+		sql, args, err := pq.sql(p)
+		if err != nil {
+			return nil, PageInfo{}, err
+		}
+		rows, err := q.Query(ctx, sql, args...)
+		if err != nil {
+			return nil, PageInfo{}, fmt.Errorf("executing query: %w", err)
+		}
+		defer rows.Close()
+
+		var keys, ids []string
+		for rows.Next() {
+			var key string
+			item, id, err := scan(rows, &key)
+			if err != nil {
+				return nil, PageInfo{}, fmt.Errorf("scanning row: %w", err)
+			}
+			items = append(items, item)
+			keys = append(keys, key)
+			ids = append(ids, id)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, PageInfo{}, fmt.Errorf("processing rows: %w", err)
+		}
+
+		if len(items) > int(p.Limit) {
+			items = items[:p.Limit]
+			info.NextCursor = Cursor{Sort: p.Sort, Key: keys[p.Limit-1], Id: ids[p.Limit-1]}.Encode()
+		}
+	}
+
+	if p.WithTotal {
+		var total int
+		if err := q.QueryRow(ctx, pq.countSQL(), pq.args...).Scan(&total); err != nil {
+			return nil, PageInfo{}, fmt.Errorf("counting rows: %w", err)
+		}
+		info.Total = &total
+	}
+	return items, info, nil
+}
+
+// Значения полей сортировки элемента Memory
+type memSortKey struct {
+	id        string
+	name      string
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// Время создания в формате ответа API (RFC3339)
+func parseCreatedAt(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+func (k memSortKey) value(field SortField) string {
+	switch field {
+	case SortByCreatedAt:
+		return k.createdAt.Format(time.RFC3339Nano)
+	case SortByUpdatedAt:
+		return k.updatedAt.Format(time.RFC3339Nano)
+	}
+	return k.name
+}
+
+// Ключ элемента, на котором остановилась предыдущая страница
+func cursorSortKey(c Cursor) (memSortKey, error) {
+	k := memSortKey{id: c.Id}
+	switch c.Sort.Field {
+	case SortByName:
+		k.name = c.Key
+		return k, nil
+	case SortByCreatedAt, SortByUpdatedAt:
+		t, err := time.Parse(time.RFC3339Nano, c.Key)
+		if err != nil {
+			return memSortKey{}, ErrInvalidCursor
+		}
+		k.createdAt, k.updatedAt = t, t
+		return k, nil
+	}
+	return memSortKey{}, ErrInvalidCursor
+}
+
+// Порядок по полю сортировки, при равенстве — по идентификатору. Названия
+// сравниваются побайтно, как в PostgreSQL с COLLATE "C".
+func (k memSortKey) compare(o memSortKey, field SortField) int {
+	var c int
+	switch field {
+	case SortByName:
+		c = strings.Compare(k.name, o.name)
+	case SortByCreatedAt:
+		c = k.createdAt.Compare(o.createdAt)
+	case SortByUpdatedAt:
+		c = k.updatedAt.Compare(o.updatedAt)
+	}
+	return cmp.Or(c, strings.Compare(k.id, o.id))
+}
+
+// Страница списка Memory с той же семантикой, что и queryPage
+func memSortedPage[T any](items []T, p Page, key func(T) memSortKey) ([]T, PageInfo, error) {
+	var info PageInfo
+	if p.WithTotal {
+		total := len(items)
+		info.Total = &total
+	}
+
+	order := func(a, b memSortKey) int {
+		if p.Sort.Desc {
+			return b.compare(a, p.Sort.Field)
+		}
+		return a.compare(b, p.Sort.Field)
+	}
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b T) int { return order(key(a), key(b)) })
+
+	if p.Cursor != nil {
+		if p.Cursor.Sort != p.Sort {
+			return nil, PageInfo{}, ErrInvalidCursor
+		}
+		after, err := cursorSortKey(*p.Cursor)
+		if err != nil {
+			return nil, PageInfo{}, err
+		}
+		start := len(sorted)
+		for i, item := range sorted {
+			if order(key(item), after) > 0 {
+				start = i
+				break
+			}
+		}
+		sorted = sorted[start:]
+	}
+
+	sorted = sorted[min(int(p.Offset), len(sorted)):]
+	if len(sorted) > int(p.Limit) {
+		sorted = sorted[:p.Limit]
+		if p.Limit > 0 {
+			last := key(sorted[p.Limit-1])
+			info.NextCursor = Cursor{Sort: p.Sort, Key: last.value(p.Sort.Field), Id: last.id}.Encode()
+		}
+	}
+	return sorted, info, nil
+}
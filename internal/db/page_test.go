@@ -0,0 +1,151 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := map[string]Sort{
+		"name":           {Field: SortByName},
+		"createdAt,desc": {Field: SortByCreatedAt, Desc: true},
+		"updatedAt,ASC":  {Field: SortByUpdatedAt},
+	}
+	for value, want := range tests {
+		if got, err := ParseSort(value, TenderSortFields); err != nil || got != want {
+			t.Errorf("ParseSort(%q) = %+v, %v; want %+v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"price", "name,up", "updatedAt"} {
+		if _, err := ParseSort(value, OrganizationSortFields); !errors.Is(err, ErrInvalidSort) {
+			t.Errorf("ParseSort(%q) = %v, want ErrInvalidSort", value, err)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	cursor := Cursor{Sort: Sort{Field: SortByCreatedAt, Desc: true}, Key: "2024-09-10 12:00:00.5", Id: "6d1a9a0e-5f5b-4c1e-9d59-1d3f0c5e7a10"}
+	decoded, err := DecodeCursor(cursor.Encode())
+	if err != nil || decoded != cursor {
+		t.Fatalf("DecodeCursor(Encode()) = %+v, %v; want %+v", decoded, err, cursor)
+	}
+	for _, value := range []string{"not base64!", "e30"} {
+		if _, err := DecodeCursor(value); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) = %v, want ErrInvalidCursor", value, err)
+		}
+	}
+}
+
+func TestPageQuerySQL(t *testing.T) {
+	query := pageQuery{
+		columns: tenderColumns,
+		from:    "FROM tenders t WHERE t.creator_username = $1",
+		args:    []interface{}{"alice"},
+		sort:    tenderSortColumns,
+		id:      "t.id",
+	}
+	sort := Sort{Field: SortByUpdatedAt, Desc: true}
+	cursor := Cursor{Sort: sort, Key: "2024-09-10 12:00:00.123456", Id: "6d1a9a0e-5f5b-4c1e-9d59-1d3f0c5e7a10"}
+
+	sql, args, err := query.sql(Page{Limit: 5, Sort: sort, Cursor: &cursor})
+	if err != nil {
+		t.Fatalf("sql: %v", err)
+	}
+	for _, part := range []string{
+		"(COALESCE(t.updated_at, t.created_at))::text FROM tenders t",
+		"AND (COALESCE(t.updated_at, t.created_at), t.id) < ($2::timestamp, $3::uuid)",
+		"ORDER BY COALESCE(t.updated_at, t.created_at) DESC, t.id DESC LIMIT $4 OFFSET $5",
+	} {
+		if !strings.Contains(sql, part) {
+			t.Errorf("query does not contain %q:\n%s", part, sql)
+		}
+	}
+	// Выбирается на одну строку больше лимита
+	if len(args) != 5 || args[3] != int32(6) {
+		t.Errorf("args = %v", args)
+	}
+
+	cursor.Key = "yesterday"
+	if _, _, err := query.sql(Page{Limit: 5, Sort: sort, Cursor: &cursor}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("sql with invalid cursor key = %v, want ErrInvalidCursor", err)
+	}
+	if _, _, err := query.sql(Page{Limit: 5, Sort: Sort{Field: SortByName}, Cursor: &cursor}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("sql with cursor of another sort = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestMemSortedPage(t *testing.T) {
+	start := time.Date(2024, 9, 10, 12, 0, 0, 0, time.UTC)
+	var items []memSortKey
+	for i, name := range []string{"d", "b", "e", "a", "c"} {
+		items = append(items, memSortKey{id: name, name: name, createdAt: start.Add(time.Duration(i) * time.Second)})
+	}
+	key := func(k memSortKey) memSortKey { return k }
+
+	var names []string
+	page := Page{Limit: 2, Sort: Sort{Field: SortByName}, WithTotal: true}
+	for {
+		result, info, err := memSortedPage(items, page, key)
+		if err != nil {
+			t.Fatalf("memSortedPage: %v", err)
+		}
+		if info.Total == nil || *info.Total != 5 {
+			t.Errorf("total = %v, want 5", info.Total)
+		}
+		for _, k := range result {
+			names = append(names, k.name)
+		}
+		if info.NextCursor == "" {
+			break
+		}
+		cursor, err := DecodeCursor(info.NextCursor)
+		if err != nil {
+			t.Fatalf("DecodeCursor: %v", err)
+		}
+		page.Cursor = &cursor
+	}
+	if got := strings.Join(names, ""); got != "abcde" {
+		t.Errorf("pages = %q, want abcde", got)
+	}
+
+	result, info, _ := memSortedPage(items, Page{Limit: 2, Offset: 1, Sort: Sort{Field: SortByCreatedAt, Desc: true}}, key)
+	if len(result) != 2 || result[0].name != "a" || result[1].name != "e" || info.NextCursor == "" {
+		t.Errorf("createdAt desc page = %+v, %+v", result, info)
+	}
+	if result, info, _ := memSortedPage(items, Page{Sort: Sort{Field: SortByName}}, key); len(result) != 0 || info.NextCursor != "" {
+		t.Errorf("empty page = %+v, %+v", result, info)
+	}
+}
+
+// Названия упорядочиваются побайтно и в Memory, и в PostgreSQL (COLLATE "C"),
+// поэтому страницы списков совпадают в обоих хранилищах независимо от локали
+func TestNameSortIsBytewise(t *testing.T) {
+	for table, columns := range map[string]sortColumns{
+		"tenders":       tenderSortColumns,
+		"bids":          bidSortColumns,
+		"organizations": organizationSortColumns,
+	} {
+		if expr := columns[SortByName].expr; !strings.HasSuffix(expr, `.name COLLATE "C"`) {
+			t.Errorf("%s: name sort expression = %q, want bytewise collation", table, expr)
+		}
+	}
+
+	var items []memSortKey
+	for i, name := range []string{"яблоко", "Zeta", "ёж", "alpha", "Яблоко", "9", "Ёж", "10"} {
+		items = append(items, memSortKey{id: strings.Repeat("0", i), name: name})
+	}
+	result, _, err := memSortedPage(items, Page{Limit: 10, Sort: Sort{Field: SortByName}}, func(k memSortKey) memSortKey { return k })
+	if err != nil {
+		t.Fatalf("memSortedPage: %v", err)
+	}
+	var names []string
+	for _, k := range result {
+		names = append(names, k.name)
+	}
+	// Порядок кодовых точек: цифры, латиница в верхнем и нижнем регистре, Ё, А-Я, а-я, ё
+	if got, want := strings.Join(names, " "), "10 9 Zeta alpha Ёж Яблоко яблоко ёж"; got != want {
+		t.Errorf("names = %q, want %q", got, want)
+	}
+}
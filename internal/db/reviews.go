@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Отправка отзыва по предложению. Оставить отзыв может только
+// ответственный за организацию, которой принадлежит тендер предложения.
+func (db *DB) SubmitBidFeedback(ctx context.Context, bidId string, feedback string, username string) (api.Bid, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
+		return api.Bid{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	access, err := db.authorizeBid(ctx, tx, bidId, username, authz.BidFeedback)
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	var bid api.Bid
+	var createdAt time.Time
+	query := `
+        SELECT id, name, description, tender_id, author_id, author_type, status, version, created_at
+        FROM bids
+        WHERE id = $1
+    `
+	err = tx.QueryRow(ctx, query, bidId).Scan(
+		&bid.Id,
+		&bid.Name,
+		&bid.Description,
+		&bid.TenderId,
+		&bid.AuthorId,
+		&bid.AuthorType,
+		&bid.Status,
+		&bid.Version,
+		&createdAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			db.Log.InfoContext(ctx, "Bid not found", slog.String("bid_id", bidId))
+			return api.Bid{}, ErrBidNotFound
+		}
+		db.Log.ErrorContext(ctx, "Error retrieving bid", slog.String("bid_id", bidId), sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	if !BidStatus(bid.Status).visibleToTender() {
+		db.Log.InfoContext(ctx, "Feedback is not allowed for bid", slog.String("bid_id", bidId), slog.String("bid_status", string(bid.Status)))
+		return api.Bid{}, ErrFeedbackNotAllowed
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO bid_reviews (bid_id, reviewer_id, description)
+        VALUES ($1, $2, $3)
+    `, bidId, access.UserId, feedback)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error saving review for bid", slog.String("bid_id", bidId), sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	bid.CreatedAt = createdAt.Format(time.RFC3339)
+
+	err = db.insertEvent(ctx, tx, events.BidFeedbackSubmitted, events.BidFeedback{Bid: bid, Feedback: feedback, Username: username})
+	if err != nil {
+		return api.Bid{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return api.Bid{}, err
+	}
+
+	db.Log.InfoContext(ctx, "Review of bid saved", slog.String("username", username), slog.String("bid_id", bidId))
+	return bid, nil
+}
+
+// Просмотр отзывов на прошлые предложения автора по всем тендерам.
+// Доступно только ответственным за организацию, которой принадлежит тендер.
+func (db *DB) GetBidReviews(ctx context.Context, tenderId string, authorUsername string, requesterUsername string, page Page) ([]api.BidReview, PageInfo, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := db.authorizeTender(ctx, db.Pool, tenderId, requesterUsername, authz.TenderReviews); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	authorId, err := getUserId(ctx, db.Pool, authorUsername)
+	if err != nil {
+		if err == ErrUserNotFound {
+			return nil, PageInfo{}, ErrAuthorNotFound
+		}
+		return nil, PageInfo{}, err
+	}
+
+	reviews, info, err := queryPage(ctx, db.Pool, pageQuery{
+		columns: "r.id, r.description, r.created_at",
+		from: `
+        FROM bid_reviews r
+        JOIN bids b ON b.id = r.bid_id
+        WHERE b.author_type = 'USER' AND b.author_id = $1`,
+		args: []interface{}{authorId},
+		sort: sortColumns{SortByCreatedAt: {expr: "r.created_at", typ: "timestamp"}},
+		id:   "r.id",
+	}, page, func(rows pgx.Rows, key *string) (api.BidReview, string, error) {
+		var review api.BidReview
+		var createdAt time.Time
+
+		err := rows.Scan(&review.Id, &review.Description, &createdAt, key)
+		review.CreatedAt = createdAt.Format(time.RFC3339)
+		return review, review.Id, err
+	})
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error getting reviews of author", slog.String("author_username", authorUsername), sl.Err(err))
+		return nil, PageInfo{}, err
+	}
+
+	db.Log.DebugContext(ctx, "Successfully retrieved reviews of author", slog.Int("count", len(reviews)), slog.String("author_username", authorUsername))
+	return reviews, info, nil
+}
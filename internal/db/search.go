@@ -0,0 +1,338 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+var ErrEmptySearchQuery = newError(KindValidation, "search query must not be empty")
+
+// Тендер, найденный полнотекстовым поиском. Snippet содержит фрагменты
+// названия и описания, в которых найденные слова выделены тегами <b></b>.
+type TenderSearchResult struct {
+	api.Tender
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// Предложение, найденное полнотекстовым поиском
+type BidSearchResult struct {
+	api.Bid
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// Запрос к индексу: синтаксис websearch ("кавычки", or, -исключение) в
+// русской и английской конфигурациях. Вынесен в CTE, чтобы разобрать
+// строку поиска один раз.
+const searchQuery = `
+    WITH q AS (
+        SELECT websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1) AS query
+    )
+`
+
+// Параметры ts_headline: до двух фрагментов по 5-20 слов
+const snippetOptions = `'StartSel=<b>, StopSel=</b>, MaxFragments=2, MinWords=5, MaxWords=20, FragmentDelimiter=" … "'`
+
+// Поиск тендеров по названию и описанию. Результаты упорядочены по
+// релевантности.
+func (db *DB) SearchTenders(ctx context.Context, query string, serviceTypes []api.TenderServiceType, limit int32, offset int32) ([]TenderSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var queryBuilder strings.Builder
+	args := []interface{}{query}
+
+	queryBuilder.WriteString(searchQuery)
+	queryBuilder.WriteString(`
+        SELECT t.id, t.name, t.description, t.organization_id, t.service_type, t.status, t.version, t.created_at,
+            ts_rank_cd(t.search_vector, q.query) AS rank,
+            ts_headline('russian', t.name || '. ' || coalesce(t.description, ''), q.query, ` + snippetOptions + `)
+        FROM tenders t, q
+        WHERE t.search_vector @@ q.query
+    `)
+
+	if len(serviceTypes) > 0 {
+		args = append(args, serviceTypes)
+		queryBuilder.WriteString(fmt.Sprintf(" AND t.service_type = ANY($%d)", len(args)))
+	}
+
+	args = append(args, limit, offset)
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY rank DESC, t.created_at DESC, t.id LIMIT $%d OFFSET $%d", len(args)-1, len(args)))
+
+	rows, err := db.Pool.Query(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error executing query to search tenders", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []TenderSearchResult{}
+	for rows.Next() {
+		var r TenderSearchResult
+		var createdAt time.Time
+
+		err := rows.Scan(
+			&r.Id,
+			&r.Name,
+			&r.Description,
+			&r.OrganizationId,
+			&r.ServiceType,
+			&r.Status,
+			&r.Version,
+			&createdAt,
+			&r.Rank,
+			&r.Snippet,
+		)
+		if err != nil {
+			db.Log.ErrorContext(ctx, "Error scanning row", sl.Err(err))
+			return nil, err
+		}
+
+		r.CreatedAt = createdAt.Format(time.RFC3339)
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		db.Log.ErrorContext(ctx, "Error after processing rows", sl.Err(err))
+		return nil, err
+	}
+
+	db.Log.DebugContext(ctx, "Found tenders for query", slog.Int("count", len(results)), slog.String("query", query))
+	return results, nil
+}
+
+// Статусы тендеров, предложения по которым политика показывает
+// ответственному за организацию
+func responsibleBidTenderStatuses(policy *authz.Policy, username string) []string {
+	subject := newSubject(username, map[authz.Role]bool{authz.RoleOrgResponsible: true})
+	var statuses []string
+	for _, status := range []TenderStatus{TenderStatusCreated, TenderStatusPublished, TenderStatusClosed} {
+		if policy.Allowed(authz.TenderBids, subject, string(status)) {
+			statuses = append(statuses, string(status))
+		}
+	}
+	return statuses
+}
+
+// Поиск предложений для ответственного: опубликованные и рассмотренные
+// предложения по тендерам его организации, а также собственные
+// предложения пользователя и его организации в любом статусе.
+func (db *DB) SearchBids(ctx context.Context, query string, username string, limit int32, offset int32) ([]BidSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	userId, err := getUserId(ctx, db.Pool, username)
+	if err != nil {
+		return nil, err
+	}
+
+	sql := searchQuery + `
+        SELECT b.id, b.name, b.description, b.tender_id, b.author_id, b.author_type, b.status, b.version, b.created_at,
+            ts_rank_cd(b.search_vector, q.query) AS rank,
+            ts_headline('russian', b.name || '. ' || coalesce(b.description, ''), q.query, ` + snippetOptions + `)
+        FROM bids b
+        JOIN tenders t ON t.id = b.tender_id, q
+        WHERE b.search_vector @@ q.query
+        AND (
+            (
+                t.organization_id IN (SELECT organization_id FROM organization_responsible WHERE user_id = $2)
+                AND t.status::text = ANY($3)
+                AND b.status IN ('PUBLISHED', 'APPROVED', 'REJECTED')
+            )
+            OR b.author_id = $2
+            OR b.author_id IN (SELECT organization_id FROM organization_responsible WHERE user_id = $2)
+        )
+        ORDER BY rank DESC, b.created_at DESC, b.id
+        LIMIT $4 OFFSET $5
+    `
+	args := []interface{}{query, userId, responsibleBidTenderStatuses(db.Policy, username), limit, offset}
+
+	rows, err := db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error executing query to search bids", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []BidSearchResult{}
+	for rows.Next() {
+		var r BidSearchResult
+		var createdAt time.Time
+
+		err := rows.Scan(
+			&r.Id,
+			&r.Name,
+			&r.Description,
+			&r.TenderId,
+			&r.AuthorId,
+			&r.AuthorType,
+			&r.Status,
+			&r.Version,
+			&createdAt,
+			&r.Rank,
+			&r.Snippet,
+		)
+		if err != nil {
+			db.Log.ErrorContext(ctx, "Error scanning row", sl.Err(err))
+			return nil, err
+		}
+
+		r.CreatedAt = createdAt.Format(time.RFC3339)
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		db.Log.ErrorContext(ctx, "Error after processing rows", sl.Err(err))
+		return nil, err
+	}
+
+	db.Log.DebugContext(ctx, "Found bids for query", slog.Int("count", len(results)), slog.String("username", username), slog.String("query", query))
+	return results, nil
+}
+
+// Веса совпадений в названии и описании, как у весов A и B в ts_rank_cd
+const (
+	nameMatchWeight        = 1.0
+	descriptionMatchWeight = 0.4
+)
+
+// Число слов во фрагменте Memory и слов перед первым совпадением
+const (
+	snippetWords       = 20
+	snippetWordsBefore = 5
+)
+
+// Поиск по тексту для Memory. Приближает поведение словарей PostgreSQL:
+// слово документа совпадает со словом запроса, если они различаются не
+// более чем окончанием (см. wordMatches). Все слова запроса должны
+// встретиться в названии или описании.
+type textSearch struct {
+	terms []string
+}
+
+func newTextSearch(query string) (textSearch, error) {
+	var terms []string
+	for _, w := range textWords(query) {
+		terms = append(terms, normalizeWord(query[w[0]:w[1]]))
+	}
+	if len(terms) == 0 {
+		return textSearch{}, ErrEmptySearchQuery
+	}
+	return textSearch{terms: terms}, nil
+}
+
+// Границы слов текста: пары индексов байтов [начало, конец)
+func textWords(text string) [][2]int {
+	var words [][2]int
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			words = append(words, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, [2]int{start, len(text)})
+	}
+	return words
+}
+
+func normalizeWord(word string) string {
+	return strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+}
+
+// Словоформы совпадают, если у них общая основа не короче minStemLength
+// символов, а окончания не длиннее maxEndingLength символов
+const (
+	minStemLength   = 3
+	maxEndingLength = 2
+)
+
+func wordMatches(term string, word string) bool {
+	if term == word {
+		return true
+	}
+	a, b := []rune(term), []rune(word)
+	stem := 0
+	for stem < len(a) && stem < len(b) && a[stem] == b[stem] {
+		stem++
+	}
+	return stem >= minStemLength && len(a)-stem <= maxEndingLength && len(b)-stem <= maxEndingLength
+}
+
+// Ранг и фрагмент с выделенными словами. ok ложно, если какое-то слово
+// запроса не найдено.
+func (s textSearch) match(name string, description string) (rank float32, snippet string, ok bool) {
+	text := name + ". " + description
+	words := textWords(text)
+	nameEnd := len(name)
+
+	found := make([]bool, len(s.terms))
+	hits := make([]bool, len(words))
+	for i, w := range words {
+		word := normalizeWord(text[w[0]:w[1]])
+		for j, term := range s.terms {
+			if !wordMatches(term, word) {
+				continue
+			}
+			found[j], hits[i] = true, true
+			if w[0] < nameEnd {
+				rank += nameMatchWeight
+			} else {
+				rank += descriptionMatchWeight
+			}
+		}
+	}
+	for _, f := range found {
+		if !f {
+			return 0, "", false
+		}
+	}
+
+	first := 0
+	for i, hit := range hits {
+		if hit {
+			first = i
+			break
+		}
+	}
+	from := max(first-snippetWordsBefore, 0)
+	to := min(from+snippetWords, len(words))
+
+	var b strings.Builder
+	for i := from; i < to; i++ {
+		if i > from {
+			b.WriteString(text[words[i-1][1]:words[i][0]])
+		}
+		word := text[words[i][0]:words[i][1]]
+		if hits[i] {
+			b.WriteString("<b>" + word + "</b>")
+		} else {
+			b.WriteString(word)
+		}
+	}
+	return rank, b.String(), true
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Сводка по данным сервиса для метрик. TendersByStatus содержит все
+// статусы тендера, включая отсутствующие (с нулем). BidsAwaitingDecision —
+// опубликованные предложения к опубликованным тендерам, по которым еще не
+// принято итоговое решение.
+type Stats struct {
+	TendersByStatus      map[TenderStatus]int
+	BidsAwaitingDecision int
+}
+
+func newStats() Stats {
+	stats := Stats{TendersByStatus: map[TenderStatus]int{}}
+	for status := range tenderStatusOrder {
+		stats.TendersByStatus[status] = 0
+	}
+	return stats
+}
+
+func (db *DB) Stats(ctx context.Context) (Stats, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	stats := newStats()
+	rows, err := db.Pool.Query(ctx, `SELECT status, COUNT(*) FROM tenders GROUP BY status`)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error counting tenders", sl.Err(err))
+		return Stats{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			db.Log.ErrorContext(ctx, "Error scanning row", sl.Err(err))
+			return Stats{}, err
+		}
+		stats.TendersByStatus[TenderStatus(status)] = count
+	}
+	if err := rows.Err(); err != nil {
+		db.Log.ErrorContext(ctx, "Error after processing rows", sl.Err(err))
+		return Stats{}, err
+	}
+
+	err = db.Pool.QueryRow(ctx, `
+        SELECT COUNT(*)
+        FROM bids b
+        JOIN tenders t ON t.id = b.tender_id
+        WHERE b.status = 'PUBLISHED' AND t.status = 'PUBLISHED'
+    `).Scan(&stats.BidsAwaitingDecision)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error counting bids awaiting decision", sl.Err(err))
+		return Stats{}, err
+	}
+	return stats, nil
+}
+
+func (m *Memory) Stats(ctx context.Context) (Stats, error) {
+	if err := m.lock(ctx); err != nil {
+		return Stats{}, err
+	}
+	defer m.mu.Unlock()
+
+	stats := newStats()
+	for _, t := range m.tenders {
+		stats.TendersByStatus[TenderStatus(t.Status)]++
+	}
+	for _, b := range m.bids {
+		if BidStatus(b.Status) == BidStatusPublished && TenderStatus(m.tender(b.TenderId).Status) == TenderStatusPublished {
+			stats.BidsAwaitingDecision++
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+)
+
+// Хранилище тендеров, предложений, организаций и сотрудников.
+// Реализации: DB (PostgreSQL) и Memory (в памяти процесса, для тестов и
+// демонстраций). Обе реализации возвращают одинаковые ошибки этого пакета
+// и одинаково проверяют права, статусы и версии. Каждое изменение тендера
+// или предложения записывает доменное событие в outbox атомарно с самим
+// изменением. Операции прерываются при отмене ctx; истечение дедлайна
+// распознается функцией IsTimeout. Списки возвращаются постранично в
+// порядке Page.Sort (см. Page).
+type Store interface {
+	GetTenders(ctx context.Context, serviceTypes []api.TenderServiceType, page Page) ([]api.Tender, PageInfo, error)
+	SearchTenders(ctx context.Context, query string, serviceTypes []api.TenderServiceType, limit int32, offset int32) ([]TenderSearchResult, error)
+	GetUserTenders(ctx context.Context, username string, page Page) ([]api.Tender, PageInfo, error)
+	CreateTender(ctx context.Context, tender api.Tender, creatorUsername string) (api.Tender, error)
+	EditTender(ctx context.Context, tenderId string, name string, description string, serviceType string, username string) (api.Tender, error)
+	RollbackTender(ctx context.Context, tenderId string, version int, username string) (api.Tender, error)
+	GetTenderStatus(ctx context.Context, tenderId string, username string) (string, error)
+	UpdateTenderStatus(ctx context.Context, tenderId string, status api.TenderStatus, username string) (api.Tender, error)
+	GetTenderVersions(ctx context.Context, tenderId string, username string) ([]TenderVersion, error)
+
+	GetUserBids(ctx context.Context, username string, page Page) ([]api.Bid, PageInfo, error)
+	CreateBid(ctx context.Context, bid api.Bid, username string) (api.Bid, error)
+	EditBid(ctx context.Context, bidId string, name *string, description *string, username string) (api.Bid, error)
+	RollbackBid(ctx context.Context, bidId string, version int, username string) (api.Bid, error)
+	GetBidsForTender(ctx context.Context, tenderId string, username string, page Page) ([]api.Bid, PageInfo, error)
+	SearchBids(ctx context.Context, query string, username string, limit int32, offset int32) ([]BidSearchResult, error)
+	GetBidStatus(ctx context.Context, bidId string, username string) (string, error)
+	UpdateBidStatus(ctx context.Context, bidId string, status api.BidStatus, username string) (api.Bid, error)
+	SubmitBidDecision(ctx context.Context, bidId string, decision api.BidDecision, username string) (api.Bid, error)
+	SubmitBidFeedback(ctx context.Context, bidId string, feedback string, username string) (api.Bid, error)
+	GetBidReviews(ctx context.Context, tenderId string, authorUsername string, requesterUsername string, page Page) ([]api.BidReview, PageInfo, error)
+
+	GetEmployeeByUsername(ctx context.Context, username string) (Employee, error)
+	CreateEmployee(ctx context.Context, employee Employee, username string) (Employee, error)
+
+	CreateOrganization(ctx context.Context, org Organization, username string) (Organization, error)
+	GetOrganizations(ctx context.Context, page Page) ([]Organization, PageInfo, error)
+	GetOrganization(ctx context.Context, organizationId string) (Organization, error)
+	UpdateOrganization(ctx context.Context, organizationId string, name *string, description *string, orgType *OrganizationType, username string) (Organization, error)
+	GetOrganizationResponsibles(ctx context.Context, organizationId string) ([]Employee, error)
+	AddOrganizationResponsible(ctx context.Context, organizationId string, employeeUsername string, username string) (Employee, error)
+	RemoveOrganizationResponsible(ctx context.Context, organizationId string, employeeUsername string, username string) error
+
+	// Доставка доменных событий, записанных в outbox вместе с изменениями
+	// тендеров и предложений (см. events.Outbox)
+	ProcessOutbox(ctx context.Context, afterId int64, limit int, handle func(ctx context.Context, batch []events.Event) []int64) (int, error)
+	PruneOutbox(ctx context.Context, retention time.Duration) (int, error)
+
+	// Лента событий тендера из outbox с учетом прав пользователя
+	// (afterId = FromLatestEvent — с последнего события)
+	GetTenderEvents(ctx context.Context, tenderId string, username string, afterId int64, limit int) ([]events.Event, int64, error)
+
+	// Вебхуки организаций и очередь доставки событий им. Доставки
+	// выбираются с арендой lease, чтобы несколько экземпляров сервиса не
+	// отправляли одно событие одновременно.
+	CreateWebhook(ctx context.Context, webhook Webhook, username string) (Webhook, error)
+	GetWebhooks(ctx context.Context, organizationId string, username string) ([]Webhook, error)
+	GetWebhook(ctx context.Context, webhookId string, username string) (Webhook, error)
+	UpdateWebhook(ctx context.Context, webhookId string, update WebhookUpdate, username string) (Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookId string, username string) error
+	GetWebhookDeliveries(ctx context.Context, webhookId string, status WebhookDeliveryStatus, username string, page Page) ([]WebhookDelivery, PageInfo, error)
+	EnqueueWebhookDeliveries(ctx context.Context, e events.Event) (int, error)
+	DueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DueWebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, deliveryId string, attempt WebhookAttempt) (bool, error)
+
+	// Сводка для метрик сервиса
+	Stats(ctx context.Context) (Stats, error)
+
+	// Проверка доступности хранилища для readiness-проверки
+	Ping(ctx context.Context) error
+	Close()
+}
+
+var (
+	_ Store = (*DB)(nil)
+	_ Store = (*Memory)(nil)
+)
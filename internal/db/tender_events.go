@@ -0,0 +1,196 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Начало ленты событий тендера с последнего записанного события: прошлые
+// события не возвращаются
+const FromLatestEvent int64 = -1
+
+// Типы событий ленты тендера: смена статуса тендера, публикация и смена
+// статуса предложений, решения ответственных
+var tenderFeedTypes = []events.Type{events.TenderStatusChanged, events.BidStatusChanged, events.BidDecisionSubmitted}
+
+// Права пользователя на ленту тендера. События видны по тем же правилам,
+// что и в REST: смена статуса тендера — тем, кто видит тендер; предложение —
+// тем, кто видит все предложения тендера, если оно опубликовано или по нему
+// принято решение, и его автору; решения — только тем, кто видит все
+// предложения.
+type tenderFeed struct {
+	viewTender bool
+	seeAll     bool
+	// Идентификаторы пользователя и организаций, за которые он отвечает:
+	// предложения от их имени считаются собственными
+	authors []string
+}
+
+func (f tenderFeed) visible(e events.Event) bool {
+	switch e.Type {
+	case events.TenderStatusChanged:
+		return f.viewTender
+	case events.BidStatusChanged, events.BidDecisionSubmitted:
+		var state struct {
+			Bid api.Bid `json:"bid"`
+		}
+		if err := json.Unmarshal(e.Payload, &state); err != nil {
+			return false
+		}
+		if e.Type == events.BidDecisionSubmitted {
+			return f.seeAll
+		}
+		switch status, _ := ParseBidStatus(state.Bid.Status); status {
+		case BidStatusPublished, BidStatusApproved, BidStatusRejected:
+			if f.seeAll {
+				return true
+			}
+		}
+		return slices.Contains(f.authors, state.Bid.AuthorId)
+	}
+	return false
+}
+
+// Права на ленту тендера. Ленту получает тот, кто видит тендер или его
+// предложения, в том числе только собственные.
+func (db *DB) tenderFeed(ctx context.Context, tenderId string, username string) (tenderFeed, error) {
+	access, err := getTenderAccess(ctx, db.Pool, tenderId, username)
+	if err != nil {
+		return tenderFeed{}, err
+	}
+	feed := tenderFeed{
+		viewTender: db.Policy.Allowed(authz.TenderView, access.Subject, string(access.Status)),
+		seeAll:     db.Policy.Allowed(authz.TenderBids, access.Subject, string(access.Status)),
+		authors:    []string{access.UserId.String()},
+	}
+
+	var hasOwnBids bool
+	query := `
+        SELECT ARRAY(SELECT organization_id::text FROM organization_responsible WHERE user_id = $2),
+            EXISTS(
+                SELECT 1 FROM bids b
+                WHERE b.tender_id = $1
+                AND (b.author_id = $2 OR b.author_id IN (SELECT organization_id FROM organization_responsible WHERE user_id = $2))
+            )
+    `
+	var organizations []string
+	if err := db.Pool.QueryRow(ctx, query, tenderId, access.UserId).Scan(&organizations, &hasOwnBids); err != nil {
+		db.Log.ErrorContext(ctx, "Error checking access to events of tender", slog.String("username", username), slog.String("tender_id", tenderId), sl.Err(err))
+		return tenderFeed{}, err
+	}
+	feed.authors = append(feed.authors, organizations...)
+
+	if !feed.viewTender && !feed.seeAll && !hasOwnBids {
+		return tenderFeed{}, ErrForbidden
+	}
+	return feed, nil
+}
+
+// Лента событий тендера для пользователя: до limit видимых ему событий с Id
+// больше afterId по возрастанию Id. Второе значение — Id последнего
+// просмотренного события, включая невидимые; его передают как afterId
+// следующего вызова. С afterId = FromLatestEvent события не возвращаются,
+// а позиция устанавливается на последнее записанное событие тендера.
+// События берутся из outbox, поэтому ленту можно продолжить с любого
+// ранее полученного события. Id событий выдаются в порядке фиксации
+// транзакций (см. insertEvent), поэтому позже зафиксированное событие не
+// окажется позади позиции ленты.
+func (db *DB) GetTenderEvents(ctx context.Context, tenderId string, username string, afterId int64, limit int) ([]events.Event, int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	feed, err := db.tenderFeed(ctx, tenderId, username)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if afterId == FromLatestEvent {
+		var last int64
+		err := db.Pool.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM outbox WHERE tender_id = $1`, tenderId).Scan(&last)
+		if err != nil {
+			db.Log.ErrorContext(ctx, "Error retrieving last event of tender", slog.String("tender_id", tenderId), sl.Err(err))
+			return nil, 0, err
+		}
+		return []events.Event{}, last, nil
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+        SELECT id, event_type, aggregate_type, aggregate_id, tender_id, payload, created_at
+        FROM outbox
+        WHERE tender_id = $1 AND id > $2
+        ORDER BY id
+        LIMIT $3
+    `, tenderId, afterId, limit)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error retrieving events of tender", slog.String("tender_id", tenderId), sl.Err(err))
+		return nil, 0, err
+	}
+	scanned, err := scanEvents(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := []events.Event{}
+	last := afterId
+	for _, e := range scanned {
+		last = e.Id
+		if slices.Contains(tenderFeedTypes, e.Type) && feed.visible(e) {
+			result = append(result, e)
+		}
+	}
+	return result, last, nil
+}
+
+func (m *Memory) GetTenderEvents(ctx context.Context, tenderId string, username string, afterId int64, limit int) ([]events.Event, int64, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, 0, err
+	}
+	defer m.mu.Unlock()
+
+	access, _, err := m.tenderAccess(tenderId, username)
+	if err != nil {
+		return nil, 0, err
+	}
+	userId := access.UserId.String()
+	feed := tenderFeed{
+		viewTender: m.Policy.Allowed(authz.TenderView, access.Subject, string(access.Status)),
+		seeAll:     m.Policy.Allowed(authz.TenderBids, access.Subject, string(access.Status)),
+		authors:    []string{userId},
+	}
+	if org, ok := m.responsibles[userId]; ok {
+		feed.authors = append(feed.authors, org)
+	}
+	hasOwnBids := slices.ContainsFunc(m.bids, func(b *memBid) bool {
+		return b.TenderId == tenderId && slices.Contains(feed.authors, b.AuthorId)
+	})
+	if !feed.viewTender && !feed.seeAll && !hasOwnBids {
+		return nil, 0, ErrForbidden
+	}
+
+	result := []events.Event{}
+	last, scanned := max(afterId, 0), 0
+	for _, e := range m.eventsAfter(last) {
+		if e.TenderId != tenderId {
+			continue
+		}
+		if afterId == FromLatestEvent {
+			last = e.Id
+			continue
+		}
+		if scanned == limit {
+			break
+		}
+		last, scanned = e.Id, scanned+1
+		if slices.Contains(tenderFeedTypes, e.Type) && feed.visible(e.Event) {
+			result = append(result, e.Event)
+		}
+	}
+	return result, last, nil
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/migrate"
+)
+
+// Переменная окружения со строкой подключения к PostgreSQL для тестов DB.
+// Если она не задана, эти тесты пропускаются.
+const testPostgresEnv = "TEST_POSTGRES_CONN"
+
+// Хранилище DB на тестовой базе с примененными миграциями
+func testDB(t *testing.T) *DB {
+	t.Helper()
+
+	conn := os.Getenv(testPostgresEnv)
+	if conn == "" {
+		t.Skipf("%s is not set", testPostgresEnv)
+	}
+	ctx := context.Background()
+	db, err := NewDB(ctx, conn, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	migrator, err := migrate.New(db.Pool, db.Log)
+	if err != nil {
+		t.Fatalf("migrate.New: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// Ответственный за новую организацию и созданный им тендер
+func seedDBTender(t *testing.T, db *DB) (string, api.Tender) {
+	t.Helper()
+
+	ctx := context.Background()
+	username := "events_" + uuid.NewString()[:8]
+	var organizationId string
+	err := db.Pool.QueryRow(ctx, `
+        WITH e AS (
+            INSERT INTO employee (username, first_name, last_name) VALUES ($1, 'Test', 'User') RETURNING id
+        ), o AS (
+            INSERT INTO organization (name, type) VALUES ($1, 'LLC') RETURNING id
+        )
+        INSERT INTO organization_responsible (organization_id, user_id)
+        SELECT o.id, e.id FROM o, e
+        RETURNING organization_id::text
+    `, username).Scan(&organizationId)
+	if err != nil {
+		t.Fatalf("seeding responsible: %v", err)
+	}
+
+	tender, err := db.CreateTender(ctx, api.Tender{
+		Name:           "Delivery",
+		Description:    "Delivery of goods",
+		OrganizationId: organizationId,
+		ServiceType:    api.Delivery,
+		Status:         api.TenderStatus(TenderStatusCreated),
+		Version:        1,
+	}, username)
+	if err != nil {
+		t.Fatalf("CreateTender: %v", err)
+	}
+	return username, tender
+}
+
+// Транзакция, которая записала событие раньше, но зафиксировалась позже,
+// не должна пропадать из ленты, читаемой по Id
+func TestDBTenderEventsLateCommit(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	username, tender := seedDBTender(t, db)
+	change := events.TenderStatusChange{Tender: tender, PreviousStatus: string(TenderStatusCreated), Username: username}
+
+	_, after, err := db.GetTenderEvents(ctx, tender.Id, username, FromLatestEvent, 100)
+	if err != nil {
+		t.Fatalf("GetTenderEvents: %v", err)
+	}
+
+	early, err := db.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	defer early.Rollback(ctx)
+	if err := db.insertEvent(ctx, early, events.TenderStatusChanged, change); err != nil {
+		t.Fatalf("insertEvent: %v", err)
+	}
+
+	lateDone := make(chan error, 1)
+	go func() {
+		late, err := db.Pool.Begin(ctx)
+		if err != nil {
+			lateDone <- err
+			return
+		}
+		defer late.Rollback(ctx)
+		if err = db.insertEvent(ctx, late, events.TenderStatusChanged, change); err == nil {
+			err = late.Commit(ctx)
+		}
+		lateDone <- err
+	}()
+
+	// Пока первая транзакция не зафиксирована, вторая ждет ее, и в ленте
+	// не появляется событие с большим Id
+	select {
+	case err := <-lateDone:
+		t.Fatalf("late transaction committed before the early one: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	got, next, err := db.GetTenderEvents(ctx, tender.Id, username, after, 100)
+	if err != nil || len(got) != 0 || next != after {
+		t.Fatalf("events before commit = %v, %d, %v; want none", got, next, err)
+	}
+
+	if err := early.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := <-lateDone; err != nil {
+		t.Fatalf("late transaction: %v", err)
+	}
+	got, _, err = db.GetTenderEvents(ctx, tender.Id, username, after, 100)
+	if err != nil || len(got) != 2 || got[0].Id >= got[1].Id {
+		t.Fatalf("events after commit = %v, %v; want both events in commit order", got, err)
+	}
+}
+
+// Лента, читаемая во время параллельных изменений, получает каждое событие
+// ровно один раз
+func TestMemoryTenderEventsConcurrentCommits(t *testing.T) {
+	m, tender, _ := seedMemoryTender(t)
+	ctx := context.Background()
+	const writers = 20
+
+	_, after, err := m.GetTenderEvents(ctx, tender.Id, "alice", FromLatestEvent, 100)
+	if err != nil {
+		t.Fatalf("GetTenderEvents: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range writers {
+		author := m.SeedEmployee(fmt.Sprintf("author_%d", i), "Test", "User")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bid, err := m.CreateBid(ctx, api.Bid{
+				Name:       "Offer",
+				TenderId:   tender.Id,
+				AuthorId:   author.Id,
+				AuthorType: "User",
+				Status:     api.BidStatus(BidStatusCreated),
+				Version:    1,
+			}, "")
+			if err == nil {
+				_, err = m.UpdateBidStatus(ctx, bid.Id, api.BidStatus(BidStatusPublished), author.Username)
+			}
+			if err != nil {
+				t.Errorf("publishing bid of %s: %v", author.Username, err)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	seen := map[int64]bool{}
+	for finished := false; ; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		batch, next, err := m.GetTenderEvents(ctx, tender.Id, "alice", after, 3)
+		if err != nil {
+			t.Fatalf("GetTenderEvents: %v", err)
+		}
+		for _, e := range batch {
+			if seen[e.Id] {
+				t.Errorf("event %d returned twice", e.Id)
+			}
+			seen[e.Id] = true
+		}
+		if finished && next == after {
+			break
+		}
+		after = next
+	}
+	if len(seen) != writers {
+		t.Errorf("feed returned %d events, want %d", len(seen), writers)
+	}
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+)
+
+// Статус тендера в том виде, в котором он хранится в базе (enum tender_status)
+type TenderStatus string
+
+const (
+	TenderStatusCreated   TenderStatus = "CREATED"
+	TenderStatusPublished TenderStatus = "PUBLISHED"
+	TenderStatusClosed    TenderStatus = "CLOSED"
+)
+
+// Порядок статусов в жизненном цикле тендера
+var tenderStatusOrder = map[TenderStatus]int{
+	TenderStatusCreated:   0,
+	TenderStatusPublished: 1,
+	TenderStatusClosed:    2,
+}
+
+var (
+	ErrInvalidTenderStatus     = newError(KindValidation, "invalid tender status")
+	ErrInvalidTenderTransition = newError(KindConflict, "invalid tender status transition")
+)
+
+// Переход между статусами тендера
+type TenderTransition struct {
+	From TenderStatus
+	To   TenderStatus
+}
+
+func (t TenderTransition) String() string {
+	return fmt.Sprintf("%s:%s", t.From, t.To)
+}
+
+// Ошибка недопустимого перехода между статусами тендера
+type TenderTransitionError struct {
+	From TenderStatus
+	To   TenderStatus
+}
+
+func (e *TenderTransitionError) Error() string {
+	return fmt.Sprintf("tender status cannot be changed from %s to %s", e.From, e.To)
+}
+
+func (e *TenderTransitionError) Unwrap() error {
+	return ErrInvalidTenderTransition
+}
+
+// Машина состояний тендера: CREATED -> PUBLISHED -> CLOSED и
+// дополнительно разрешенные обратные переходы. Каждому переходу
+// сопоставлено действие политики доступа, которое проверяется для пользователя.
+type TenderStateMachine struct {
+	transitions map[TenderTransition]authz.Action
+}
+
+// Создает машину состояний тендера. backTransitions - разрешенные
+// переходы к более раннему статусу, например PUBLISHED -> CREATED.
+func NewTenderStateMachine(backTransitions ...TenderTransition) (*TenderStateMachine, error) {
+	m := &TenderStateMachine{
+		transitions: map[TenderTransition]authz.Action{
+			{From: TenderStatusCreated, To: TenderStatusPublished}: authz.TenderPublish,
+			{From: TenderStatusPublished, To: TenderStatusClosed}:  authz.TenderClose,
+		},
+	}
+
+	for _, t := range backTransitions {
+		from, fromOk := tenderStatusOrder[t.From]
+		to, toOk := tenderStatusOrder[t.To]
+		if !fromOk || !toOk {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTenderStatus, t)
+		}
+		if to >= from {
+			return nil, fmt.Errorf("%s is not a back transition", t)
+		}
+		m.transitions[t] = authz.TenderRevert
+	}
+
+	return m, nil
+}
+
+// Разбирает список переходов вида "PUBLISHED:CREATED,CLOSED:PUBLISHED"
+func ParseTenderTransitions(s string) ([]TenderTransition, error) {
+	var transitions []TenderTransition
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid tender transition %q, expected FROM:TO", part)
+		}
+		fromStatus, err := ParseTenderStatus(api.TenderStatus(from))
+		if err != nil {
+			return nil, err
+		}
+		toStatus, err := ParseTenderStatus(api.TenderStatus(to))
+		if err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, TenderTransition{From: fromStatus, To: toStatus})
+	}
+	return transitions, nil
+}
+
+// Приводит статус из API (Published) или из базы (PUBLISHED) к TenderStatus
+func ParseTenderStatus(status api.TenderStatus) (TenderStatus, error) {
+	s := TenderStatus(strings.ToUpper(strings.TrimSpace(string(status))))
+	if _, ok := tenderStatusOrder[s]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrInvalidTenderStatus, status)
+	}
+	return s, nil
+}
+
+// Возвращает действие политики доступа для перехода или ошибку, если переход недопустим
+func (m *TenderStateMachine) Transition(from TenderStatus, to TenderStatus) (authz.Action, error) {
+	action, ok := m.transitions[TenderTransition{From: from, To: to}]
+	if !ok {
+		return "", &TenderTransitionError{From: from, To: to}
+	}
+	return action, nil
+}
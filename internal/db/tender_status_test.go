@@ -0,0 +1,71 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+)
+
+func TestTenderStateMachineDefaults(t *testing.T) {
+	m, err := NewTenderStateMachine()
+	if err != nil {
+		t.Fatalf("NewTenderStateMachine: %v", err)
+	}
+
+	if _, err := m.Transition(TenderStatusCreated, TenderStatusPublished); err != nil {
+		t.Errorf("CREATED -> PUBLISHED: unexpected error %v", err)
+	}
+	if _, err := m.Transition(TenderStatusPublished, TenderStatusClosed); err != nil {
+		t.Errorf("PUBLISHED -> CLOSED: unexpected error %v", err)
+	}
+	if _, err := m.Transition(TenderStatusClosed, TenderStatusPublished); !errors.Is(err, ErrInvalidTenderTransition) {
+		t.Errorf("CLOSED -> PUBLISHED: error = %v, want ErrInvalidTenderTransition", err)
+	}
+	if _, err := m.Transition(TenderStatusCreated, TenderStatusClosed); !errors.Is(err, ErrInvalidTenderTransition) {
+		t.Errorf("CREATED -> CLOSED: error = %v, want ErrInvalidTenderTransition", err)
+	}
+}
+
+func TestTenderStateMachineBackTransitions(t *testing.T) {
+	transitions, err := ParseTenderTransitions("Closed:Published, PUBLISHED:CREATED")
+	if err != nil {
+		t.Fatalf("ParseTenderTransitions: %v", err)
+	}
+
+	m, err := NewTenderStateMachine(transitions...)
+	if err != nil {
+		t.Fatalf("NewTenderStateMachine: %v", err)
+	}
+
+	action, err := m.Transition(TenderStatusClosed, TenderStatusPublished)
+	if err != nil {
+		t.Fatalf("CLOSED -> PUBLISHED: unexpected error %v", err)
+	}
+	if action != authz.TenderRevert {
+		t.Errorf("CLOSED -> PUBLISHED: action = %s, want %s", action, authz.TenderRevert)
+	}
+
+	if _, err := NewTenderStateMachine(TenderTransition{From: TenderStatusCreated, To: TenderStatusClosed}); err == nil {
+		t.Error("forward transition must not be accepted as a back transition")
+	}
+
+	if _, err := ParseTenderTransitions("CLOSED-PUBLISHED"); err == nil {
+		t.Error("malformed transition must be rejected")
+	}
+}
+
+func TestTransitionErrorKind(t *testing.T) {
+	m, err := NewTenderStateMachine()
+	if err != nil {
+		t.Fatalf("NewTenderStateMachine: %v", err)
+	}
+
+	_, err = m.Transition(TenderStatusClosed, TenderStatusPublished)
+	if kind := KindOf(err); kind != KindConflict {
+		t.Errorf("KindOf(%v) = %d, want KindConflict", err, kind)
+	}
+	if kind := KindOf(errors.New("connection reset")); kind != KindInternal {
+		t.Errorf("KindOf(unexpected error) = %d, want KindInternal", kind)
+	}
+}
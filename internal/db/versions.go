@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Типы изменений, фиксируемые в истории тендера
+const (
+	TenderChangeCreated    = "CREATED"
+	TenderChangeEdited     = "EDITED"
+	TenderChangeStatus     = "STATUS_CHANGED"
+	TenderChangeRolledBack = "ROLLED_BACK"
+	// Версия, с которой начата история тендера, созданного до ее появления
+	TenderChangeImported = "IMPORTED"
+)
+
+// Типы изменений, фиксируемые в истории предложения
+const (
+	BidChangeCreated    = "CREATED"
+	BidChangeEdited     = "EDITED"
+	BidChangeStatus     = "STATUS_CHANGED"
+	BidChangeRolledBack = "ROLLED_BACK"
+	// Версия, с которой начата история предложения, созданного до ее появления
+	BidChangeImported = "IMPORTED"
+)
+
+// Снимок тендера на момент конкретной версии
+type TenderVersion struct {
+	TenderId    string `json:"tenderId"`
+	Version     int32  `json:"version"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ServiceType string `json:"serviceType"`
+	Status      string `json:"status"`
+	ChangedBy   string `json:"changedBy"`
+	ChangeType  string `json:"changeType"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+// Сохраняет текущее состояние тендера в историю версий.
+// Вызывается в той же транзакции, что и изменение тендера.
+func (db *DB) insertTenderVersion(ctx context.Context, tx pgx.Tx, tenderId string, changedBy string, changeType string) error {
+	query := `
+        INSERT INTO tender_versions (tender_id, version, name, description, service_type, status, changed_by, change_type)
+        SELECT id, version, name, description, service_type, status, $2, $3
+        FROM tenders
+        WHERE id = $1
+    `
+	_, err := tx.Exec(ctx, query, tenderId, changedBy, changeType)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error saving version of tender", slog.String("tender_id", tenderId), sl.Err(err))
+		return err
+	}
+	return nil
+}
+
+// Сохраняет текущее состояние предложения в историю версий.
+// Пустой changedBy означает, что автор изменения не известен по username.
+func (db *DB) insertBidVersion(ctx context.Context, tx pgx.Tx, bidId string, changedBy string, changeType string) error {
+	query := `
+        INSERT INTO bid_versions (bid_id, version, name, description, status, changed_by, change_type)
+        SELECT id, version, name, description, status, NULLIF($2, ''), $3
+        FROM bids
+        WHERE id = $1
+    `
+	_, err := tx.Exec(ctx, query, bidId, changedBy, changeType)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error saving version of bid", slog.String("bid_id", bidId), sl.Err(err))
+		return err
+	}
+	return nil
+}
+
+// Получение всей истории версий тендера
+func (db *DB) GetTenderVersions(ctx context.Context, tenderId string, username string) ([]TenderVersion, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	db.Log.DebugContext(ctx, "Checking permission to view history of tender", slog.String("username", username), slog.String("tender_id", tenderId))
+	if _, err := db.authorizeTender(ctx, db.Pool, tenderId, username, authz.TenderHistory); err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT tender_id, version, name, description, service_type, status, changed_by, change_type, created_at
+        FROM tender_versions
+        WHERE tender_id = $1
+        ORDER BY version
+    `
+	rows, err := db.Pool.Query(ctx, query, tenderId)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error executing query", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []TenderVersion{}
+	for rows.Next() {
+		var v TenderVersion
+		var createdAt time.Time
+
+		err := rows.Scan(
+			&v.TenderId,
+			&v.Version,
+			&v.Name,
+			&v.Description,
+			&v.ServiceType,
+			&v.Status,
+			&v.ChangedBy,
+			&v.ChangeType,
+			&createdAt,
+		)
+		if err != nil {
+			db.Log.ErrorContext(ctx, "Error scanning row", sl.Err(err))
+			return nil, err
+		}
+
+		v.CreatedAt = createdAt.Format(time.RFC3339)
+		versions = append(versions, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		db.Log.ErrorContext(ctx, "Error after processing rows", sl.Err(err))
+		return nil, err
+	}
+
+	db.Log.DebugContext(ctx, "Successfully retrieved versions of tender", slog.Int("count", len(versions)), slog.String("tender_id", tenderId))
+	return versions, nil
+}
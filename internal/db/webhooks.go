@@ -0,0 +1,866 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/authz"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/netguard"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+var (
+	ErrWebhookNotFound        = newError(KindNotFound, "webhook not found")
+	ErrInvalidWebhookURL      = newError(KindValidation, "url must be an absolute http or https URL of at most 2000 characters")
+	ErrWebhookURLNotPublic    = newError(KindValidation, "url must point to a public address")
+	ErrInvalidEventType       = newError(KindValidation, "unknown event type")
+	ErrInvalidDeliveryStatus  = newError(KindValidation, "invalid delivery status")
+	ErrWebhookDeliveryMissing = newError(KindNotFound, "webhook delivery not found")
+)
+
+// Максимальная длина адреса вебхука (столбец webhooks.url)
+const maxWebhookURLLength = 2000
+
+// Состояние доставки события вебхуку (enum webhook_delivery_status)
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "FAILED"
+)
+
+func ParseWebhookDeliveryStatus(s string) (WebhookDeliveryStatus, error) {
+	switch status := WebhookDeliveryStatus(s); status {
+	case WebhookDeliveryPending, WebhookDeliveryDelivered, WebhookDeliveryFailed:
+		return status, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrInvalidDeliveryStatus, s)
+}
+
+// Вебхук организации. Secret возвращается только при создании. Events —
+// типы событий, которые получает вебхук; пустой список означает все.
+// Вебхук отключается вручную или автоматически после серии неудачных
+// доставок; DisabledAt — время отключения.
+type Webhook struct {
+	Id                  string        `json:"id"`
+	OrganizationId      string        `json:"organizationId"`
+	URL                 string        `json:"url"`
+	Events              []events.Type `json:"events"`
+	Enabled             bool          `json:"enabled"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+	DisabledAt          string        `json:"disabledAt,omitempty"`
+	Secret              string        `json:"secret,omitempty"`
+	CreatedAt           string        `json:"createdAt"`
+}
+
+// Изменение вебхука, незаданные поля не меняются. Включение вебхука
+// сбрасывает счетчик неудачных доставок.
+type WebhookUpdate struct {
+	URL     *string
+	Events  *[]events.Type
+	Enabled *bool
+}
+
+// Запись журнала доставки события вебхуку с итогом последней попытки
+type WebhookDelivery struct {
+	Id             string                `json:"id"`
+	WebhookId      string                `json:"webhookId"`
+	EventId        int64                 `json:"eventId"`
+	EventType      events.Type           `json:"eventType"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseStatus int                   `json:"responseStatus,omitempty"`
+	Error          string                `json:"error,omitempty"`
+	NextAttemptAt  string                `json:"nextAttemptAt,omitempty"`
+	DeliveredAt    string                `json:"deliveredAt,omitempty"`
+	CreatedAt      string                `json:"createdAt"`
+}
+
+// Доставка, которую пора выполнить. Event — тело запроса, событие в
+// формате JSON; Attempts — число уже выполненных попыток.
+type DueWebhookDelivery struct {
+	Id        string
+	WebhookId string
+	URL       string
+	Secret    string
+	EventId   int64
+	EventType events.Type
+	Event     []byte
+	Attempts  int
+}
+
+// Итог попытки доставки. Пустой Error означает успешную доставку. При
+// неудаче RetryAfter — пауза до следующей попытки, ноль — попытки
+// исчерпаны. Вебхук отключается, когда число неудач подряд достигает
+// DisableAfter (ноль — не отключать).
+type WebhookAttempt struct {
+	ResponseStatus int
+	Error          string
+	RetryAfter     time.Duration
+	DisableAfter   int
+}
+
+// Проверяет адрес и типы событий вебхука. Адрес должен указывать на
+// публичный хост или на разрешенный списком allowed.
+func validateWebhook(allowed *netguard.Allowlist, rawURL *string, types *[]events.Type) error {
+	if rawURL != nil {
+		u, err := url.Parse(*rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(*rawURL) > maxWebhookURLLength {
+			return ErrInvalidWebhookURL
+		}
+		if err := allowed.CheckURL(u); err != nil {
+			return fmt.Errorf("%w: %s", ErrWebhookURLNotPublic, u.Hostname())
+		}
+	}
+	if types != nil {
+		for _, t := range *types {
+			if !slices.Contains(events.Types, t) {
+				return fmt.Errorf("%w: %s", ErrInvalidEventType, t)
+			}
+		}
+	}
+	return nil
+}
+
+// Секрет подписи вебхука
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+// Организации, вебхуки которых получают событие. События тендера получает
+// организация тендера. События предложения она получает, только когда
+// видит предложение в списке предложений тендера; организация-автор
+// получает события своего предложения, кроме решений и отзывов
+// ответственных, которые ей не показываются.
+func webhookAudience(e events.Event, tenderOrganizationId string) []string {
+	if e.AggregateType != events.AggregateBid {
+		return []string{tenderOrganizationId}
+	}
+	var state struct {
+		Bid api.Bid `json:"bid"`
+	}
+	if err := json.Unmarshal(e.Payload, &state); err != nil {
+		return nil
+	}
+
+	var organizations []string
+	switch status, _ := ParseBidStatus(state.Bid.Status); status {
+	case BidStatusPublished, BidStatusApproved, BidStatusRejected:
+		organizations = append(organizations, tenderOrganizationId)
+	}
+	authorEvent := e.Type != events.BidDecisionSubmitted && e.Type != events.BidFeedbackSubmitted
+	if authorEvent && state.Bid.AuthorType == "ORGANIZATION" && state.Bid.AuthorId != tenderOrganizationId {
+		organizations = append(organizations, state.Bid.AuthorId)
+	}
+	return organizations
+}
+
+func eventTypeStrings(types []events.Type) []string {
+	result := make([]string, len(types))
+	for i, t := range types {
+		result[i] = string(t)
+	}
+	return result
+}
+
+const webhookColumns = "w.id, w.organization_id, w.url, w.event_types, w.enabled, w.consecutive_failures, w.disabled_at, w.created_at"
+
+func scanWebhook(row pgx.Row) (Webhook, error) {
+	var w Webhook
+	var types []string
+	var disabledAt *time.Time
+	var createdAt time.Time
+	err := row.Scan(&w.Id, &w.OrganizationId, &w.URL, &types, &w.Enabled, &w.ConsecutiveFailures, &disabledAt, &createdAt)
+	if err != nil {
+		return Webhook{}, err
+	}
+	w.Events = []events.Type{}
+	for _, t := range types {
+		w.Events = append(w.Events, events.Type(t))
+	}
+	if disabledAt != nil {
+		w.DisabledAt = disabledAt.Format(time.RFC3339)
+	}
+	w.CreatedAt = createdAt.Format(time.RFC3339)
+	return w, nil
+}
+
+// Вебхук и проверка права пользователя управлять вебхуками его организации
+func (db *DB) authorizeWebhook(ctx context.Context, q querier, webhookId string, username string) (Webhook, error) {
+	w, err := scanWebhook(q.QueryRow(ctx, "SELECT "+webhookColumns+" FROM webhooks w WHERE w.id::text = $1", webhookId))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return Webhook{}, ErrWebhookNotFound
+		}
+		db.Log.ErrorContext(ctx, "Error retrieving webhook", slog.String("webhook_id", webhookId), sl.Err(err))
+		return Webhook{}, err
+	}
+	if err := db.authorizeOrganization(ctx, q, w.OrganizationId, username, authz.OrganizationWebhooks); err != nil {
+		return Webhook{}, err
+	}
+	return w, nil
+}
+
+// Регистрация вебхука организации ответственным за нее
+func (db *DB) CreateWebhook(ctx context.Context, webhook Webhook, username string) (Webhook, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if err := validateWebhook(db.WebhookHosts, &webhook.URL, &webhook.Events); err != nil {
+		return Webhook{}, err
+	}
+	if err := db.authorizeOrganization(ctx, db.Pool, webhook.OrganizationId, username, authz.OrganizationWebhooks); err != nil {
+		return Webhook{}, err
+	}
+
+	secret := newWebhookSecret()
+	query := `
+        INSERT INTO webhooks AS w (organization_id, url, event_types, secret, created_by)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING ` + webhookColumns
+	created, err := scanWebhook(db.Pool.QueryRow(ctx, query,
+		webhook.OrganizationId, webhook.URL, eventTypeStrings(webhook.Events), secret, username))
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error creating webhook", sl.Err(err))
+		return Webhook{}, err
+	}
+	created.Secret = secret
+
+	db.Log.InfoContext(ctx, "Webhook created", slog.String("webhook_id", created.Id), slog.String("organization_id", created.OrganizationId), slog.String("username", username))
+	return created, nil
+}
+
+// Вебхуки организации
+func (db *DB) GetWebhooks(ctx context.Context, organizationId string, username string) ([]Webhook, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if err := db.authorizeOrganization(ctx, db.Pool, organizationId, username, authz.OrganizationWebhooks); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Pool.Query(ctx, "SELECT "+webhookColumns+" FROM webhooks w WHERE w.organization_id = $1 ORDER BY w.created_at, w.id", organizationId)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error executing query", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			db.Log.ErrorContext(ctx, "Error scanning row", sl.Err(err))
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	if err := rows.Err(); err != nil {
+		db.Log.ErrorContext(ctx, "Error after processing rows", sl.Err(err))
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (db *DB) GetWebhook(ctx context.Context, webhookId string, username string) (Webhook, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.authorizeWebhook(ctx, db.Pool, webhookId, username)
+}
+
+func (db *DB) UpdateWebhook(ctx context.Context, webhookId string, update WebhookUpdate, username string) (Webhook, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if err := validateWebhook(db.WebhookHosts, update.URL, update.Events); err != nil {
+		return Webhook{}, err
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
+		return Webhook{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := db.authorizeWebhook(ctx, tx, webhookId, username); err != nil {
+		return Webhook{}, err
+	}
+
+	var types []string
+	if update.Events != nil {
+		types = eventTypeStrings(*update.Events)
+	}
+	query := `
+        UPDATE webhooks w
+        SET url = COALESCE($1, url),
+            event_types = COALESCE($2::text[], event_types),
+            enabled = COALESCE($3::boolean, enabled),
+            consecutive_failures = CASE WHEN $3::boolean THEN 0 ELSE consecutive_failures END,
+            disabled_at = CASE
+                WHEN $3::boolean THEN NULL
+                WHEN NOT $3::boolean THEN COALESCE(disabled_at, CURRENT_TIMESTAMP)
+                ELSE disabled_at
+            END,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $4
+        RETURNING ` + webhookColumns
+	updated, err := scanWebhook(tx.QueryRow(ctx, query, update.URL, types, update.Enabled, webhookId))
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error updating webhook", slog.String("webhook_id", webhookId), sl.Err(err))
+		return Webhook{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return Webhook{}, err
+	}
+
+	db.Log.InfoContext(ctx, "Webhook updated", slog.String("webhook_id", webhookId), slog.String("username", username))
+	return updated, nil
+}
+
+// Удаление вебхука вместе с журналом доставки
+func (db *DB) DeleteWebhook(ctx context.Context, webhookId string, username string) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := db.authorizeWebhook(ctx, db.Pool, webhookId, username); err != nil {
+		return err
+	}
+	if _, err := db.Pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, webhookId); err != nil {
+		db.Log.ErrorContext(ctx, "Error deleting webhook", slog.String("webhook_id", webhookId), sl.Err(err))
+		return err
+	}
+
+	db.Log.InfoContext(ctx, "Webhook deleted", slog.String("webhook_id", webhookId), slog.String("username", username))
+	return nil
+}
+
+// Страница журнала доставки вебхука. Пустой status — доставки в любом
+// состоянии.
+func (db *DB) GetWebhookDeliveries(ctx context.Context, webhookId string, status WebhookDeliveryStatus, username string, page Page) ([]WebhookDelivery, PageInfo, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := db.authorizeWebhook(ctx, db.Pool, webhookId, username); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	return queryPage(ctx, db.Pool, pageQuery{
+		columns: `d.id, d.webhook_id, d.event_id, d.event_type, d.status, d.attempts, d.last_response_status,
+            d.last_error, d.next_attempt_at, d.delivered_at, d.created_at`,
+		from: `
+        FROM webhook_deliveries d
+        WHERE d.webhook_id = $1 AND ($2 = '' OR d.status::text = $2)`,
+		args: []interface{}{webhookId, string(status)},
+		sort: sortColumns{SortByCreatedAt: {expr: "d.created_at", typ: "timestamp"}},
+		id:   "d.id",
+	}, page, func(rows pgx.Rows, key *string) (WebhookDelivery, string, error) {
+		var d WebhookDelivery
+		var responseStatus *int
+		var lastError *string
+		var nextAttemptAt, deliveredAt *time.Time
+		var createdAt time.Time
+
+		err := rows.Scan(&d.Id, &d.WebhookId, &d.EventId, &d.EventType, &d.Status, &d.Attempts, &responseStatus,
+			&lastError, &nextAttemptAt, &deliveredAt, &createdAt, key)
+		if responseStatus != nil {
+			d.ResponseStatus = *responseStatus
+		}
+		if lastError != nil {
+			d.Error = *lastError
+		}
+		if nextAttemptAt != nil && d.Status == WebhookDeliveryPending {
+			d.NextAttemptAt = nextAttemptAt.Format(time.RFC3339)
+		}
+		if deliveredAt != nil {
+			d.DeliveredAt = deliveredAt.Format(time.RFC3339)
+		}
+		d.CreatedAt = createdAt.Format(time.RFC3339)
+		return d, d.Id, err
+	})
+}
+
+// Ставит событие в очередь доставки включенным вебхукам организаций,
+// которым оно видно. Повторный вызов для того же события ничего не
+// добавляет. Возвращает число новых доставок.
+func (db *DB) EnqueueWebhookDeliveries(ctx context.Context, e events.Event) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var tenderOrganizationId string
+	err := db.Pool.QueryRow(ctx, `SELECT organization_id FROM tenders WHERE id = $1`, e.TenderId).Scan(&tenderOrganizationId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, nil
+		}
+		db.Log.ErrorContext(ctx, "Error retrieving tender", slog.String("tender_id", e.TenderId), sl.Err(err))
+		return 0, err
+	}
+	organizations := webhookAudience(e, tenderOrganizationId)
+	if len(organizations) == 0 {
+		return 0, nil
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+	tag, err := db.Pool.Exec(ctx, `
+        INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, event)
+        SELECT id, $1::bigint, $2::text, $3::jsonb
+        FROM webhooks
+        WHERE enabled AND organization_id::text = ANY($4)
+        AND (cardinality(event_types) = 0 OR $2::text = ANY(event_types))
+        ON CONFLICT (webhook_id, event_id) DO NOTHING
+    `, e.Id, string(e.Type), body, organizations)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error enqueuing webhook deliveries for event", slog.Int64("event_id", e.Id), sl.Err(err))
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// Выбирает до limit доставок включенных вебхуков, время попытки которых
+// наступило, и откладывает их на lease, чтобы другие экземпляры сервиса
+// не отправили их одновременно. Итог попытки записывает RecordWebhookAttempt.
+func (db *DB) DueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DueWebhookDelivery, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.Pool.Query(ctx, `
+        UPDATE webhook_deliveries d
+        SET next_attempt_at = CURRENT_TIMESTAMP + $2::bigint * interval '1 millisecond'
+        FROM webhooks w
+        WHERE w.id = d.webhook_id AND d.id IN (
+            SELECT due.id
+            FROM webhook_deliveries due
+            JOIN webhooks dw ON dw.id = due.webhook_id
+            WHERE due.status = 'PENDING' AND due.next_attempt_at <= CURRENT_TIMESTAMP AND dw.enabled
+            ORDER BY due.next_attempt_at, due.event_id
+            LIMIT $1
+            FOR UPDATE OF due SKIP LOCKED
+        )
+        RETURNING d.id, d.webhook_id, w.url, w.secret, d.event_id, d.event_type, d.event, d.attempts
+    `, limit, lease.Milliseconds())
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error selecting due webhook deliveries", sl.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []DueWebhookDelivery
+	for rows.Next() {
+		var d DueWebhookDelivery
+		if err := rows.Scan(&d.Id, &d.WebhookId, &d.URL, &d.Secret, &d.EventId, &d.EventType, &d.Event, &d.Attempts); err != nil {
+			db.Log.ErrorContext(ctx, "Error scanning row", sl.Err(err))
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	if err := rows.Err(); err != nil {
+		db.Log.ErrorContext(ctx, "Error after processing rows", sl.Err(err))
+		return nil, err
+	}
+	return due, nil
+}
+
+// Итоговый статус доставки после попытки
+func (a WebhookAttempt) status() WebhookDeliveryStatus {
+	switch {
+	case a.Error == "":
+		return WebhookDeliveryDelivered
+	case a.RetryAfter > 0:
+		return WebhookDeliveryPending
+	}
+	return WebhookDeliveryFailed
+}
+
+// Счетчик неудач подряд после попытки и признак того, что попытка
+// отключает включенный вебхук
+func (a WebhookAttempt) apply(enabled bool, failures int) (int, bool) {
+	if a.Error == "" {
+		return 0, false
+	}
+	failures++
+	return failures, enabled && a.DisableAfter > 0 && failures >= a.DisableAfter
+}
+
+// Записывает итог попытки доставки и обновляет счетчик неудач вебхука.
+// Возвращает true, если вебхук отключен из-за этой неудачи.
+func (db *DB) RecordWebhookAttempt(ctx context.Context, deliveryId string, attempt WebhookAttempt) (bool, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error starting transaction", sl.Err(err))
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	status := attempt.status()
+	var webhookId string
+	err = tx.QueryRow(ctx, `
+        UPDATE webhook_deliveries
+        SET attempts = attempts + 1,
+            status = $2,
+            last_response_status = NULLIF($3, 0),
+            last_error = NULLIF($4, ''),
+            next_attempt_at = CURRENT_TIMESTAMP + $5::bigint * interval '1 millisecond',
+            delivered_at = CASE WHEN $2 = 'DELIVERED' THEN CURRENT_TIMESTAMP END,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1
+        RETURNING webhook_id
+    `, deliveryId, string(status), attempt.ResponseStatus, attempt.Error, attempt.RetryAfter.Milliseconds()).Scan(&webhookId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, ErrWebhookDeliveryMissing
+		}
+		db.Log.ErrorContext(ctx, "Error recording attempt of webhook delivery", slog.String("delivery_id", deliveryId), sl.Err(err))
+		return false, err
+	}
+
+	var enabled bool
+	var failures int
+	err = tx.QueryRow(ctx, `SELECT enabled, consecutive_failures FROM webhooks WHERE id = $1 FOR UPDATE`, webhookId).Scan(&enabled, &failures)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error retrieving webhook", slog.String("webhook_id", webhookId), sl.Err(err))
+		return false, err
+	}
+	failures, disabled := attempt.apply(enabled, failures)
+	_, err = tx.Exec(ctx, `
+        UPDATE webhooks
+        SET consecutive_failures = $2,
+            enabled = enabled AND NOT $3,
+            disabled_at = CASE WHEN $3 THEN CURRENT_TIMESTAMP ELSE disabled_at END,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1
+    `, webhookId, failures, disabled)
+	if err != nil {
+		db.Log.ErrorContext(ctx, "Error updating failures of webhook", slog.String("webhook_id", webhookId), sl.Err(err))
+		return false, err
+	}
+	if disabled {
+		db.Log.InfoContext(ctx, "Webhook disabled after consecutive failures", slog.String("webhook_id", webhookId), slog.Int("failures", failures))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		db.Log.ErrorContext(ctx, "Error committing transaction", sl.Err(err))
+		return false, err
+	}
+	return disabled, nil
+}
+
+type memWebhook struct {
+	Webhook
+	secret string
+}
+
+type memWebhookDelivery struct {
+	WebhookDelivery
+	event         []byte
+	nextAttemptAt time.Time
+	createdAt     time.Time
+}
+
+// Вебхук Memory и проверка права пользователя управлять им
+func (m *Memory) authorizeWebhook(webhookId string, username string) (*memWebhook, error) {
+	for _, w := range m.webhooks {
+		if w.Id == webhookId {
+			if err := m.authorizeOrganization(w.OrganizationId, username, authz.OrganizationWebhooks); err != nil {
+				return nil, err
+			}
+			return w, nil
+		}
+	}
+	return nil, ErrWebhookNotFound
+}
+
+func (m *Memory) CreateWebhook(ctx context.Context, webhook Webhook, username string) (Webhook, error) {
+	if err := validateWebhook(m.WebhookHosts, &webhook.URL, &webhook.Events); err != nil {
+		return Webhook{}, err
+	}
+	if err := m.lock(ctx); err != nil {
+		return Webhook{}, err
+	}
+	defer m.mu.Unlock()
+
+	if err := m.authorizeOrganization(webhook.OrganizationId, username, authz.OrganizationWebhooks); err != nil {
+		return Webhook{}, err
+	}
+
+	w := &memWebhook{
+		Webhook: Webhook{
+			Id:             uuid.NewString(),
+			OrganizationId: webhook.OrganizationId,
+			URL:            webhook.URL,
+			Events:         append([]events.Type{}, webhook.Events...),
+			Enabled:        true,
+			CreatedAt:      memNow(),
+		},
+		secret: newWebhookSecret(),
+	}
+	m.webhooks = append(m.webhooks, w)
+
+	created := w.Webhook
+	created.Secret = w.secret
+	return created, nil
+}
+
+func (m *Memory) GetWebhooks(ctx context.Context, organizationId string, username string) ([]Webhook, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	if err := m.authorizeOrganization(organizationId, username, authz.OrganizationWebhooks); err != nil {
+		return nil, err
+	}
+
+	webhooks := []Webhook{}
+	for _, w := range m.webhooks {
+		if w.OrganizationId == organizationId {
+			webhooks = append(webhooks, w.Webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (m *Memory) GetWebhook(ctx context.Context, webhookId string, username string) (Webhook, error) {
+	if err := m.lock(ctx); err != nil {
+		return Webhook{}, err
+	}
+	defer m.mu.Unlock()
+
+	w, err := m.authorizeWebhook(webhookId, username)
+	if err != nil {
+		return Webhook{}, err
+	}
+	return w.Webhook, nil
+}
+
+func (m *Memory) UpdateWebhook(ctx context.Context, webhookId string, update WebhookUpdate, username string) (Webhook, error) {
+	if err := validateWebhook(m.WebhookHosts, update.URL, update.Events); err != nil {
+		return Webhook{}, err
+	}
+	if err := m.lock(ctx); err != nil {
+		return Webhook{}, err
+	}
+	defer m.mu.Unlock()
+
+	w, err := m.authorizeWebhook(webhookId, username)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	if update.URL != nil {
+		w.URL = *update.URL
+	}
+	if update.Events != nil {
+		w.Events = append([]events.Type{}, *update.Events...)
+	}
+	if update.Enabled != nil {
+		switch {
+		case *update.Enabled:
+			w.ConsecutiveFailures = 0
+			w.DisabledAt = ""
+		case w.DisabledAt == "":
+			w.DisabledAt = memNow()
+		}
+		w.Enabled = *update.Enabled
+	}
+	return w.Webhook, nil
+}
+
+func (m *Memory) DeleteWebhook(ctx context.Context, webhookId string, username string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if _, err := m.authorizeWebhook(webhookId, username); err != nil {
+		return err
+	}
+	m.webhooks = slices.DeleteFunc(m.webhooks, func(w *memWebhook) bool { return w.Id == webhookId })
+	m.deliveries = slices.DeleteFunc(m.deliveries, func(d *memWebhookDelivery) bool { return d.WebhookId == webhookId })
+	return nil
+}
+
+func (m *Memory) GetWebhookDeliveries(ctx context.Context, webhookId string, status WebhookDeliveryStatus, username string, page Page) ([]WebhookDelivery, PageInfo, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, PageInfo{}, err
+	}
+	defer m.mu.Unlock()
+
+	if _, err := m.authorizeWebhook(webhookId, username); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	var deliveries []*memWebhookDelivery
+	for _, d := range m.deliveries {
+		if d.WebhookId == webhookId && (status == "" || d.Status == status) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	result, info, err := memSortedPage(deliveries, page, func(d *memWebhookDelivery) memSortKey {
+		return memSortKey{id: d.Id, createdAt: d.createdAt}
+	})
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	items := []WebhookDelivery{}
+	for _, d := range result {
+		item := d.WebhookDelivery
+		if d.Status == WebhookDeliveryPending {
+			item.NextAttemptAt = d.nextAttemptAt.Format(time.RFC3339)
+		}
+		items = append(items, item)
+	}
+	return items, info, nil
+}
+
+func (m *Memory) EnqueueWebhookDeliveries(ctx context.Context, e events.Event) (int, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mu.Unlock()
+
+	t := m.tender(e.TenderId)
+	if t == nil {
+		return 0, nil
+	}
+	organizations := webhookAudience(e, t.OrganizationId)
+	body, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	added := 0
+	for _, w := range m.webhooks {
+		if !w.Enabled || !slices.Contains(organizations, w.OrganizationId) {
+			continue
+		}
+		if len(w.Events) > 0 && !slices.Contains(w.Events, e.Type) {
+			continue
+		}
+		exists := slices.ContainsFunc(m.deliveries, func(d *memWebhookDelivery) bool {
+			return d.WebhookId == w.Id && d.EventId == e.Id
+		})
+		if exists {
+			continue
+		}
+		m.deliveries = append(m.deliveries, &memWebhookDelivery{
+			WebhookDelivery: WebhookDelivery{
+				Id:        uuid.NewString(),
+				WebhookId: w.Id,
+				EventId:   e.Id,
+				EventType: e.Type,
+				Status:    WebhookDeliveryPending,
+				CreatedAt: now.Format(time.RFC3339),
+			},
+			event:         body,
+			nextAttemptAt: now,
+			createdAt:     now,
+		})
+		added++
+	}
+	return added, nil
+}
+
+func (m *Memory) DueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DueWebhookDelivery, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var due []*memWebhookDelivery
+	for _, d := range m.deliveries {
+		w := m.webhookById(d.WebhookId)
+		if d.Status == WebhookDeliveryPending && !d.nextAttemptAt.After(now) && w != nil && w.Enabled {
+			due = append(due, d)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b *memWebhookDelivery) int {
+		return cmp.Or(a.nextAttemptAt.Compare(b.nextAttemptAt), cmp.Compare(a.EventId, b.EventId))
+	})
+
+	var result []DueWebhookDelivery
+	for _, d := range due[:min(limit, len(due))] {
+		w := m.webhookById(d.WebhookId)
+		d.nextAttemptAt = now.Add(lease)
+		result = append(result, DueWebhookDelivery{
+			Id:        d.Id,
+			WebhookId: d.WebhookId,
+			URL:       w.URL,
+			Secret:    w.secret,
+			EventId:   d.EventId,
+			EventType: d.EventType,
+			Event:     d.event,
+			Attempts:  d.Attempts,
+		})
+	}
+	return result, nil
+}
+
+func (m *Memory) webhookById(id string) *memWebhook {
+	for _, w := range m.webhooks {
+		if w.Id == id {
+			return w
+		}
+	}
+	return nil
+}
+
+func (m *Memory) RecordWebhookAttempt(ctx context.Context, deliveryId string, attempt WebhookAttempt) (bool, error) {
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.deliveries, func(d *memWebhookDelivery) bool { return d.Id == deliveryId })
+	if i < 0 {
+		return false, ErrWebhookDeliveryMissing
+	}
+	d := m.deliveries[i]
+	now := time.Now().UTC()
+	d.Attempts++
+	d.Status = attempt.status()
+	d.ResponseStatus = attempt.ResponseStatus
+	d.Error = attempt.Error
+	d.nextAttemptAt = now.Add(attempt.RetryAfter)
+	if d.Status == WebhookDeliveryDelivered {
+		d.DeliveredAt = now.Format(time.RFC3339)
+	}
+
+	w := m.webhookById(d.WebhookId)
+	failures, disabled := attempt.apply(w.Enabled, w.ConsecutiveFailures)
+	w.ConsecutiveFailures = failures
+	if disabled {
+		w.Enabled = false
+		w.DisabledAt = now.Format(time.RFC3339)
+	}
+	return disabled, nil
+}
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Хранилище событий, ожидающих доставки
+type Outbox interface {
+	// Передает handle до limit недоставленных событий с Id больше afterId
+	// по возрастанию Id и отмечает доставленными события с Id из
+	// результата handle. Возвращает число переданных событий. Параллельные
+	// вызовы не получают одни и те же события одновременно.
+	ProcessOutbox(ctx context.Context, afterId int64, limit int, handle func(ctx context.Context, batch []Event) []int64) (int, error)
+	// Удаляет события, доставленные раньше, чем retention назад, и
+	// возвращает их число
+	PruneOutbox(ctx context.Context, retention time.Duration) (int, error)
+}
+
+// Значения по умолчанию для Dispatcher
+const (
+	DefaultInterval  = time.Second
+	DefaultBatchSize = 100
+)
+
+// Период удаления старых доставленных событий
+const pruneInterval = time.Hour
+
+// Периодически доставляет события из Outbox во все Sink. Событие
+// считается доставленным, когда его приняли все получатели; иначе оно
+// доставляется повторно на следующем проходе, в том числе тем
+// получателям, которые его уже приняли. Пока событие объекта не
+// доставлено, следующие события того же объекта не отправляются.
+// Доставленные события хранятся Retention (для ленты событий тендера) и
+// затем удаляются; ноль — хранить всегда.
+type Dispatcher struct {
+	Outbox    Outbox
+	Sinks     []Sink
+	Interval  time.Duration
+	BatchSize int
+	Retention time.Duration
+	Log       *slog.Logger
+}
+
+// Доставляет события до отмены ctx
+func (d *Dispatcher) Run(ctx context.Context) {
+	interval := d.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		if _, err := d.Flush(ctx); err != nil && ctx.Err() == nil {
+			d.Log.Error("Failed to dispatch events", slog.String("error", err.Error()))
+		}
+		if d.Retention > 0 && time.Since(pruned) >= pruneInterval {
+			pruned = time.Now()
+			if n, err := d.Outbox.PruneOutbox(ctx, d.Retention); err != nil && ctx.Err() == nil {
+				d.Log.Error("Failed to prune delivered events", slog.String("error", err.Error()))
+			} else if n > 0 {
+				d.Log.Info("Pruned delivered events", slog.Int("count", n))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Выполняет один проход по outbox до последнего записанного события.
+// Объект, событие которого не доставлено, блокируется до конца прохода,
+// а события других объектов продолжают доставляться. Возвращает число
+// доставленных событий.
+func (d *Dispatcher) Flush(ctx context.Context) (int, error) {
+	batchSize := d.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	total := 0
+	var after int64
+	blocked := map[string]bool{}
+	for {
+		n, err := d.Outbox.ProcessOutbox(ctx, after, batchSize, func(ctx context.Context, batch []Event) []int64 {
+			after = batch[len(batch)-1].Id
+			ids := d.deliver(ctx, batch, blocked)
+			total += len(ids)
+			return ids
+		})
+		if err != nil {
+			return total, err
+		}
+		if n < batchSize || ctx.Err() != nil {
+			return total, nil
+		}
+	}
+}
+
+// Доставляет пакет и возвращает Id доставленных событий
+func (d *Dispatcher) deliver(ctx context.Context, batch []Event, blocked map[string]bool) []int64 {
+	var delivered []int64
+	for _, e := range batch {
+		key := e.aggregateKey()
+		if blocked[key] {
+			continue
+		}
+		if err := d.deliverEvent(ctx, e); err != nil {
+			blocked[key] = true
+			continue
+		}
+		delivered = append(delivered, e.Id)
+	}
+	return delivered
+}
+
+func (d *Dispatcher) deliverEvent(ctx context.Context, e Event) error {
+	for _, sink := range d.Sinks {
+		if err := sink.Deliver(ctx, e); err != nil {
+			d.Log.Warn("Failed to deliver event",
+				slog.String("sink", sink.Name()),
+				slog.Int64("id", e.Id),
+				slog.String("type", string(e.Type)),
+				slog.String("error", err.Error()),
+			)
+			return err
+		}
+	}
+	return nil
+}
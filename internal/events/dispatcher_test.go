@@ -0,0 +1,168 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+)
+
+// Outbox в памяти для проверки Dispatcher
+type testOutbox struct {
+	mu        sync.Mutex
+	events    []Event
+	delivered map[int64]bool
+}
+
+func (o *testOutbox) add(typ Type, payload Payload) {
+	e, err := New(typ, payload)
+	if err != nil {
+		panic(err)
+	}
+	e.Id = int64(len(o.events) + 1)
+	o.events = append(o.events, e)
+}
+
+func (o *testOutbox) ProcessOutbox(ctx context.Context, afterId int64, limit int, handle func(ctx context.Context, batch []Event) []int64) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var batch []Event
+	for _, e := range o.events {
+		if e.Id > afterId && !o.delivered[e.Id] && len(batch) < limit {
+			batch = append(batch, e)
+		}
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	for _, id := range handle(ctx, batch) {
+		o.delivered[id] = true
+	}
+	return len(batch), nil
+}
+
+func (o *testOutbox) PruneOutbox(ctx context.Context, retention time.Duration) (int, error) {
+	return 0, nil
+}
+
+// Получатель, который запоминает события и отклоняет события из fail
+type testSink struct {
+	got  []int64
+	fail map[int64]bool
+}
+
+func (s *testSink) Name() string {
+	return "test"
+}
+
+func (s *testSink) Deliver(ctx context.Context, e Event) error {
+	if s.fail[e.Id] {
+		return errors.New("unavailable")
+	}
+	s.got = append(s.got, e.Id)
+	return nil
+}
+
+func TestDispatcherOrderPerAggregate(t *testing.T) {
+	outbox := &testOutbox{delivered: map[int64]bool{}}
+	first := api.Tender{Id: "tender-1"}
+	second := api.Tender{Id: "tender-2"}
+	outbox.add(TenderCreated, TenderChange{Tender: first})  // 1
+	outbox.add(TenderCreated, TenderChange{Tender: second}) // 2
+	outbox.add(TenderEdited, TenderChange{Tender: first})   // 3
+	outbox.add(BidCreated, BidChange{Bid: api.Bid{Id: "bid-1", TenderId: first.Id}})
+	outbox.add(TenderEdited, TenderChange{Tender: second}) // 5
+
+	sink := &testSink{fail: map[int64]bool{1: true}}
+	d := &Dispatcher{Outbox: outbox, Sinks: []Sink{sink}, BatchSize: 2, Log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	// Пока событие 1 не доставлено, событие 3 того же тендера ждет;
+	// события других объектов доставляются
+	n, err := d.Flush(context.Background())
+	if err != nil || n != 3 || !slices.Equal(sink.got, []int64{2, 4, 5}) {
+		t.Fatalf("first Flush = %d, %v, delivered %v; want 3 events 2, 4, 5", n, err, sink.got)
+	}
+
+	sink.fail = nil
+	n, err = d.Flush(context.Background())
+	if err != nil || n != 2 || !slices.Equal(sink.got, []int64{2, 4, 5, 1, 3}) {
+		t.Fatalf("second Flush = %d, %v, delivered %v; want 1, 3 after retry", n, err, sink.got)
+	}
+	if n, err := d.Flush(context.Background()); err != nil || n != 0 {
+		t.Errorf("Flush of empty outbox = %d, %v", n, err)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var got Event
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Event-Type") != string(BidCreated) {
+			t.Errorf("X-Event-Type = %q", r.Header.Get("X-Event-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	e, err := New(BidCreated, BidChange{Bid: api.Bid{Id: "bid-1", TenderId: "tender-1", Name: "Offer"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Id = 7
+
+	sink := NewHTTPSink(srv.URL)
+	if err := sink.Deliver(context.Background(), e); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	var payload BidChange
+	if err := got.Decode(&payload); err != nil || got.Id != 7 || got.TenderId != "tender-1" || payload.Bid.Name != "Offer" {
+		t.Errorf("received %+v, payload %+v, %v", got, payload, err)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := sink.Deliver(context.Background(), e); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Deliver to failing receiver: error = %v, want status 503", err)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := int64(1); id <= 2; id++ {
+		e, _ := New(TenderCreated, TenderChange{Tender: api.Tender{Id: "tender-1"}})
+		e.Id = id
+		if err := sink.Deliver(context.Background(), e); err != nil {
+			t.Fatalf("Deliver: %v", err)
+		}
+	}
+	sink.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var last Event
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[1]), &last) != nil || last.Id != 2 || last.AggregateType != AggregateTender {
+		t.Errorf("file contents = %q", data)
+	}
+}
@@ -0,0 +1,154 @@
+// Package events описывает доменные события тендеров и предложений и их
+// доставку внешним получателям. Хранилище записывает событие в outbox в
+// той же транзакции, что и изменение данных, а Dispatcher доставляет
+// записанные события в подключенные Sink.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+)
+
+// Тип события
+type Type string
+
+const (
+	TenderCreated       Type = "tender.created"
+	TenderEdited        Type = "tender.edited"
+	TenderRolledBack    Type = "tender.rolled_back"
+	TenderStatusChanged Type = "tender.status_changed"
+
+	BidCreated           Type = "bid.created"
+	BidEdited            Type = "bid.edited"
+	BidRolledBack        Type = "bid.rolled_back"
+	BidStatusChanged     Type = "bid.status_changed"
+	BidDecisionSubmitted Type = "bid.decision_submitted"
+	BidFeedbackSubmitted Type = "bid.feedback_submitted"
+)
+
+// Все типы событий
+var Types = []Type{
+	TenderCreated, TenderEdited, TenderRolledBack, TenderStatusChanged,
+	BidCreated, BidEdited, BidRolledBack, BidStatusChanged, BidDecisionSubmitted, BidFeedbackSubmitted,
+}
+
+// Объект, к которому относится событие. События одного объекта
+// доставляются в порядке записи.
+type AggregateType string
+
+const (
+	AggregateTender AggregateType = "tender"
+	AggregateBid    AggregateType = "bid"
+)
+
+// Событие в outbox. Id возрастает в порядке записи, TenderId — тендер
+// объекта (для событий тендера совпадает с AggregateId).
+type Event struct {
+	Id            int64           `json:"id"`
+	Type          Type            `json:"type"`
+	AggregateType AggregateType   `json:"aggregateType"`
+	AggregateId   string          `json:"aggregateId"`
+	TenderId      string          `json:"tenderId"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+// Данные события. Тип данных определяет объект события.
+type Payload interface {
+	aggregate() (AggregateType, string, string)
+}
+
+// Создание, правка или откат тендера
+type TenderChange struct {
+	Tender   api.Tender `json:"tender"`
+	Username string     `json:"username"`
+}
+
+// Изменение статуса тендера. Tender содержит новое состояние.
+type TenderStatusChange struct {
+	Tender         api.Tender `json:"tender"`
+	PreviousStatus string     `json:"previousStatus"`
+	Username       string     `json:"username"`
+}
+
+// Создание, правка или откат предложения. При создании Username пустой:
+// автор задается идентификатором в Bid.
+type BidChange struct {
+	Bid      api.Bid `json:"bid"`
+	Username string  `json:"username,omitempty"`
+}
+
+// Изменение статуса предложения автором или по решениям ответственных
+type BidStatusChange struct {
+	Bid            api.Bid `json:"bid"`
+	PreviousStatus string  `json:"previousStatus"`
+	Username       string  `json:"username"`
+}
+
+// Решение ответственного по предложению. Bid содержит состояние после
+// учета решения.
+type BidDecision struct {
+	Bid      api.Bid `json:"bid"`
+	Decision string  `json:"decision"`
+	Username string  `json:"username"`
+}
+
+// Отзыв ответственного на предложение
+type BidFeedback struct {
+	Bid      api.Bid `json:"bid"`
+	Feedback string  `json:"feedback"`
+	Username string  `json:"username"`
+}
+
+func (p TenderChange) aggregate() (AggregateType, string, string) {
+	return AggregateTender, p.Tender.Id, p.Tender.Id
+}
+
+func (p TenderStatusChange) aggregate() (AggregateType, string, string) {
+	return AggregateTender, p.Tender.Id, p.Tender.Id
+}
+
+func (p BidChange) aggregate() (AggregateType, string, string) {
+	return AggregateBid, p.Bid.Id, p.Bid.TenderId
+}
+
+func (p BidStatusChange) aggregate() (AggregateType, string, string) {
+	return AggregateBid, p.Bid.Id, p.Bid.TenderId
+}
+
+func (p BidDecision) aggregate() (AggregateType, string, string) {
+	return AggregateBid, p.Bid.Id, p.Bid.TenderId
+}
+
+func (p BidFeedback) aggregate() (AggregateType, string, string) {
+	return AggregateBid, p.Bid.Id, p.Bid.TenderId
+}
+
+// Создает событие для записи в outbox. Id и CreatedAt назначает хранилище.
+func New(typ Type, payload Payload) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("marshal %s event: %w", typ, err)
+	}
+	aggregateType, aggregateId, tenderId := payload.aggregate()
+	return Event{
+		Type:          typ,
+		AggregateType: aggregateType,
+		AggregateId:   aggregateId,
+		TenderId:      tenderId,
+		Payload:       data,
+	}, nil
+}
+
+// Разбирает данные события в v
+func (e Event) Decode(v Payload) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// Ключ объекта события для упорядочивания доставки
+func (e Event) aggregateKey() string {
+	return string(e.AggregateType) + ":" + e.AggregateId
+}
@@ -0,0 +1,119 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Получатель событий. Deliver может вызываться повторно для уже
+// доставленного события (доставка "хотя бы один раз"), получатель
+// отличает повторы по Event.Id.
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, e Event) error
+}
+
+// Записывает события в журнал приложения без данных события
+type LogSink struct {
+	Log *slog.Logger
+}
+
+func (s LogSink) Name() string {
+	return "log"
+}
+
+func (s LogSink) Deliver(ctx context.Context, e Event) error {
+	s.Log.InfoContext(ctx, "Domain event",
+		slog.Int64("id", e.Id),
+		slog.String("type", string(e.Type)),
+		slog.String("aggregate", e.aggregateKey()),
+		slog.String("tender_id", e.TenderId),
+	)
+	return nil
+}
+
+// Дописывает события в файл, по одному JSON-объекту на строку
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Name() string {
+	return "file"
+}
+
+func (s *FileSink) Deliver(ctx context.Context, e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// Таймаут доставки одного события по HTTP по умолчанию
+const defaultHTTPTimeout = 10 * time.Second
+
+// Отправляет каждое событие POST-запросом с телом в формате JSON. Ответ с
+// кодом вне 2xx считается ошибкой доставки.
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{URL: url, Client: &http.Client{Timeout: defaultHTTPTimeout}}
+}
+
+func (s *HTTPSink) Name() string {
+	return "http"
+}
+
+func (s *HTTPSink) Deliver(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", fmt.Sprint(e.Id))
+	req.Header.Set("X-Event-Type", string(e.Type))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s responded with status %d", s.URL, resp.StatusCode)
+	}
+	return nil
+}
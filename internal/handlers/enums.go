@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"strings"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+)
+
+// Статусы и тип автора хранятся в верхнем регистре (PUBLISHED), а в
+// спецификации записаны с заглавной буквы (Published). Ответы приводятся
+// к записи спецификации, значения из запросов разбираются без учета регистра.
+func specEnum(v string) string {
+	if v == "" {
+		return v
+	}
+	return v[:1] + strings.ToLower(v[1:])
+}
+
+func specTender(t api.Tender) api.Tender {
+	t.Status = api.TenderStatus(specEnum(string(t.Status)))
+	return t
+}
+
+func specTenders(tenders []api.Tender) []api.Tender {
+	for i := range tenders {
+		tenders[i] = specTender(tenders[i])
+	}
+	return tenders
+}
+
+func specBid(b api.Bid) api.Bid {
+	b.Status = api.BidStatus(specEnum(string(b.Status)))
+	b.AuthorType = api.BidAuthorType(specEnum(string(b.AuthorType)))
+	return b
+}
+
+func specBids(bids []api.Bid) []api.Bid {
+	for i := range bids {
+		bids[i] = specBid(bids[i])
+	}
+	return bids
+}
+
+func specTenderResults(results []db.TenderSearchResult) []db.TenderSearchResult {
+	for i := range results {
+		results[i].Tender = specTender(results[i].Tender)
+	}
+	return results
+}
+
+func specBidResults(results []db.BidSearchResult) []db.BidSearchResult {
+	for i := range results {
+		results[i].Bid = specBid(results[i].Bid)
+	}
+	return results
+}
+
+func specTenderVersions(versions []db.TenderVersion) []db.TenderVersion {
+	for i := range versions {
+		versions[i].Status = specEnum(versions[i].Status)
+	}
+	return versions
+}
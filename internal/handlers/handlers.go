@@ -3,29 +3,39 @@ package handlers
 import (
 	_ "database/sql"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
 
 	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/auth"
 	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
-	_ "git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
 )
 
 type MyServer struct {
-	Database *db.DB
+	Database db.Store
+	Log      *slog.Logger
+	// Закрывается при остановке сервера и завершает потоки событий
+	Stopping <-chan struct{}
 }
 
 var _ api.ServerInterface = (*MyServer)(nil)
 
-func NewServer(storage *db.DB) *MyServer {
+func NewServer(storage db.Store, log *slog.Logger) *MyServer {
 	return &MyServer{
 		Database: storage,
+		Log:      log,
 	}
 }
 
 // Проверка доступности сервера
 // (GET /ping)
 func (s *MyServer) CheckServer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("ok"))
 }
@@ -33,26 +43,42 @@ func (s *MyServer) CheckServer(w http.ResponseWriter, r *http.Request) {
 // Получение списка тендеров
 // (GET /tenders)
 func (s *MyServer) GetTenders(w http.ResponseWriter, r *http.Request, params api.GetTendersParams) {
-	if params.Limit != nil && *params.Limit < 0 {
-		http.Error(w, `{"error": "invalid limit parameter"}`, http.StatusBadRequest)
+	page, err := parsePage(r.URL.Query(), db.TenderSortFields, defaultSort)
+	if err != nil {
+		writeErrorReason(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if params.Offset != nil && *params.Offset < 0 {
-		http.Error(w, `{"error": "invalid offset parameter"}`, http.StatusBadRequest)
+	var serviceTypes []api.TenderServiceType
+	if params.ServiceType != nil {
+		serviceTypes = *params.ServiceType
+	}
+
+	// Параметра q нет в спецификации: с ним список упорядочен по
+	// релевантности и дополнен рангом и фрагментом текста
+	if q := r.URL.Query().Get("q"); q != "" {
+		if page.Cursor != nil || r.URL.Query().Get("sort") != "" {
+			writeErrorReason(w, http.StatusBadRequest, "search results are ordered by relevance and paginated by offset")
+			return
+		}
+		results, err := s.Database.SearchTenders(r.Context(), q, serviceTypes, page.Limit, page.Offset)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		s.writeJSON(w, r, specTenderResults(results))
 		return
 	}
 
-	tenders, err := s.Database.GetTenders(params)
+	tenders, info, err := s.Database.GetTenders(r.Context(), serviceTypes, page)
 	if err != nil {
-		log.Printf("Error fetching tenders: %v", err)
-		http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+		s.writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(tenders)
+	writePageInfo(w, info)
+	s.writeJSON(w, r, specTenders(tenders))
 }
 
 // Получить тендеры пользователя
@@ -60,32 +86,24 @@ func (s *MyServer) GetTenders(w http.ResponseWriter, r *http.Request, params api
 func (s *MyServer) GetUserTenders(w http.ResponseWriter, r *http.Request, params api.GetUserTendersParams) {
 	username := r.URL.Query().Get("username")
 	if username == "" {
-		http.Error(w, `{"error": "username is required"}`, http.StatusUnauthorized)
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
 		return
 	}
 
-	var limit, offset int32
-	if params.Limit != nil {
-		limit = *params.Limit
-	} else {
-		limit = 10
-	}
-
-	if params.Offset != nil {
-		offset = *params.Offset
-	} else {
-		offset = 0
+	page, err := parsePage(r.URL.Query(), db.TenderSortFields, defaultSort)
+	if err != nil {
+		writeErrorReason(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	tenders, err := s.Database.GetUserTenders(username, limit, offset)
+	tenders, info, err := s.Database.GetUserTenders(r.Context(), username, page)
 	if err != nil {
-		http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+		s.writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(tenders)
+	writePageInfo(w, info)
+	s.writeJSON(w, r, specTenders(tenders))
 }
 
 // Создание нового тендера
@@ -103,14 +121,23 @@ func (s *MyServer) CreateTender(w http.ResponseWriter, r *http.Request) {
 	var request CreateTenderRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		log.Printf("Error decoding request body: %v", err)
-		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		s.Log.InfoContext(r.Context(), "Invalid request body", sl.Err(err))
+		writeErrorReason(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
+	// Автором тендера при наличии токена всегда считается аутентифицированный пользователь
+	if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+		if request.CreatorUsername != "" && request.CreatorUsername != identity.Username {
+			writeErrorReason(w, http.StatusForbidden, "creatorUsername does not match the authenticated user")
+			return
+		}
+		request.CreatorUsername = identity.Username
+	}
+
 	if request.Name == "" || request.Description == "" || request.ServiceType == "" || request.OrganizationId == "" || request.CreatorUsername == "" {
-		log.Println("Error: missing required fields in request body")
-		http.Error(w, `{"error": "missing required fields"}`, http.StatusBadRequest)
+		s.Log.InfoContext(r.Context(), "Missing required fields in request body")
+		writeErrorReason(w, http.StatusBadRequest, "missing required fields")
 		return
 	}
 
@@ -124,25 +151,21 @@ func (s *MyServer) CreateTender(w http.ResponseWriter, r *http.Request) {
 		Version:        1,
 	}
 
-	log.Printf("Creating tender: %v", request.CreatorUsername)
-	createdTender, err := s.Database.CreateTender(newTender, request.CreatorUsername)
+	s.Log.DebugContext(r.Context(), "Creating tender", slog.String("username", request.CreatorUsername))
+	createdTender, err := s.Database.CreateTender(r.Context(), newTender, request.CreatorUsername)
 	if err != nil {
-		log.Printf("Error creating tender: %v", err)
-		http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+		s.writeError(w, r, err)
 		return
 	}
 
-	log.Printf("Tender created successfully: %v", createdTender)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(createdTender)
+	s.writeJSON(w, r, specTender(createdTender))
 }
 
 // Редактирование тендера
 // (PATCH /tenders/{tenderId}/edit)
 func (s *MyServer) EditTender(w http.ResponseWriter, r *http.Request, tenderId api.TenderId, params api.EditTenderParams) {
 	if tenderId == "" || params.Username == "" {
-		http.Error(w, `{"error": "tenderId and username are required"}`, http.StatusBadRequest)
+		writeErrorReason(w, http.StatusBadRequest, "tenderId and username are required")
 		return
 	}
 
@@ -153,161 +176,143 @@ func (s *MyServer) EditTender(w http.ResponseWriter, r *http.Request, tenderId a
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		writeErrorReason(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	updatedTender, err := s.Database.EditTender(tenderId, updates.Name, updates.Description, updates.ServiceType, params.Username)
+	updatedTender, err := s.Database.EditTender(r.Context(), tenderId, updates.Name, updates.Description, updates.ServiceType, params.Username)
 	if err != nil {
-		if err == db.ErrForbidden {
-			http.Error(w, `{"error": "forbidden"}`, http.StatusForbidden)
-		} else if err == db.ErrTenderNotFound {
-			http.Error(w, `{"error": "tender not found"}`, http.StatusNotFound)
-		} else if err == db.ErrUserNotFound {
-			http.Error(w, `{"error": "user not found"}`, http.StatusNotFound)
-		} else {
-			http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
-		}
+		s.writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(updatedTender); err != nil {
-		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
-	}
+	s.writeJSON(w, r, specTender(updatedTender))
 }
 
 // Откат версии тендера
 // (PUT /tenders/{tenderId}/rollback/{version})
 func (s *MyServer) RollbackTender(w http.ResponseWriter, r *http.Request, tenderId api.TenderId, version int32, params api.RollbackTenderParams) {
 	if tenderId == "" || version < 1 || params.Username == "" {
-		http.Error(w, `{"error": "tenderId, version, and username are required"}`, http.StatusBadRequest)
+		writeErrorReason(w, http.StatusBadRequest, "tenderId, version, and username are required")
 		return
 	}
 
-	updatedTender, err := s.Database.RollbackTender(string(tenderId), int(version), params.Username)
+	updatedTender, err := s.Database.RollbackTender(r.Context(), string(tenderId), int(version), params.Username)
 	if err != nil {
-		switch err {
-		case db.ErrForbidden:
-			http.Error(w, `{"error": "forbidden"}`, http.StatusForbidden)
-		case db.ErrTenderNotFound:
-			http.Error(w, `{"error": "tender not found"}`, http.StatusNotFound)
-		case db.ErrUserNotFound:
-			http.Error(w, `{"error": "user not found"}`, http.StatusNotFound)
-		default:
-			http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
-		}
+		s.writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(updatedTender); err != nil {
-		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+	s.writeJSON(w, r, specTender(updatedTender))
+}
+
+// Получение истории версий тендера
+// (GET /tenders/{tenderId}/versions)
+func (s *MyServer) GetTenderVersions(w http.ResponseWriter, r *http.Request) {
+	tenderId := chi.URLParam(r, "tenderId")
+	username := r.URL.Query().Get("username")
+	if tenderId == "" || username == "" {
+		writeErrorReason(w, http.StatusBadRequest, "tenderId and username are required")
+		return
+	}
+
+	versions, err := s.Database.GetTenderVersions(r.Context(), tenderId, username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
 	}
+
+	s.writeJSON(w, r, specTenderVersions(versions))
 }
 
 // Получение текущего статуса тендера
 // (GET /tenders/{tenderId}/status)
 func (s *MyServer) GetTenderStatus(w http.ResponseWriter, r *http.Request, tenderId api.TenderId, params api.GetTenderStatusParams) {
-	if tenderId == "" || *params.Username == "" {
-		http.Error(w, `{"error": "tenderId and username are required"}`, http.StatusBadRequest)
+	if tenderId == "" || params.Username == nil || *params.Username == "" {
+		writeErrorReason(w, http.StatusBadRequest, "tenderId and username are required")
 		return
 	}
 
-	status, err := s.Database.GetTenderStatus(tenderId, *params.Username)
+	status, err := s.Database.GetTenderStatus(r.Context(), tenderId, *params.Username)
 	if err != nil {
-		if err == db.ErrForbidden {
-			http.Error(w, `{"error": "forbidden"}`, http.StatusForbidden)
-		} else if err == db.ErrTenderNotFound {
-			http.Error(w, `{"error": "tender not found"}`, http.StatusNotFound)
-		} else if err == db.ErrUserNotFound {
-			http.Error(w, `{"error": "user not found"}`, http.StatusUnauthorized)
-		} else {
-			http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
-		}
+		s.writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(status); err != nil {
-		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
-	}
+	s.writeJSON(w, r, specEnum(status))
 }
 
 // Изменение статуса тендера
 // (PUT /tenders/{tenderId}/status)
 func (s *MyServer) UpdateTenderStatus(w http.ResponseWriter, r *http.Request, tenderId api.TenderId, params api.UpdateTenderStatusParams) {
 	if tenderId == "" || params.Status == "" || params.Username == "" {
-		http.Error(w, `{"error": "tenderId, status, and username are required"}`, http.StatusBadRequest)
+		writeErrorReason(w, http.StatusBadRequest, "tenderId, status, and username are required")
 		return
 	}
 
-	updatedTender, err := s.Database.UpdateTenderStatus(tenderId, params.Status, params.Username)
+	updatedTender, err := s.Database.UpdateTenderStatus(r.Context(), tenderId, params.Status, params.Username)
 	if err != nil {
-		if err == db.ErrForbidden {
-			http.Error(w, `{"error": "forbidden"}`, http.StatusForbidden)
-		} else if err == db.ErrTenderNotFound {
-			http.Error(w, `{"error": "tender not found"}`, http.StatusNotFound)
-		} else if err == db.ErrUserNotFound {
-			http.Error(w, `{"error": "user not found"}`, http.StatusUnauthorized)
-		} else {
-			http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
-		}
+		s.writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(updatedTender); err != nil {
-		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
-	}
+	s.writeJSON(w, r, specTender(updatedTender))
 }
 
 // Получение списка ваших предложений
 // (GET /bids/my)
 func (s *MyServer) GetUserBids(w http.ResponseWriter, r *http.Request, params api.GetUserBidsParams) {
-	if *params.Username == "" {
-		http.Error(w, `{"error": "username is required"}`, http.StatusBadRequest)
+	if params.Username == nil || *params.Username == "" {
+		writeErrorReason(w, http.StatusBadRequest, "username is required")
 		return
 	}
 
-	var limit, offset int32
-	if params.Limit != nil {
-		limit = *params.Limit
-	} else {
-		limit = 10
+	page, err := parsePage(r.URL.Query(), db.BidSortFields, defaultSort)
+	if err != nil {
+		writeErrorReason(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	if params.Offset != nil {
-		offset = *params.Offset
-	} else {
-		offset = 0
+	bids, info, err := s.Database.GetUserBids(r.Context(), *params.Username, page)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writePageInfo(w, info)
+	s.writeJSON(w, r, specBids(bids))
+}
+
+// Полнотекстовый поиск предложений по тендерам организации пользователя
+// и по его собственным предложениям
+// (GET /bids/search)
+func (s *MyServer) SearchBids(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeErrorReason(w, http.StatusBadRequest, "q is required")
+		return
 	}
 
-	bids, err := s.Database.GetUserBids(limit, offset, *params.Username)
+	// Результаты упорядочены по релевантности, курсор и сортировка не
+	// поддерживаются
+	page, err := parsePage(r.URL.Query(), nil, defaultSort)
 	if err != nil {
-		if err == db.ErrForbidden {
-			http.Error(w, `{"error": "forbidden"}`, http.StatusForbidden)
-		} else if err == db.ErrUserNotFound {
-			http.Error(w, `{"error": "user not found"}`, http.StatusUnauthorized)
-		} else {
-			http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
-		}
+		writeErrorReason(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	response, err := json.Marshal(bids)
+	results, err := s.Database.SearchBids(r.Context(), q, username, page.Limit, page.Offset)
 	if err != nil {
-		http.Error(w, `{"error": "error marshalling response"}`, http.StatusInternalServerError)
+		s.writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(response)
+	s.writeJSON(w, r, specBidResults(results))
 }
 
 // Создание нового предложения
@@ -324,20 +329,31 @@ func (s *MyServer) CreateBid(w http.ResponseWriter, r *http.Request) {
 	var request CreateBidRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		log.Printf("Error decoding request body: %v", err)
-		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		s.Log.InfoContext(r.Context(), "Invalid request body", sl.Err(err))
+		writeErrorReason(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
+	// Предложение от пользователя при наличии токена создается только от его
+	// имени, от организации - только ответственным за нее (проверяет хранилище)
+	if identity, ok := auth.IdentityFromContext(r.Context()); ok && strings.EqualFold(request.AuthorType, "USER") {
+		if request.AuthorId != "" && request.AuthorId != identity.UserId {
+			writeErrorReason(w, http.StatusForbidden, "authorId does not match the authenticated user")
+			return
+		}
+		request.AuthorId = identity.UserId
+	}
+
 	if request.Name == "" || request.Description == "" || request.TenderId == "" || request.AuthorId == "" || request.AuthorType == "" {
-		log.Println("Error: missing required fields in request body")
-		http.Error(w, `{"error": "missing required fields"}`, http.StatusBadRequest)
+		s.Log.InfoContext(r.Context(), "Missing required fields in request body")
+		writeErrorReason(w, http.StatusBadRequest, "missing required fields")
 		return
 	}
 
-	authorType := api.BidAuthorType(request.AuthorType)
+	// Спецификация перечисляет типы автора как User и Organization
+	authorType := api.BidAuthorType(strings.ToUpper(request.AuthorType))
 	if authorType != "USER" && authorType != "ORGANIZATION" {
-		http.Error(w, `{"error": "invalid author type"}`, http.StatusBadRequest)
+		writeErrorReason(w, http.StatusBadRequest, "invalid author type")
 		return
 	}
 
@@ -347,68 +363,193 @@ func (s *MyServer) CreateBid(w http.ResponseWriter, r *http.Request) {
 		TenderId:    api.TenderId(request.TenderId),
 		AuthorId:    api.BidAuthorId(request.AuthorId),
 		AuthorType:  authorType,
-		Status:      "CREATED",
+		Status:      api.BidStatus(db.BidStatusCreated),
 		Version:     1,
 	}
 
-	log.Printf("Creating bid: %v", request.AuthorId)
-	createdBid, err := s.Database.CreateBid(newBid)
+	s.Log.DebugContext(r.Context(), "Creating bid", slog.String("author_id", request.AuthorId))
+	// Создатель предложения: пользователь из токена или необязательный
+	// параметр username. Без него предложение пользователя создает автор.
+	createdBid, err := s.Database.CreateBid(r.Context(), newBid, r.URL.Query().Get("username"))
 	if err != nil {
-		log.Printf("Error creating bid: %v", err)
-		http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+		s.writeError(w, r, err)
 		return
 	}
 
-	log.Printf("Bid created successfully: %v", createdBid)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(createdBid)
+	s.writeJSON(w, r, specBid(createdBid))
 }
 
 // Редактирование параметров предложения
 // (PATCH /bids/{bidId}/edit)
 func (s *MyServer) EditBid(w http.ResponseWriter, r *http.Request, bidId api.BidId, params api.EditBidParams) {
-	w.WriteHeader(http.StatusOK)
+	if bidId == "" || params.Username == "" {
+		writeErrorReason(w, http.StatusBadRequest, "bidId and username are required")
+		return
+	}
+
+	var updates api.EditBidJSONBody
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		writeErrorReason(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if updates.Name == nil && updates.Description == nil {
+		writeErrorReason(w, http.StatusBadRequest, "nothing to update")
+		return
+	}
+
+	updatedBid, err := s.Database.EditBid(r.Context(), bidId, updates.Name, updates.Description, params.Username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, specBid(updatedBid))
 }
 
+// Максимальная длина отзыва по спецификации
+const maxFeedbackLength = 1000
+
 // Отправка отзыва по предложению
 // (PUT /bids/{bidId}/feedback)
 func (s *MyServer) SubmitBidFeedback(w http.ResponseWriter, r *http.Request, bidId api.BidId, params api.SubmitBidFeedbackParams) {
-	w.WriteHeader(http.StatusOK)
+	if bidId == "" || params.BidFeedback == "" || params.Username == "" {
+		writeErrorReason(w, http.StatusBadRequest, "bidId, bidFeedback, and username are required")
+		return
+	}
+
+	if utf8.RuneCountInString(params.BidFeedback) > maxFeedbackLength {
+		writeErrorReason(w, http.StatusBadRequest, "bidFeedback is too long")
+		return
+	}
+
+	bid, err := s.Database.SubmitBidFeedback(r.Context(), bidId, params.BidFeedback, params.Username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, specBid(bid))
 }
 
 // Откат версии предложения
 // (PUT /bids/{bidId}/rollback/{version})
 func (s *MyServer) RollbackBid(w http.ResponseWriter, r *http.Request, bidId api.BidId, version int32, params api.RollbackBidParams) {
-	w.WriteHeader(http.StatusOK)
+	if bidId == "" || version < 1 || params.Username == "" {
+		writeErrorReason(w, http.StatusBadRequest, "bidId, version, and username are required")
+		return
+	}
+
+	updatedBid, err := s.Database.RollbackBid(r.Context(), bidId, int(version), params.Username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, specBid(updatedBid))
 }
 
 // Получение текущего статуса предложения
 // (GET /bids/{bidId}/status)
 func (s *MyServer) GetBidStatus(w http.ResponseWriter, r *http.Request, bidId api.BidId, params api.GetBidStatusParams) {
-	w.WriteHeader(http.StatusOK)
+	if bidId == "" || params.Username == "" {
+		writeErrorReason(w, http.StatusBadRequest, "bidId and username are required")
+		return
+	}
+
+	status, err := s.Database.GetBidStatus(r.Context(), bidId, params.Username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, specEnum(status))
 }
 
 // Изменение статуса предложения
 // (PUT /bids/{bidId}/status)
 func (s *MyServer) UpdateBidStatus(w http.ResponseWriter, r *http.Request, bidId api.BidId, params api.UpdateBidStatusParams) {
-	w.WriteHeader(http.StatusOK)
+	if bidId == "" || params.Status == "" || params.Username == "" {
+		writeErrorReason(w, http.StatusBadRequest, "bidId, status, and username are required")
+		return
+	}
+
+	updatedBid, err := s.Database.UpdateBidStatus(r.Context(), bidId, params.Status, params.Username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, specBid(updatedBid))
 }
 
 // Отправка решения по предложению
 // (PUT /bids/{bidId}/submit_decision)
 func (s *MyServer) SubmitBidDecision(w http.ResponseWriter, r *http.Request, bidId api.BidId, params api.SubmitBidDecisionParams) {
-	w.WriteHeader(http.StatusOK)
+	if bidId == "" || params.Decision == "" || params.Username == "" {
+		writeErrorReason(w, http.StatusBadRequest, "bidId, decision, and username are required")
+		return
+	}
+
+	if params.Decision != api.BidDecisionApproved && params.Decision != api.BidDecisionRejected {
+		writeErrorReason(w, http.StatusBadRequest, "invalid decision")
+		return
+	}
+
+	bid, err := s.Database.SubmitBidDecision(r.Context(), bidId, params.Decision, params.Username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, specBid(bid))
 }
 
 // Получение списка предложений для тендера
 // (GET /bids/{tenderId}/list)
 func (s *MyServer) GetBidsForTender(w http.ResponseWriter, r *http.Request, tenderId api.TenderId, params api.GetBidsForTenderParams) {
-	w.WriteHeader(http.StatusOK)
+	if tenderId == "" || params.Username == "" {
+		writeErrorReason(w, http.StatusBadRequest, "tenderId and username are required")
+		return
+	}
+
+	page, err := parsePage(r.URL.Query(), db.BidSortFields, defaultSort)
+	if err != nil {
+		writeErrorReason(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bids, info, err := s.Database.GetBidsForTender(r.Context(), tenderId, params.Username, page)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writePageInfo(w, info)
+	s.writeJSON(w, r, specBids(bids))
 }
 
 // Просмотр отзывов на прошлые предложения
 // (GET /bids/{tenderId}/reviews)
 func (s *MyServer) GetBidReviews(w http.ResponseWriter, r *http.Request, tenderId api.TenderId, params api.GetBidReviewsParams) {
-	w.WriteHeader(http.StatusOK)
+	if tenderId == "" || params.AuthorUsername == "" || params.RequesterUsername == "" {
+		writeErrorReason(w, http.StatusBadRequest, "tenderId, authorUsername, and requesterUsername are required")
+		return
+	}
+
+	// Новые отзывы первыми
+	page, err := parsePage(r.URL.Query(), db.ReviewSortFields, db.Sort{Field: db.SortByCreatedAt, Desc: true})
+	if err != nil {
+		writeErrorReason(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reviews, info, err := s.Database.GetBidReviews(r.Context(), tenderId, params.AuthorUsername, params.RequesterUsername, page)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writePageInfo(w, info)
+	s.writeJSON(w, r, reviews)
 }
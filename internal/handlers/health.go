@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Ограничение времени проверки хранилища в readiness-проверке
+const readinessTimeout = 2 * time.Second
+
+// Проверки работоспособности сервиса для оркестратора
+type Health struct {
+	storage  db.Store
+	log      *slog.Logger
+	draining atomic.Bool
+	stopping chan struct{}
+	drain    sync.Once
+}
+
+func NewHealth(storage db.Store, log *slog.Logger) *Health {
+	return &Health{storage: storage, log: log, stopping: make(chan struct{})}
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Переводит сервис в режим остановки: readiness-проверка начинает
+// отвечать 503, чтобы балансировщик перестал направлять новые запросы
+func (h *Health) Drain() {
+	h.draining.Store(true)
+	h.drain.Do(func() { close(h.stopping) })
+}
+
+// Закрывается при переводе в режим остановки. Долгие запросы, например
+// потоки событий, завершаются по нему, не дожидаясь отключения клиента.
+func (h *Health) Stopping() <-chan struct{} {
+	return h.stopping
+}
+
+// Процесс запущен и обрабатывает запросы
+// (GET /health/live)
+func (h *Health) Live(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// Сервис готов принимать запросы: не останавливается и хранилище доступно
+// (GET /health/ready)
+func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		writeHealth(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Reason: "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	if err := h.storage.Ping(ctx); err != nil {
+		h.log.WarnContext(r.Context(), "Readiness check failed", sl.Err(err))
+		writeHealth(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Reason: "storage is not available"})
+		return
+	}
+
+	writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+func writeHealth(w http.ResponseWriter, code int, resp healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+)
+
+// Ограничения длины полей из схемы таблиц employee и organization
+const (
+	maxOrganizationNameLength = 100
+	maxUsernameLength         = 50
+	maxPersonNameLength       = 50
+)
+
+// Идентификатор организации из пути запроса в каноническом виде
+func organizationIdParam(r *http.Request) (string, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "organizationId"))
+	if err != nil {
+		return "", false
+	}
+	return id.String(), true
+}
+
+// Создание организации
+// (POST /organizations/new)
+func (s *MyServer) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+
+	var request struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Type        string `json:"type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorReason(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if request.Name == "" || utf8.RuneCountInString(request.Name) > maxOrganizationNameLength {
+		writeErrorReason(w, http.StatusBadRequest, "name is required and must not exceed 100 characters")
+		return
+	}
+	orgType, err := db.ParseOrganizationType(request.Type)
+	if err != nil {
+		writeErrorReason(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	organization, err := s.Database.CreateOrganization(r.Context(), db.Organization{
+		Name:        request.Name,
+		Description: request.Description,
+		Type:        orgType,
+	}, username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, organization)
+}
+
+// Получение списка организаций
+// (GET /organizations)
+func (s *MyServer) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r.URL.Query(), db.OrganizationSortFields, defaultSort)
+	if err != nil {
+		writeErrorReason(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	organizations, info, err := s.Database.GetOrganizations(r.Context(), page)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writePageInfo(w, info)
+	s.writeJSON(w, r, organizations)
+}
+
+// Получение организации
+// (GET /organizations/{organizationId})
+func (s *MyServer) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	organizationId, ok := organizationIdParam(r)
+	if !ok {
+		writeErrorReason(w, http.StatusBadRequest, "invalid organizationId")
+		return
+	}
+
+	organization, err := s.Database.GetOrganization(r.Context(), organizationId)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, organization)
+}
+
+// Редактирование организации
+// (PATCH /organizations/{organizationId}/edit)
+func (s *MyServer) EditOrganization(w http.ResponseWriter, r *http.Request) {
+	organizationId, ok := organizationIdParam(r)
+	if !ok {
+		writeErrorReason(w, http.StatusBadRequest, "invalid organizationId")
+		return
+	}
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+
+	var request struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Type        *string `json:"type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorReason(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if request.Name == nil && request.Description == nil && request.Type == nil {
+		writeErrorReason(w, http.StatusBadRequest, "nothing to update")
+		return
+	}
+	if request.Name != nil && (*request.Name == "" || utf8.RuneCountInString(*request.Name) > maxOrganizationNameLength) {
+		writeErrorReason(w, http.StatusBadRequest, "name must not be empty or exceed 100 characters")
+		return
+	}
+	var orgType *db.OrganizationType
+	if request.Type != nil {
+		t, err := db.ParseOrganizationType(*request.Type)
+		if err != nil {
+			writeErrorReason(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		orgType = &t
+	}
+
+	organization, err := s.Database.UpdateOrganization(r.Context(), organizationId, request.Name, request.Description, orgType, username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, organization)
+}
+
+// Получение ответственных за организацию
+// (GET /organizations/{organizationId}/responsibles)
+func (s *MyServer) GetOrganizationResponsibles(w http.ResponseWriter, r *http.Request) {
+	organizationId, ok := organizationIdParam(r)
+	if !ok {
+		writeErrorReason(w, http.StatusBadRequest, "invalid organizationId")
+		return
+	}
+
+	employees, err := s.Database.GetOrganizationResponsibles(r.Context(), organizationId)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, employees)
+}
+
+// Назначение ответственного за организацию
+// (PUT /organizations/{organizationId}/responsibles/{employeeUsername})
+func (s *MyServer) AddOrganizationResponsible(w http.ResponseWriter, r *http.Request) {
+	organizationId, ok := organizationIdParam(r)
+	if !ok {
+		writeErrorReason(w, http.StatusBadRequest, "invalid organizationId")
+		return
+	}
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+
+	employee, err := s.Database.AddOrganizationResponsible(r.Context(), organizationId, chi.URLParam(r, "employeeUsername"), username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, employee)
+}
+
+// Снятие ответственного за организацию
+// (DELETE /organizations/{organizationId}/responsibles/{employeeUsername})
+func (s *MyServer) RemoveOrganizationResponsible(w http.ResponseWriter, r *http.Request) {
+	organizationId, ok := organizationIdParam(r)
+	if !ok {
+		writeErrorReason(w, http.StatusBadRequest, "invalid organizationId")
+		return
+	}
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+
+	err := s.Database.RemoveOrganizationResponsible(r.Context(), organizationId, chi.URLParam(r, "employeeUsername"), username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Регистрация сотрудника
+// (POST /employees/new)
+func (s *MyServer) CreateEmployee(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+
+	var request db.Employee
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorReason(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if request.Username == "" || utf8.RuneCountInString(request.Username) > maxUsernameLength {
+		writeErrorReason(w, http.StatusBadRequest, "username is required and must not exceed 50 characters")
+		return
+	}
+	if utf8.RuneCountInString(request.FirstName) > maxPersonNameLength || utf8.RuneCountInString(request.LastName) > maxPersonNameLength {
+		writeErrorReason(w, http.StatusBadRequest, "firstName and lastName must not exceed 50 characters")
+		return
+	}
+
+	employee, err := s.Database.CreateEmployee(r.Context(), db.Employee{
+		Username:  request.Username,
+		FirstName: request.FirstName,
+		LastName:  request.LastName,
+	}, username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, employee)
+}
+
+// Получение сотрудника
+// (GET /employees/{employeeUsername})
+func (s *MyServer) GetEmployee(w http.ResponseWriter, r *http.Request) {
+	employee, err := s.Database.GetEmployeeByUsername(r.Context(), chi.URLParam(r, "employeeUsername"))
+	if err != nil {
+		// Искомый сотрудник здесь не выполняет запрос, поэтому 404, а не 401
+		if errors.Is(err, db.ErrUserNotFound) {
+			err = db.ErrEmployeeNotFound
+		}
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, employee)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+)
+
+// Заголовки ответа со списком: курсор следующей страницы и общее число
+// элементов (если запрошено параметром total=true)
+const (
+	nextCursorHeader = "X-Next-Cursor"
+	totalCountHeader = "X-Total-Count"
+)
+
+// Сортировка списков по умолчанию: по алфавиту, как требует спецификация
+var defaultSort = db.Sort{Field: db.SortByName}
+
+// Разбирает параметры страницы списка: limit, offset, cursor, sort и total.
+// fields — поля, по которым можно сортировать этот список.
+func parsePage(query url.Values, fields []db.SortField, sort db.Sort) (db.Page, error) {
+	page := db.Page{Limit: db.DefaultPageLimit, Sort: sort}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 || n > int64(db.MaxPageLimit) {
+			return db.Page{}, fmt.Errorf("limit must be between 0 and %d", db.MaxPageLimit)
+		}
+		page.Limit = int32(n)
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return db.Page{}, fmt.Errorf("invalid offset")
+		}
+		page.Offset = int32(n)
+	}
+	if v := query.Get("sort"); v != "" {
+		s, err := db.ParseSort(v, fields)
+		if err != nil {
+			return db.Page{}, err
+		}
+		page.Sort = s
+	}
+	if v := query.Get("total"); v != "" {
+		total, err := strconv.ParseBool(v)
+		if err != nil {
+			return db.Page{}, fmt.Errorf("invalid total")
+		}
+		page.WithTotal = total
+	}
+
+	// Курсор хранит сортировку, с которой получен: без параметра sort она
+	// берется из курсора, с ним должна совпадать
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := db.DecodeCursor(v)
+		if err != nil {
+			return db.Page{}, err
+		}
+		if !slices.Contains(fields, cursor.Sort.Field) || (query.Get("sort") != "" && cursor.Sort != page.Sort) {
+			return db.Page{}, db.ErrInvalidCursor
+		}
+		if page.Offset > 0 {
+			return db.Page{}, fmt.Errorf("cursor and offset cannot be used together")
+		}
+		page.Sort = cursor.Sort
+		page.Cursor = &cursor
+	}
+	return page, nil
+}
+
+func writePageInfo(w http.ResponseWriter, info db.PageInfo) {
+	if info.NextCursor != "" {
+		w.Header().Set(nextCursorHeader, info.NextCursor)
+	}
+	if info.Total != nil {
+		w.Header().Set(totalCountHeader, strconv.Itoa(*info.Total))
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/httpx"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// Наибольшая длина идентификатора запроса, принимаемого от клиента
+const maxRequestIDLength = 128
+
+// Middleware роутера: присваивает запросу идентификатор и пишет в журнал
+// итог обработки. Идентификатор берется из заголовка X-Request-ID, если его
+// передал клиент или прокси, иначе создается новый. Он возвращается в
+// ответе и добавляется ко всем записям журнала, сделанным с контекстом
+// запроса (см. sl.New).
+func RequestLogger(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			r = r.WithContext(sl.WithRequestID(r.Context(), id))
+
+			start := time.Now()
+			sw := httpx.NewStatusWriter(w)
+			next.ServeHTTP(sw, r)
+
+			// Путь может содержать имя пользователя, поэтому пишется шаблон маршрута
+			var route string
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+			log.InfoContext(r.Context(), "Request handled",
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.Int("status", sw.Status()),
+				slog.Duration("duration", time.Since(start)),
+			)
+		})
+	}
+}
+
+// Идентификатор от клиента попадает в журнал и заголовки ответа, поэтому
+// допускаются только печатные ASCII-символы без пробелов
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Коды ответа для категорий доменных ошибок хранилища
+var errorStatus = map[db.ErrorKind]int{
+	db.KindValidation:   http.StatusBadRequest,
+	db.KindUnauthorized: http.StatusUnauthorized,
+	db.KindForbidden:    http.StatusForbidden,
+	db.KindNotFound:     http.StatusNotFound,
+	db.KindConflict:     http.StatusConflict,
+}
+
+func (s *MyServer) writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.Log.ErrorContext(r.Context(), "Error encoding response", sl.Err(err))
+	}
+}
+
+// Ответ с описанием ошибки в формате api.ErrorResponse
+func writeErrorReason(w http.ResponseWriter, code int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(api.ErrorResponse{Reason: reason})
+}
+
+// Ответ на ошибку хранилища. Код доменной ошибки определяется ее
+// категорией, текст передается клиенту. На непредвиденные ошибки
+// сервис отвечает 504, если истек дедлайн операции с хранилищем,
+// иначе 500, не раскрывая подробностей.
+func (s *MyServer) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if code, ok := errorStatus[db.KindOf(err)]; ok {
+		writeErrorReason(w, code, err.Error())
+		return
+	}
+
+	s.Log.ErrorContext(r.Context(), "Storage error", sl.Err(err))
+	if db.IsTimeout(err) {
+		writeErrorReason(w, http.StatusGatewayTimeout, "storage timeout")
+		return
+	}
+	writeErrorReason(w, http.StatusInternalServerError, "internal server error")
+}
+
+// Ответ на ошибку привязки параметров запроса в api.ServerInterfaceWrapper
+// (ChiServerOptions.ErrorHandlerFunc)
+func ParamError(w http.ResponseWriter, r *http.Request, err error) {
+	writeErrorReason(w, http.StatusBadRequest, err.Error())
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Параметры ленты событий тендера: период опроса хранилища, пауза, после
+// которой отправляется комментарий для поддержания соединения, число
+// событий за один запрос к хранилищу и пауза переподключения клиента
+const (
+	tenderEventsPollInterval = time.Second
+	tenderEventsHeartbeat    = 15 * time.Second
+	tenderEventsBatchSize    = 100
+	tenderEventsRetry        = 3 * time.Second
+)
+
+// Лента событий тендера в формате Server-Sent Events: смена статуса
+// тендера, публикация и смена статуса предложений, решения ответственных.
+// События видны по тем же правилам, что и в REST. Поле id события —
+// его номер в outbox; клиент, переподключаясь с заголовком Last-Event-ID,
+// получает пропущенные события. Без заголовка лента начинается с текущего
+// момента.
+// (GET /tenders/{tenderId}/events)
+func (s *MyServer) StreamTenderEvents(w http.ResponseWriter, r *http.Request) {
+	tenderId, err := uuid.Parse(chi.URLParam(r, "tenderId"))
+	if err != nil {
+		writeErrorReason(w, http.StatusBadRequest, "invalid tenderId")
+		return
+	}
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+	after := db.FromLatestEvent
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			writeErrorReason(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		after = n
+	}
+
+	// Права проверяются до начала потока, чтобы ошибка вернулась обычным ответом
+	batch, next, err := s.Database.GetTenderEvents(r.Context(), tenderId.String(), username, after, tenderEventsBatchSize)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	// Поток длится дольше таймаута записи сервера
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", tenderEventsRetry.Milliseconds())
+
+	ticker := time.NewTicker(tenderEventsPollInterval)
+	defer ticker.Stop()
+	lastWrite := time.Now()
+	for {
+		for _, e := range batch {
+			if err := writeTenderEvent(w, e); err != nil {
+				return
+			}
+			lastWrite = time.Now()
+		}
+		if time.Since(lastWrite) >= tenderEventsHeartbeat {
+			io.WriteString(w, ": ping\n\n")
+			lastWrite = time.Now()
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		// Пока хранилище возвращает новые события, они читаются без паузы
+		if next == after {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-s.Stopping:
+				return
+			case <-ticker.C:
+			}
+		}
+
+		after = next
+		batch, next, err = s.Database.GetTenderEvents(r.Context(), tenderId.String(), username, after, tenderEventsBatchSize)
+		if err != nil {
+			if r.Context().Err() == nil {
+				s.writeTenderEventsError(w, r, err)
+				rc.Flush()
+			}
+			return
+		}
+	}
+}
+
+func writeTenderEvent(w io.Writer, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.Type, data)
+	return err
+}
+
+// Завершает поток событием error, например когда пользователь потерял
+// доступ к тендеру
+func (s *MyServer) writeTenderEventsError(w io.Writer, r *http.Request, err error) {
+	reason := err.Error()
+	if _, ok := errorStatus[db.KindOf(err)]; !ok {
+		s.Log.ErrorContext(r.Context(), "Storage error", sl.Err(err))
+		reason = "internal server error"
+	}
+	data, _ := json.Marshal(api.ErrorResponse{Reason: reason})
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+}
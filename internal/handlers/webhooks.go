@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+)
+
+// Идентификатор вебхука из пути запроса в каноническом виде
+func webhookIdParam(r *http.Request) (string, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "webhookId"))
+	if err != nil {
+		return "", false
+	}
+	return id.String(), true
+}
+
+// Регистрация вебхука организации. Секрет подписи возвращается только в
+// ответе на этот запрос.
+// (POST /webhooks/new)
+func (s *MyServer) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+
+	var request struct {
+		OrganizationId string        `json:"organizationId"`
+		URL            string        `json:"url"`
+		Events         []events.Type `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorReason(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	organizationId, err := uuid.Parse(request.OrganizationId)
+	if err != nil {
+		writeErrorReason(w, http.StatusBadRequest, "invalid organizationId")
+		return
+	}
+
+	webhook, err := s.Database.CreateWebhook(r.Context(), db.Webhook{
+		OrganizationId: organizationId.String(),
+		URL:            request.URL,
+		Events:         request.Events,
+	}, username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, webhook)
+}
+
+// Получение вебхуков организации
+// (GET /webhooks)
+func (s *MyServer) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+	organizationId, err := uuid.Parse(r.URL.Query().Get("organizationId"))
+	if err != nil {
+		writeErrorReason(w, http.StatusBadRequest, "invalid organizationId")
+		return
+	}
+
+	webhooks, err := s.Database.GetWebhooks(r.Context(), organizationId.String(), username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, webhooks)
+}
+
+// Получение вебхука
+// (GET /webhooks/{webhookId})
+func (s *MyServer) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := webhookIdParam(r)
+	if !ok {
+		writeErrorReason(w, http.StatusBadRequest, "invalid webhookId")
+		return
+	}
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+
+	webhook, err := s.Database.GetWebhook(r.Context(), webhookId, username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, webhook)
+}
+
+// Редактирование вебхука. Включение вебхука, отключенного после серии
+// неудачных доставок, сбрасывает счетчик неудач.
+// (PATCH /webhooks/{webhookId}/edit)
+func (s *MyServer) EditWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := webhookIdParam(r)
+	if !ok {
+		writeErrorReason(w, http.StatusBadRequest, "invalid webhookId")
+		return
+	}
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+
+	var request struct {
+		URL     *string        `json:"url"`
+		Events  *[]events.Type `json:"events"`
+		Enabled *bool          `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorReason(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if request.URL == nil && request.Events == nil && request.Enabled == nil {
+		writeErrorReason(w, http.StatusBadRequest, "nothing to update")
+		return
+	}
+
+	webhook, err := s.Database.UpdateWebhook(r.Context(), webhookId, db.WebhookUpdate{
+		URL:     request.URL,
+		Events:  request.Events,
+		Enabled: request.Enabled,
+	}, username)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, webhook)
+}
+
+// Удаление вебхука вместе с журналом доставки
+// (DELETE /webhooks/{webhookId})
+func (s *MyServer) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := webhookIdParam(r)
+	if !ok {
+		writeErrorReason(w, http.StatusBadRequest, "invalid webhookId")
+		return
+	}
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+
+	if err := s.Database.DeleteWebhook(r.Context(), webhookId, username); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Журнал доставки событий вебхуку, по умолчанию сначала новые. Параметр
+// status оставляет доставки в одном состоянии.
+// (GET /webhooks/{webhookId}/deliveries)
+func (s *MyServer) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := webhookIdParam(r)
+	if !ok {
+		writeErrorReason(w, http.StatusBadRequest, "invalid webhookId")
+		return
+	}
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		writeErrorReason(w, http.StatusUnauthorized, "username is required")
+		return
+	}
+	var status db.WebhookDeliveryStatus
+	if v := r.URL.Query().Get("status"); v != "" {
+		var err error
+		if status, err = db.ParseWebhookDeliveryStatus(v); err != nil {
+			writeErrorReason(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	page, err := parsePage(r.URL.Query(), db.WebhookDeliverySortFields, db.Sort{Field: db.SortByCreatedAt, Desc: true})
+	if err != nil {
+		writeErrorReason(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	deliveries, info, err := s.Database.GetWebhookDeliveries(r.Context(), webhookId, status, username, page)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writePageInfo(w, info)
+	s.writeJSON(w, r, deliveries)
+}
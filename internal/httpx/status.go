@@ -0,0 +1,38 @@
+// Package httpx содержит общие вспомогательные типы для middleware HTTP.
+package httpx
+
+import "net/http"
+
+// Обертка ResponseWriter, которая запоминает код ответа. Unwrap дает
+// http.ResponseController доступ к исходному ResponseWriter, например для
+// потоков событий.
+type StatusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Код ответа; 200, если обработчик не вызывал WriteHeader
+func (w *StatusWriter) Status() int {
+	return w.status
+}
+
+func (w *StatusWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *StatusWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
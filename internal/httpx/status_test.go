@@ -0,0 +1,25 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusWriter(t *testing.T) {
+	w := NewStatusWriter(httptest.NewRecorder())
+	w.Write([]byte("ok"))
+	if w.Status() != http.StatusOK {
+		t.Errorf("status without WriteHeader = %d, want 200", w.Status())
+	}
+
+	w = NewStatusWriter(httptest.NewRecorder())
+	w.WriteHeader(http.StatusNotFound)
+	w.WriteHeader(http.StatusInternalServerError)
+	if w.Status() != http.StatusNotFound {
+		t.Errorf("status = %d, want first written 404", w.Status())
+	}
+	if err := http.NewResponseController(w).Flush(); err != nil {
+		t.Errorf("Flush through Unwrap: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/logger/sl"
+)
+
+// Состояние пула соединений pgxpool на момент сбора метрик
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired, idle, total, max        *prometheus.Desc
+	acquires, emptyAcquires, canceled *prometheus.Desc
+	acquireDuration                   *prometheus.Desc
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) prometheus.Collector {
+	return &poolCollector{
+		pool:     pool,
+		acquired: prometheus.NewDesc("db_pool_acquired_connections", "Connections currently acquired from the pool.", nil, nil),
+		idle:     prometheus.NewDesc("db_pool_idle_connections", "Idle connections in the pool.", nil, nil),
+		total:    prometheus.NewDesc("db_pool_total_connections", "Total connections in the pool, including ones being established.", nil, nil),
+		max:      prometheus.NewDesc("db_pool_max_connections", "Maximum size of the pool.", nil, nil),
+		acquires: prometheus.NewDesc("db_pool_acquires_total", "Successful connection acquires.", nil, nil),
+		emptyAcquires: prometheus.NewDesc("db_pool_empty_acquires_total",
+			"Acquires that had to wait for a connection because the pool was empty.", nil, nil),
+		canceled: prometheus.NewDesc("db_pool_canceled_acquires_total", "Acquires canceled by the context.", nil, nil),
+		acquireDuration: prometheus.NewDesc("db_pool_acquire_duration_seconds_total",
+			"Total time spent waiting for connections in successful acquires.", nil, nil),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceled, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
+
+// Ограничение времени сбора показателей из хранилища
+const statsTimeout = 5 * time.Second
+
+// Показатели данных сервиса, которые считаются запросом к хранилищу при
+// каждом сборе метрик. Если хранилище недоступно, показатели пропускаются,
+// а остальные метрики отдаются как обычно.
+type storeCollector struct {
+	store db.Store
+	log   *slog.Logger
+
+	tenders, bidsAwaitingDecision *prometheus.Desc
+}
+
+func NewStoreCollector(store db.Store, log *slog.Logger) prometheus.Collector {
+	return &storeCollector{
+		store:   store,
+		log:     log,
+		tenders: prometheus.NewDesc("tenders", "Number of tenders by status.", []string{"status"}, nil),
+		bidsAwaitingDecision: prometheus.NewDesc("bids_awaiting_decision",
+			"Published bids of published tenders without a final decision.", nil, nil),
+	}
+}
+
+func (c *storeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.tenders
+	ch <- c.bidsAwaitingDecision
+}
+
+func (c *storeCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	stats, err := c.store.Stats(ctx)
+	if err != nil {
+		c.log.ErrorContext(ctx, "Error collecting storage metrics", sl.Err(err))
+		return
+	}
+	for status, count := range stats.TendersByStatus {
+		ch <- prometheus.MustNewConstMetric(c.tenders, prometheus.GaugeValue, float64(count), string(status))
+	}
+	ch <- prometheus.MustNewConstMetric(c.bidsAwaitingDecision, prometheus.GaugeValue, float64(stats.BidsAwaitingDecision))
+}
@@ -0,0 +1,93 @@
+// Package metrics собирает метрики сервиса в формате Prometheus: запросы
+// HTTP по операциям спецификации, состояние пула соединений с базой данных
+// и показатели данных сервиса (тендеры по статусам, предложения, ожидающие
+// решения).
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/httpx"
+)
+
+// Метка операции запроса, не совпавшего ни с одним маршрутом
+const unmatchedOperation = "unmatched"
+
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	// Метод и шаблон пути -> operationId спецификации
+	operations map[string]string
+}
+
+// Создает реестр с метриками запросов и процесса. operations сопоставляет
+// метод и шаблон пути роутера ("GET /api/tenders") с operationId
+// спецификации (см. openapi.Operations); запросы к маршрутам вне
+// спецификации помечаются методом и шаблоном пути.
+func New(operations map[string]string) *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests by operation and response status code.",
+		}, []string{"operation", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by operation.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		operations: operations,
+	}
+	m.Registry.MustRegister(
+		m.requests,
+		m.duration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Отдает метрики реестра (GET /metrics)
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware роутера chi, учитывающее число и длительность запросов.
+// Операция определяется после обработки запроса, когда роутер уже
+// сопоставил шаблон пути.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := httpx.NewStatusWriter(w)
+		next.ServeHTTP(sw, r)
+
+		operation := m.operation(r)
+		m.requests.WithLabelValues(operation, strconv.Itoa(sw.Status())).Inc()
+		m.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	})
+}
+
+func (m *Metrics) operation(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return unmatchedOperation
+	}
+	pattern := rctx.RoutePattern()
+	if pattern == "" || pattern[len(pattern)-1] == '*' {
+		return unmatchedOperation
+	}
+	key := r.Method + " " + pattern
+	if id, ok := m.operations[key]; ok {
+		return id
+	}
+	return key
+}
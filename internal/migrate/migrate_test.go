@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	migrations, err := Load(embedded)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %s: version = %d, want %d", m, m.Version, i+1)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"migrations/0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"migrations/0001_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"migrations/0001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].String() != "0001_first" || migrations[1].Down != "DROP TABLE b;" {
+		t.Errorf("unexpected migrations: %+v", migrations)
+	}
+
+	invalid := []fstest.MapFS{
+		{"migrations/0001_first.up.sql": {Data: []byte("SELECT 1;")}},
+		{"migrations/first.up.sql": {Data: []byte("SELECT 1;")}},
+		{
+			"migrations/0001_first.up.sql":   {Data: []byte("SELECT 1;")},
+			"migrations/0001_other.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for _, fsys := range invalid {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("Load(%v) must fail", fsys)
+		}
+	}
+}
+
+// Схема баз, созданных скриптом init.sql, покрывается первыми тремя
+// миграциями, поэтому они не должны падать на уже существующих объектах
+func TestInitialMigrationsIdempotent(t *testing.T) {
+	migrations, err := Load(embedded)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, m := range migrations[:3] {
+		for _, line := range strings.Split(m.Up, "\n") {
+			plainTable := strings.HasPrefix(line, "CREATE TABLE ") && !strings.HasPrefix(line, "CREATE TABLE IF NOT EXISTS ")
+			// Типы создаются в блоках DO с обработкой duplicate_object
+			if plainTable || strings.HasPrefix(line, "CREATE TYPE ") {
+				t.Errorf("migration %s: %q fails on existing schema", m, line)
+			}
+		}
+	}
+}
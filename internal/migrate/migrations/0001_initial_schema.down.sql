@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS bids;
+DROP TYPE IF EXISTS bid_author_type;
+DROP TYPE IF EXISTS bid_status;
+DROP TABLE IF EXISTS tenders;
+DROP TYPE IF EXISTS tender_status;
+DROP TABLE IF EXISTS organization_responsible;
+DROP TABLE IF EXISTS organization;
+DROP TYPE IF EXISTS organization_type;
+DROP TABLE IF EXISTS employee;
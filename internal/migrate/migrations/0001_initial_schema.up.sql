@@ -0,0 +1,124 @@
+--Базы, созданные до появления миграций скриптом init.sql, уже содержат
+--часть схемы, поэтому объекты создаются, только если их еще нет
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS employee (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    username VARCHAR(50) UNIQUE NOT NULL,
+    first_name VARCHAR(50),
+    last_name VARCHAR(50),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+BEGIN
+    CREATE TYPE organization_type AS ENUM (
+        'IE',
+        'LLC',
+        'JSC'
+    );
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS organization (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    type organization_type,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+--Пользователь может быть ответственным только в одной организации
+CREATE TABLE IF NOT EXISTS organization_responsible (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organization(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+    CONSTRAINT organization_responsible_user_unique UNIQUE (user_id)
+);
+
+--В первой версии init.sql у таблицы не было ограничений
+ALTER TABLE organization_responsible
+    ALTER COLUMN organization_id SET NOT NULL,
+    ALTER COLUMN user_id SET NOT NULL;
+
+DO $$
+BEGIN
+    ALTER TABLE organization_responsible
+        ADD CONSTRAINT organization_responsible_user_unique UNIQUE (user_id);
+EXCEPTION
+    WHEN duplicate_table OR duplicate_object THEN NULL;
+END
+$$;
+
+--Хранение и параметры тендеров
+DO $$
+BEGIN
+    CREATE TYPE tender_status AS ENUM (
+        'CREATED',
+        'PUBLISHED',
+        'CLOSED'
+    );
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS tenders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    organization_id UUID NOT NULL REFERENCES organization(id) ON DELETE CASCADE,
+    service_type VARCHAR(50),
+    status tender_status DEFAULT 'CREATED',
+    version INT DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    creator_username VARCHAR(50) NOT NULL
+);
+
+--Хранение и параметры ставок
+DO $$
+BEGIN
+    CREATE TYPE bid_status AS ENUM (
+        'CREATED',
+        'PUBLISHED',
+        'CANCELED',
+        'APPROVED',
+        'REJECTED'
+    );
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END
+$$;
+
+--В первой версии init.sql статусов решений не было
+ALTER TYPE bid_status ADD VALUE IF NOT EXISTS 'APPROVED';
+ALTER TYPE bid_status ADD VALUE IF NOT EXISTS 'REJECTED';
+
+DO $$
+BEGIN
+    CREATE TYPE bid_author_type AS ENUM (
+        'USER',
+        'ORGANIZATION'
+    );
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS bids (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    tender_id UUID NOT NULL REFERENCES tenders(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL,
+    author_type bid_author_type NOT NULL,
+    status bid_status DEFAULT 'CREATED',
+    version INT DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
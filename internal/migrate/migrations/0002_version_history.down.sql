@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bid_versions;
+DROP TABLE IF EXISTS tender_versions;
@@ -0,0 +1,41 @@
+--Таблицы могли быть созданы скриптом init.sql до появления миграций
+--История версий тендеров
+CREATE TABLE IF NOT EXISTS tender_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tender_id UUID NOT NULL REFERENCES tenders(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    service_type VARCHAR(50),
+    status tender_status NOT NULL,
+    changed_by VARCHAR(50) NOT NULL,
+    change_type VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tender_id, version)
+);
+
+--История версий предложений
+CREATE TABLE IF NOT EXISTS bid_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    bid_id UUID NOT NULL REFERENCES bids(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    status bid_status NOT NULL,
+    changed_by VARCHAR(50),
+    change_type VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (bid_id, version)
+);
+
+--Текущее состояние существующих тендеров и предложений становится их
+--текущей версией, иначе откат к ней не найдет снимок
+INSERT INTO tender_versions (tender_id, version, name, description, service_type, status, changed_by, change_type, created_at)
+SELECT id, version, name, description, service_type, status, creator_username, 'IMPORTED', COALESCE(updated_at, CURRENT_TIMESTAMP)
+FROM tenders
+ON CONFLICT (tender_id, version) DO NOTHING;
+
+INSERT INTO bid_versions (bid_id, version, name, description, status, changed_by, change_type, created_at)
+SELECT id, version, name, description, status, NULL, 'IMPORTED', COALESCE(updated_at, CURRENT_TIMESTAMP)
+FROM bids
+ON CONFLICT (bid_id, version) DO NOTHING;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS bid_reviews;
+DROP TABLE IF EXISTS bid_decisions;
+DROP TYPE IF EXISTS bid_decision;
@@ -0,0 +1,30 @@
+--Таблицы могли быть созданы скриптом init.sql до появления миграций
+--Решения ответственных по предложениям
+DO $$
+BEGIN
+    CREATE TYPE bid_decision AS ENUM (
+        'APPROVED',
+        'REJECTED'
+    );
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS bid_decisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    bid_id UUID NOT NULL REFERENCES bids(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+    decision bid_decision NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (bid_id, user_id)
+);
+
+--Отзывы ответственных на предложения
+CREATE TABLE IF NOT EXISTS bid_reviews (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    bid_id UUID NOT NULL REFERENCES bids(id) ON DELETE CASCADE,
+    reviewer_id UUID NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+    description VARCHAR(1000) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
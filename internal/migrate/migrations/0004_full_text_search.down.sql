@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS bids_search_vector_idx;
+ALTER TABLE bids DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS tenders_search_vector_idx;
+ALTER TABLE tenders DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,20 @@
+--Полнотекстовый поиск по тендерам и предложениям. Названия весят больше
+--описаний; русская и английская конфигурации объединяются, чтобы искать
+--по словоформам на обоих языках.
+ALTER TABLE tenders ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX tenders_search_vector_idx ON tenders USING GIN (search_vector);
+
+ALTER TABLE bids ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX bids_search_vector_idx ON bids USING GIN (search_vector);
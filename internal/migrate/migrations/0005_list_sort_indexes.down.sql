@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS bids_author_id_idx;
+DROP INDEX IF EXISTS bids_tender_id_name_id_idx;
+
+DROP INDEX IF EXISTS tenders_creator_username_idx;
+DROP INDEX IF EXISTS tenders_created_at_id_idx;
+DROP INDEX IF EXISTS tenders_name_id_idx;
@@ -0,0 +1,7 @@
+--Индексы для постраничной выборки списков по курсору (поле сортировки, id)
+CREATE INDEX tenders_name_id_idx ON tenders (name, id);
+CREATE INDEX tenders_created_at_id_idx ON tenders (created_at, id);
+CREATE INDEX tenders_creator_username_idx ON tenders (creator_username);
+
+CREATE INDEX bids_tender_id_name_id_idx ON bids (tender_id, name, id);
+CREATE INDEX bids_author_id_idx ON bids (author_id);
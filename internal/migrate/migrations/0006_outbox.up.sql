@@ -0,0 +1,15 @@
+--Доменные события, записываемые в одной транзакции с изменением данных.
+--Dispatcher доставляет недоставленные события по возрастанию id.
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    aggregate_type VARCHAR(20) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    tender_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX outbox_undelivered_idx ON outbox (id) WHERE delivered_at IS NULL;
+CREATE INDEX outbox_tender_id_id_idx ON outbox (tender_id, id);
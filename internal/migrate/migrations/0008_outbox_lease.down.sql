@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS outbox_delivered_at_idx;
+DROP TABLE IF EXISTS outbox_lease;
@@ -0,0 +1,11 @@
+--Аренда обработки outbox: пока она действует, события выбирает и
+--доставляет только ее владелец. Аренда заменяет блокировку на время
+--доставки, чтобы не держать транзакцию и соединение открытыми.
+CREATE TABLE outbox_lease (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    owner UUID NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+--Удаление доставленных событий старше срока хранения
+CREATE INDEX outbox_delivered_at_idx ON outbox (delivered_at) WHERE delivered_at IS NOT NULL;
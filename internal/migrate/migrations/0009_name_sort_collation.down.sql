@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS bids_tender_id_name_id_idx;
+CREATE INDEX bids_tender_id_name_id_idx ON bids (tender_id, name, id);
+
+DROP INDEX IF EXISTS tenders_name_id_idx;
+CREATE INDEX tenders_name_id_idx ON tenders (name, id);
@@ -0,0 +1,8 @@
+--Названия сортируются побайтно (COLLATE "C"), одинаково с хранилищем
+--в памяти и независимо от локали базы. Индексы по названию пересоздаются
+--с той же сортировкой, иначе они не используются для ORDER BY.
+DROP INDEX IF EXISTS tenders_name_id_idx;
+CREATE INDEX tenders_name_id_idx ON tenders ((name COLLATE "C"), id);
+
+DROP INDEX IF EXISTS bids_tender_id_name_id_idx;
+CREATE INDEX bids_tender_id_name_id_idx ON bids (tender_id, (name COLLATE "C"), id);
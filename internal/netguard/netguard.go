@@ -0,0 +1,139 @@
+// Package netguard ограничивает исходящие запросы по адресам, которые
+// задают пользователи (вебхуки), публичными адресами интернета. Запросы
+// к loopback, link-local, частным и служебным сетям отклоняются, если
+// адрес не разрешен явно списком Allowlist.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("address is not public")
+
+// Разделяемое адресное пространство операторов связи (RFC 6598)
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Адрес публичный: не loopback, не link-local, не из частных сетей и не
+// multicast
+func Public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// Непубличные хосты и сети, запросы к которым все же разрешены, например
+// для локальной проверки вебхуков. Нулевой указатель ничего не разрешает.
+type Allowlist struct {
+	hosts    map[string]bool
+	prefixes []netip.Prefix
+}
+
+// Разбирает список через запятую из имен хостов, IP-адресов и подсетей
+// в записи CIDR, например "localhost,127.0.0.1,10.0.0.0/8"
+func ParseAllowlist(s string) (*Allowlist, error) {
+	a := &Allowlist{hosts: map[string]bool{}}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch {
+		case item == "":
+		case strings.Contains(item, "/"):
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid subnet %q", item)
+			}
+			a.prefixes = append(a.prefixes, prefix.Masked())
+		default:
+			if addr, err := netip.ParseAddr(item); err == nil {
+				addr = addr.Unmap()
+				a.prefixes = append(a.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			} else {
+				a.hosts[item] = true
+			}
+		}
+	}
+	return a, nil
+}
+
+func (a *Allowlist) allowedHost(host string) bool {
+	return a != nil && a.hosts[strings.ToLower(host)]
+}
+
+func (a *Allowlist) allowedAddr(addr netip.Addr) bool {
+	if a == nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range a.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Проверяет адрес при регистрации: отклоняет непубличные IP-адреса и
+// имена localhost. Имена хостов не разрешаются, поэтому окончательная
+// проверка выполняется при каждом соединении клиента Client.
+func (a *Allowlist) CheckURL(u *url.URL) error {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if a.allowedHost(host) {
+		return nil
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !Public(addr) && !a.allowedAddr(addr) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+		}
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// HTTP-клиент, который соединяется только с публичными адресами или
+// разрешенными списком и не следует перенаправлениям: ответ с
+// перенаправлением возвращается как есть. Адрес проверяется после
+// разрешения имени, непосредственно перед соединением, поэтому смена
+// DNS-записи после регистрации не обходит проверку. Прокси из окружения
+// не используется.
+func (a *Allowlist) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	guarded := *dialer
+	guarded.Control = func(network, address string, _ syscall.RawConn) error {
+		addrPort, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return err
+		}
+		if addr := addrPort.Addr(); !Public(addr) && !a.allowedAddr(addr) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+		}
+		return nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err == nil && a.allowedHost(host) {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package netguard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestPublic(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if got := Public(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("Public(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	allow, err := ParseAllowlist("receiver.local, 10.0.0.0/8,::1")
+	if err != nil {
+		t.Fatalf("ParseAllowlist: %v", err)
+	}
+	tests := []struct {
+		url   string
+		allow *Allowlist
+		ok    bool
+	}{
+		{"https://example.com/hook", nil, true},
+		{"https://93.184.216.34/hook", nil, true},
+		{"http://127.0.0.1:8080/hook", nil, false},
+		{"http://169.254.169.254/latest/meta-data", nil, false},
+		{"http://[::1]/hook", nil, false},
+		{"http://localhost/hook", nil, false},
+		{"http://api.localhost./hook", nil, false},
+		{"http://10.1.2.3/hook", allow, true},
+		{"http://[::1]/hook", allow, true},
+		{"http://receiver.local/hook", allow, true},
+		{"http://192.168.1.1/hook", allow, false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		err := tt.allow.CheckURL(u)
+		if (err == nil) != tt.ok {
+			t.Errorf("CheckURL(%s) = %v, want ok %v", tt.url, err, tt.ok)
+		}
+		if err != nil && !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("CheckURL(%s) = %v, want ErrForbiddenAddress", tt.url, err)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/target", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// Адрес тестового сервера не публичный и по умолчанию недоступен
+	var strict *Allowlist
+	if _, err := strict.Client(time.Second).Get(srv.URL); !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("request to loopback = %v, want ErrForbiddenAddress", err)
+	}
+
+	allow, err := ParseAllowlist("127.0.0.1")
+	if err != nil {
+		t.Fatalf("ParseAllowlist: %v", err)
+	}
+	client := allow.Client(time.Second)
+	resp, err := client.Get(srv.URL + "/redirect")
+	if err != nil {
+		t.Fatalf("request to allowed address: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("status = %d, want redirect response as is", resp.StatusCode)
+	}
+}
@@ -0,0 +1,26 @@
+package openapi
+
+import (
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+)
+
+// Операции спецификации по методу и шаблону пути роутера, например
+// "GET /api/tenders/{tenderId}/status" -> "getTenderStatus"
+func Operations() (map[string]string, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(api.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec: %w", err)
+	}
+
+	operations := map[string]string{}
+	for path, item := range doc.Paths.Map() {
+		for method, op := range item.Operations() {
+			operations[method+" "+basePath+path] = op.OperationID
+		}
+	}
+	return operations, nil
+}
@@ -0,0 +1,192 @@
+// Package openapi проверяет запросы и ответы сервиса по спецификации API:
+// длины строк, перечисления, форматы и обязательные поля тела, параметров
+// пути и запроса. Маршруты, которых нет в спецификации (организации,
+// сотрудники, проверки работоспособности), не проверяются.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+)
+
+// Префикс, под которым смонтированы маршруты спецификации
+const basePath = "/api"
+
+type Options struct {
+	// Проверять и ответы сервиса. Предназначено для тестов: ответ клиенту
+	// буферизуется целиком.
+	ValidateResponses bool
+	// Вызывается для ответа, не соответствующего спецификации. Ответ
+	// все равно передается клиенту.
+	OnResponseError func(r *http.Request, err error)
+}
+
+type Validator struct {
+	router  routers.Router
+	options Options
+	filter  *openapi3filter.Options
+}
+
+// Загружает спецификацию, встроенную в пакет api
+func NewValidator(options Options) (*Validator, error) {
+	return NewValidatorFromSpec(api.Spec, options)
+}
+
+func NewValidatorFromSpec(spec []byte, options Options) (*Validator, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec: %w", err)
+	}
+	// Примеры в спецификации задания неполные, они не проверяются
+	if err := doc.Validate(context.Background(), openapi3.DisableExamplesValidation()); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+
+	// Адрес сервера из спецификации (http://localhost:8080/api) не совпадает
+	// с адресом развернутого сервиса, поэтому сопоставляется только путь
+	doc.Servers = openapi3.Servers{{URL: basePath}}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("build openapi router: %w", err)
+	}
+
+	return &Validator{
+		router:  router,
+		options: options,
+		filter: &openapi3filter.Options{
+			// Пользователя проверяет auth.Authenticator
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			// Значения по умолчанию выбирают обработчики
+			SkipSettingDefaults: true,
+		},
+	}, nil
+}
+
+// Middleware роутера. Запрос, не соответствующий спецификации, отклоняется
+// с кодом 400 и описанием ошибки в формате api.ErrorResponse.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    v.filter,
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			writeError(w, http.StatusBadRequest, describe(err))
+			return
+		}
+
+		if !v.options.ValidateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 recorder.status,
+			Header:                 recorder.header,
+			Body:                   io.NopCloser(bytes.NewReader(recorder.body.Bytes())),
+			Options:                v.filter,
+		})
+		if err != nil && v.options.OnResponseError != nil {
+			v.options.OnResponseError(r, fmt.Errorf("%s %s: response %d does not match the spec: %w", r.Method, r.URL.Path, recorder.status, err))
+		}
+
+		for key, values := range recorder.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(recorder.status)
+		w.Write(recorder.body.Bytes())
+	})
+}
+
+// Краткое описание ошибки проверки запроса без дампа схемы
+func describe(err error) string {
+	var subject string
+	var requestErr *openapi3filter.RequestError
+	if errors.As(err, &requestErr) {
+		switch {
+		case requestErr.Parameter != nil:
+			subject = fmt.Sprintf("%s parameter %q", requestErr.Parameter.In, requestErr.Parameter.Name)
+		case requestErr.RequestBody != nil:
+			subject = "request body"
+		}
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		if field := strings.Join(schemaErr.JSONPointer(), "."); field != "" && subject == "request body" {
+			subject = fmt.Sprintf("field %q", field)
+		}
+		return joinReason(subject, schemaErr.Reason)
+	}
+	if requestErr != nil {
+		reason := requestErr.Reason
+		if reason == "" && requestErr.Err != nil {
+			reason = requestErr.Err.Error()
+		}
+		return joinReason(subject, reason)
+	}
+	return err.Error()
+}
+
+func joinReason(subject, reason string) string {
+	if subject == "" {
+		return reason
+	}
+	return subject + ": " + reason
+}
+
+func writeError(w http.ResponseWriter, code int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(api.ErrorResponse{Reason: reason})
+}
+
+// Буфер ответа обработчика для проверки по спецификации
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	wrote  bool
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wrote {
+		r.status, r.wrote = status, true
+	}
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.wrote = true
+	return r.body.Write(p)
+}
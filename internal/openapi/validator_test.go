@@ -0,0 +1,99 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/api"
+)
+
+func TestSpecMatchesTask(t *testing.T) {
+	task, err := os.ReadFile("../../задание/openapi.yml")
+	if err != nil {
+		t.Fatalf("read task spec: %v", err)
+	}
+	if !bytes.Equal(task, api.Spec) {
+		t.Error("api/openapi.yml differs from задание/openapi.yml, run go generate ./api")
+	}
+}
+
+func TestValidatorRequests(t *testing.T) {
+	v, err := NewValidator(Options{})
+	if err != nil {
+		t.Fatalf("NewValidator: %v", err)
+	}
+	handler := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method, target, body string
+		want                 int
+		reason               string
+	}{
+		{"GET", "/api/tenders?service_type=Delivery", "", http.StatusOK, ""},
+		{"GET", "/api/tenders?service_type=Catering", "", http.StatusBadRequest, `query parameter "service_type"`},
+		{"GET", "/api/bids/my?limit=100", "", http.StatusBadRequest, `query parameter "limit"`},
+		{"PUT", "/api/tenders/1/status?username=alice&status=Closed", "", http.StatusOK, ""},
+		// Перечисления проверяются строго по спецификации
+		{"PUT", "/api/tenders/1/status?username=alice&status=CLOSED", "", http.StatusBadRequest, `query parameter "status"`},
+		{"POST", "/api/bids/new", `{"name":"Offer","description":"","tenderId":"1","authorType":"USER","authorId":"1"}`, http.StatusBadRequest, `field "authorType"`},
+		{"POST", "/api/bids/new", `{"name":"Offer","description":"","tenderId":"1","authorType":"User","authorId":"1"}`, http.StatusOK, ""},
+		{"POST", "/api/bids/new", `{"name":"Offer","tenderId":"1","authorType":"User","authorId":"1"}`, http.StatusBadRequest, `field "description"`},
+		// Маршрутов вне спецификации проверка не касается
+		{"GET", "/api/organizations?limit=100", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if tt.body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		var response api.ErrorResponse
+		if tt.want != http.StatusOK {
+			json.NewDecoder(w.Body).Decode(&response)
+		}
+		if w.Code != tt.want || !strings.Contains(response.Reason, tt.reason) {
+			t.Errorf("%s %s = %d %q; want %d with reason containing %q", tt.method, tt.target, w.Code, response.Reason, tt.want, tt.reason)
+		}
+	}
+}
+
+func TestValidatorResponses(t *testing.T) {
+	var failures []error
+	v, err := NewValidator(Options{
+		ValidateResponses: true,
+		OnResponseError:   func(r *http.Request, err error) { failures = append(failures, err) },
+	})
+	if err != nil {
+		t.Fatalf("NewValidator: %v", err)
+	}
+	handler := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte(`"DRAFT"`))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/tenders/1/status?username=alice", nil))
+	// Статус 418 отсутствует в спецификации и не проверяется
+	if len(failures) != 0 || w.Code != http.StatusTeapot || w.Body.String() != `"DRAFT"` {
+		t.Fatalf("undocumented status: failures %v, response %d %s", failures, w.Code, w.Body)
+	}
+
+	handler = v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`"DRAFT"`))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/tenders/1/status?username=alice", nil))
+	if len(failures) != 1 {
+		t.Errorf("response with unknown status value: %d failures, want 1", len(failures))
+	}
+}
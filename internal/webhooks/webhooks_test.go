@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":1,"type":"tender.created"}`)
+	now := time.Unix(1700000000, 0)
+	header := Sign("whsec_test", now, body)
+
+	if err := Verify("whsec_test", header, body, now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Fatalf("Verify(%q) = %v", header, err)
+	}
+	if err := Verify("whsec_other", header, body, now, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify with another secret = %v, want ErrInvalidSignature", err)
+	}
+	if err := Verify("whsec_test", header, []byte(`{"id":2}`), now, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify of modified body = %v, want ErrInvalidSignature", err)
+	}
+	if err := Verify("whsec_test", header, body, now.Add(time.Hour), 5*time.Minute); !errors.Is(err, ErrExpiredSignature) {
+		t.Errorf("Verify of old signature = %v, want ErrExpiredSignature", err)
+	}
+	if err := Verify("whsec_test", "v1=abc", body, now, 0); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify without timestamp = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	w := &Worker{MaxAttempts: 5, Backoff: 10 * time.Second, MaxBackoff: time.Minute}
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, 0}
+	for i, d := range want {
+		if got := w.retryAfter(i + 1); got != d {
+			t.Errorf("retryAfter(%d) = %s, want %s", i+1, got, d)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Package webhooks доставляет доменные события вебхукам организаций.
+// Sink ставит событие из outbox в очередь доставки каждому подходящему
+// вебхуку, а Worker отправляет доставки подписанными POST-запросами и
+// повторяет неудачные с экспоненциальной паузой.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/db"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/events"
+	"git.codenrock.com/avito-testirovanie-na-backend-1270/cnrprod1725728996-team-79175/zadanie-6105/internal/netguard"
+)
+
+// Очередь доставки вебхукам (реализуется db.Store)
+type Store interface {
+	EnqueueWebhookDeliveries(ctx context.Context, e events.Event) (int, error)
+	DueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]db.DueWebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, deliveryId string, attempt db.WebhookAttempt) (bool, error)
+}
+
+// Получатель событий Dispatcher, который ставит событие в очередь
+// доставки вебхукам. Повторная постановка того же события ничего не меняет.
+type Sink struct {
+	Store Store
+}
+
+func (s Sink) Name() string {
+	return "webhooks"
+}
+
+func (s Sink) Deliver(ctx context.Context, e events.Event) error {
+	_, err := s.Store.EnqueueWebhookDeliveries(ctx, e)
+	return err
+}
+
+// Значения по умолчанию для Worker
+const (
+	DefaultInterval    = time.Second
+	DefaultBatchSize   = 50
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxAttempts = 8
+	DefaultBackoff     = 10 * time.Second
+	DefaultMaxBackoff  = time.Hour
+)
+
+// Клиент по умолчанию: только публичные адреса, без перенаправлений
+var publicClient = (*netguard.Allowlist)(nil).Client(0)
+
+// Отправляет доставки, время которых наступило. Ответ с кодом 2xx
+// считается успешной доставкой; после неудачи попытка повторяется через
+// Backoff, удваиваясь до MaxBackoff, пока не исчерпаны MaxAttempts
+// попыток. Вебхук отключается после DisableAfter неудач подряд.
+type Worker struct {
+	Store        Store
+	Client       *http.Client
+	Interval     time.Duration
+	BatchSize    int
+	Timeout      time.Duration
+	MaxAttempts  int
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	DisableAfter int
+	Log          *slog.Logger
+}
+
+// Отправляет доставки до отмены ctx
+func (w *Worker) Run(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := w.RunOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				w.Log.Error("Failed to deliver webhooks", slog.String("error", err.Error()))
+			}
+			if err != nil || n < w.batchSize() {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Отправляет один пакет доставок параллельно и записывает итоги попыток.
+// Возвращает число выполненных попыток.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	// Доставка откладывается на время попытки с запасом, чтобы ее не
+	// выбрал другой экземпляр сервиса
+	due, err := w.Store.DueWebhookDeliveries(ctx, w.batchSize(), 2*w.timeout()+time.Minute)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.attempt(ctx, d)
+		}()
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+func (w *Worker) attempt(ctx context.Context, d db.DueWebhookDelivery) {
+	attempt := w.send(ctx, d)
+	if attempt.Error != "" {
+		attempt.RetryAfter = w.retryAfter(d.Attempts + 1)
+	}
+	attempt.DisableAfter = w.DisableAfter
+
+	// Итог записывается и при остановке сервиса, чтобы не повторять
+	// уже выполненную попытку
+	disabled, err := w.Store.RecordWebhookAttempt(context.WithoutCancel(ctx), d.Id, attempt)
+	if err != nil {
+		w.Log.Error("Failed to record webhook attempt", slog.String("delivery_id", d.Id), slog.String("error", err.Error()))
+		return
+	}
+	if attempt.Error != "" {
+		w.Log.Warn("Webhook delivery failed",
+			slog.String("webhook_id", d.WebhookId),
+			slog.Int64("event_id", d.EventId),
+			slog.Int("attempt", d.Attempts+1),
+			slog.String("error", attempt.Error),
+		)
+	}
+	if disabled {
+		w.Log.Warn("Webhook disabled after repeated failures", slog.String("webhook_id", d.WebhookId))
+	}
+}
+
+// Выполняет запрос доставки
+func (w *Worker) send(ctx context.Context, d db.DueWebhookDelivery) db.WebhookAttempt {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Event))
+	if err != nil {
+		return db.WebhookAttempt{Error: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", d.WebhookId)
+	req.Header.Set("X-Webhook-Delivery", d.Id)
+	req.Header.Set("X-Event-Id", strconv.FormatInt(d.EventId, 10))
+	req.Header.Set("X-Event-Type", string(d.EventType))
+	req.Header.Set(SignatureHeader, Sign(d.Secret, time.Now(), d.Event))
+
+	client := w.Client
+	if client == nil {
+		client = publicClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return db.WebhookAttempt{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return db.WebhookAttempt{ResponseStatus: resp.StatusCode, Error: fmt.Sprintf("unexpected response status %d", resp.StatusCode)}
+	}
+	return db.WebhookAttempt{ResponseStatus: resp.StatusCode}
+}
+
+// Пауза после неудачной попытки с номером attempt (с 1); ноль, если
+// попытки исчерпаны
+func (w *Worker) retryAfter(attempt int) time.Duration {
+	maxAttempts := w.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if attempt >= maxAttempts {
+		return 0
+	}
+	backoff, maxBackoff := w.Backoff, w.MaxBackoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+func (w *Worker) batchSize() int {
+	if w.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return w.BatchSize
+}
+
+func (w *Worker) timeout() time.Duration {
+	if w.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return w.Timeout
+}
@@ -0,0 +1,74 @@
+package sl
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Ключ идентификатора запроса в записях журнала
+const RequestIDKey = "request_id"
+
+// Значение, которым заменяются скрытые атрибуты
+const Redacted = "[REDACTED]"
+
+type requestIDKey struct{}
+
+// Запоминает идентификатор запроса в контексте. Логгеры, созданные New,
+// добавляют его ко всем записям, сделанным с этим контекстом.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// Идентификатор запроса из контекста или пустая строка
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Создает JSON-логгер с уровнем level. К записям добавляется идентификатор
+// запроса из контекста (методы *Context, например InfoContext). Если
+// уровень выше debug, описания и имена пользователей в атрибутах скрываются
+// (см. Sensitive).
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if level > slog.LevelDebug {
+		opts.ReplaceAttr = redact
+	}
+	return slog.New(contextHandler{slog.NewJSONHandler(w, opts)})
+}
+
+// Атрибуты с персональными или объемными данными: описания тендеров,
+// предложений и организаций, а также имена пользователей ("username",
+// "author_username" и т. п.)
+func Sensitive(key string) bool {
+	return key == "description" || key == "username" || strings.HasSuffix(key, "_username")
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if Sensitive(a.Key) && a.Value.Kind() != slog.KindGroup {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// Добавляет к записям идентификатор запроса из контекста
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
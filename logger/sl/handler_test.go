@@ -0,0 +1,70 @@
+package sl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func logRecord(t *testing.T, level slog.Level, log func(*slog.Logger)) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	log(New(&buf, level))
+	if buf.Len() == 0 {
+		return nil
+	}
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid record %q: %v", buf.String(), err)
+	}
+	return record
+}
+
+func TestRequestID(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-1")
+	record := logRecord(t, slog.LevelInfo, func(log *slog.Logger) {
+		log.With(slog.String("component", "test")).InfoContext(ctx, "Handled")
+	})
+	if record[RequestIDKey] != "req-1" || record["component"] != "test" {
+		t.Errorf("record = %v, want request_id and component", record)
+	}
+
+	record = logRecord(t, slog.LevelInfo, func(log *slog.Logger) {
+		log.Info("Handled")
+	})
+	if _, ok := record[RequestIDKey]; ok {
+		t.Errorf("record without request context = %v", record)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	write := func(log *slog.Logger) {
+		log.Info("Tender created",
+			slog.String("tender_id", "t1"),
+			slog.String("username", "alice"),
+			slog.String("author_username", "bob"),
+			slog.String("description", "secret"),
+		)
+	}
+
+	record := logRecord(t, slog.LevelInfo, write)
+	for _, key := range []string{"username", "author_username", "description"} {
+		if record[key] != Redacted {
+			t.Errorf("%s = %v at info, want redacted", key, record[key])
+		}
+	}
+	if record["tender_id"] != "t1" {
+		t.Errorf("tender_id = %v, want t1", record["tender_id"])
+	}
+
+	record = logRecord(t, slog.LevelDebug, write)
+	if record["username"] != "alice" || record["description"] != "secret" {
+		t.Errorf("record at debug = %v, want values as is", record)
+	}
+
+	if record := logRecord(t, slog.LevelWarn, write); record != nil {
+		t.Errorf("info record written at warn level: %v", record)
+	}
+}